@@ -0,0 +1,841 @@
+// api.go
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/openapi"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/reminders"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/service"
+	"journal-lite/internal/tokens"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// apiScopeSession marks routes that only accept a session token from
+// /api/v1/auth/token, never a personal access token.
+const apiScopeSession = "session"
+
+// apiMux routes the JSON API. main and the tests build it with newAPIMux.
+var apiMux *apiRouter
+
+// apiRouter is a ServeMux that remembers the patterns registered on it, so
+// that they can be held against openapi.json.
+type apiRouter struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *apiRouter) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *apiRouter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+func newAPIMux(cfg config.Config) *apiRouter {
+	mux := &apiRouter{ServeMux: http.NewServeMux()}
+
+	mux.Handle("POST "+apiPrefix+"/accounts", apiPasswordLoginOnly(cfg.Auth.PasswordLoginEnabled, http.HandlerFunc(apiCreateAccountHandler)))
+	mux.Handle("POST "+apiPrefix+"/auth/token", apiPasswordLoginOnly(cfg.Auth.PasswordLoginEnabled, http.HandlerFunc(apiCreateTokenHandler)))
+	mux.Handle("GET "+apiPrefix+"/account", apiAuthMiddleware(tokens.ScopeAccountRead, http.HandlerFunc(apiGetAccountHandler)))
+	mux.Handle("PATCH "+apiPrefix+"/account", apiAuthMiddleware(tokens.ScopeAccountWrite, http.HandlerFunc(apiUpdateAccountHandler)))
+	mux.Handle("DELETE "+apiPrefix+"/account", apiAuthMiddleware(tokens.ScopeAccountWrite, http.HandlerFunc(apiDeleteAccountHandler)))
+
+	mux.Handle("GET "+apiPrefix+"/tokens", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiListTokensHandler)))
+	mux.Handle("POST "+apiPrefix+"/tokens", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiCreatePersonalTokenHandler)))
+	mux.Handle("DELETE "+apiPrefix+"/tokens/{id}", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiRevokeTokenHandler)))
+
+	mux.Handle("GET "+apiPrefix+"/feeds", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiListFeedsHandler)))
+	mux.Handle("POST "+apiPrefix+"/feeds", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiCreateFeedHandler)))
+	mux.Handle("DELETE "+apiPrefix+"/feeds/{id}", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiDeleteFeedHandler)))
+	mux.Handle("GET "+apiPrefix+"/reminders", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiListRemindersHandler)))
+	mux.Handle("POST "+apiPrefix+"/reminders", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiCreateReminderHandler)))
+	mux.Handle("DELETE "+apiPrefix+"/reminders/{id}", apiAuthMiddleware(apiScopeSession, http.HandlerFunc(apiDeleteReminderHandler)))
+
+	mux.Handle("GET "+apiPrefix+"/posts", apiAuthMiddleware(tokens.ScopePostsRead, http.HandlerFunc(apiListPostsHandler)))
+	mux.Handle("POST "+apiPrefix+"/posts", apiAuthMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(apiCreatePostHandler)))
+	mux.Handle("GET "+apiPrefix+"/posts/{id}", apiAuthMiddleware(tokens.ScopePostsRead, http.HandlerFunc(apiGetPostHandler)))
+	mux.Handle("PATCH "+apiPrefix+"/posts/{id}", apiAuthMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(apiUpdatePostHandler)))
+	mux.Handle("DELETE "+apiPrefix+"/posts/{id}", apiAuthMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(apiDeletePostHandler)))
+
+	mux.Handle("GET "+apiPrefix+"/tags", apiAuthMiddleware(tokens.ScopePostsRead, http.HandlerFunc(apiListTagsHandler)))
+	mux.Handle("GET "+apiPrefix+"/on-this-day", apiAuthMiddleware(tokens.ScopePostsRead, http.HandlerFunc(apiOnThisDayHandler)))
+	mux.Handle("GET "+apiPrefix+"/stats", apiAuthMiddleware(tokens.ScopePostsRead, http.HandlerFunc(apiStatsHandler)))
+
+	mux.Handle("GET "+apiPrefix+"/admin/backups", apiAdminMiddleware(cfg.Admin.Token, http.HandlerFunc(apiListBackupsHandler)))
+	mux.Handle("POST "+apiPrefix+"/admin/backups", apiAdminMiddleware(cfg.Admin.Token, http.HandlerFunc(apiCreateBackupHandler)))
+	mux.Handle("GET "+apiPrefix+"/admin/replication", apiAdminMiddleware(cfg.Admin.Token, http.HandlerFunc(apiReplicationHandler)))
+
+	mux.HandleFunc("GET /api/openapi.json", openAPIHandler)
+
+	mux.HandleFunc(apiPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "No such API route.")
+	})
+
+	return mux
+}
+
+// --- API Types ---
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type apiToken struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type apiAccount struct {
+	Id        int64  `json:"id"`
+	Username  string `json:"username"`
+	TimeZone  string `json:"time_zone"`
+	CreatedAt string `json:"created_at"`
+}
+
+type apiAccountInput struct {
+	TimeZone *string `json:"time_zone"`
+}
+
+type apiPostInput struct {
+	Content *string   `json:"content"`
+	Tags    *[]string `json:"tags"`
+}
+
+type apiTokenInput struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at"`
+}
+
+type apiCreatedToken struct {
+	tokens.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+type apiTokenList struct {
+	Tokens []tokens.PersonalAccessToken `json:"tokens"`
+}
+
+type apiFeedInput struct {
+	Name string `json:"name"`
+}
+
+type apiCreatedFeed struct {
+	feeds.Feed
+	Token string   `json:"token"`
+	URLs  feedURLs `json:"urls"`
+}
+
+type apiFeedList struct {
+	Feeds []feeds.Feed `json:"feeds"`
+}
+
+type apiReminderInput struct {
+	Title    string   `json:"title"`
+	Time     string   `json:"time"`
+	Weekdays []string `json:"weekdays"`
+}
+
+type apiReminderList struct {
+	Reminders []reminders.Reminder `json:"reminders"`
+}
+
+type apiPostList struct {
+	Posts      []posts.Post `json:"posts"`
+	PageNumber int64        `json:"page_number"`
+	PageSize   int64        `json:"page_size"`
+}
+
+type apiTagList struct {
+	Tags []posts.Tag `json:"tags"`
+}
+
+type apiOnThisDay struct {
+	Date     string         `json:"date"`
+	Memories []posts.Memory `json:"memories"`
+}
+
+// --- Account Handlers ---
+
+func apiCreateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials apiCredentials
+	if !decodeJSON(w, r, &credentials) {
+		return
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Username and password are required.")
+		return
+	}
+
+	ctx := r.Context()
+	id, err := accountService.CreateAccount(ctx, accounts.Account{
+		Username:     credentials.Username,
+		PasswordHash: credentials.Password,
+	})
+	if err != nil {
+		writeAPIInternalError(w, r, "Error creating account", err)
+		return
+	}
+	if id == 0 {
+		writeAPIError(w, http.StatusConflict, "username_taken", "An account with that username already exists.")
+		return
+	}
+
+	account, err := accountService.GetAccountById(ctx, id)
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching account", err)
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/account")
+	writeJSON(w, http.StatusCreated, toAPIAccount(account))
+}
+
+func apiCreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials apiCredentials
+	if !decodeJSON(w, r, &credentials) {
+		return
+	}
+
+	token, err := authenticator.Login(r.Context(), database.Db, credentials.Username, credentials.Password)
+	metrics.Login("password", err == nil)
+	if err != nil {
+		writeAPIError(w, http.StatusUnauthorized, "invalid_credentials", err.Error())
+		return
+	}
+
+	claims, err := authenticator.ValidateToken(token)
+	if err != nil {
+		writeAPIInternalError(w, r, "Error reading issued token", err)
+		return
+	}
+	metrics.Sessions.Start(token, claims.ExpiresAt.Time)
+
+	writeJSON(w, http.StatusCreated, apiToken{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+func apiGetAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account, err := accountService.GetAccountById(r.Context(), apiAccountId(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Account not found.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching account", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIAccount(account))
+}
+
+func apiUpdateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var input apiAccountInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	ctx := r.Context()
+	if input.TimeZone != nil {
+		err := accountService.SetTimeZone(ctx, apiAccountId(r), *input.TimeZone)
+		if errors.Is(err, service.ErrUnknownTimeZone) {
+			writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Unknown time zone; use an IANA name such as Europe/Berlin.")
+			return
+		}
+		if err != nil {
+			writeAPIInternalError(w, r, "Error updating account", err)
+			return
+		}
+	}
+	apiGetAccountHandler(w, r)
+}
+
+func apiDeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if err := accountService.DeleteAccountById(r.Context(), apiAccountId(r)); err != nil {
+		writeAPIInternalError(w, r, "Error deleting account", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Personal Access Token Handlers ---
+
+func apiListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokensList, err := tokenService.GetTokens(r.Context(), apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching tokens", err)
+		return
+	}
+	if tokensList == nil {
+		tokensList = []tokens.PersonalAccessToken{}
+	}
+	writeJSON(w, http.StatusOK, apiTokenList{Tokens: tokensList})
+}
+
+func apiCreatePersonalTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input apiTokenInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	plaintext, token, err := tokenService.CreateToken(r.Context(), apiAccountId(r), input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/tokens/"+strconv.FormatInt(token.Id, 10))
+	writeJSON(w, http.StatusCreated, apiCreatedToken{PersonalAccessToken: token, Token: plaintext})
+}
+
+func apiRevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return
+	}
+
+	err = tokenService.RevokeToken(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Token not found or already revoked.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error revoking token", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Feed Handlers ---
+
+func apiListFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	feedList, err := feedService.GetFeeds(r.Context(), apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching feeds", err)
+		return
+	}
+	if feedList == nil {
+		feedList = []feeds.Feed{}
+	}
+	writeJSON(w, http.StatusOK, apiFeedList{Feeds: feedList})
+}
+
+func apiCreateFeedHandler(w http.ResponseWriter, r *http.Request) {
+	var input apiFeedInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	plaintext, feed, err := feedService.CreateFeed(r.Context(), apiAccountId(r), input.Name)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/feeds/"+strconv.FormatInt(feed.Id, 10))
+	writeJSON(w, http.StatusCreated, apiCreatedFeed{Feed: feed, Token: plaintext, URLs: newFeedURLs(r, plaintext)})
+}
+
+func apiDeleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return
+	}
+
+	err = feedService.DeleteFeed(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Feed not found.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error revoking feed", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiListRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	reminderList, err := reminderService.GetReminders(r.Context(), apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching reminders", err)
+		return
+	}
+	if reminderList == nil {
+		reminderList = []reminders.Reminder{}
+	}
+	writeJSON(w, http.StatusOK, apiReminderList{Reminders: reminderList})
+}
+
+func apiCreateReminderHandler(w http.ResponseWriter, r *http.Request) {
+	var input apiReminderInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	reminder, err := reminderService.CreateReminder(r.Context(), apiAccountId(r), reminders.Reminder{
+		Title:    input.Title,
+		Time:     input.Time,
+		Weekdays: input.Weekdays,
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/reminders/"+strconv.FormatInt(reminder.Id, 10))
+	writeJSON(w, http.StatusCreated, reminder)
+}
+
+func apiDeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return
+	}
+
+	err = reminderService.DeleteReminder(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Reminder not found.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error deleting reminder", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Post Handlers ---
+
+func apiListPostsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := apiQueryParams(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+	if params.DateFrom != "" || params.DateTo != "" {
+		// Days start and end in the account's time zone.
+		account, err := accountService.GetAccountById(r.Context(), params.AccountId)
+		if err != nil {
+			writeAPIInternalError(w, r, "Error loading account", err)
+			return
+		}
+		params.Location = account.Location()
+	}
+
+	postsList, err := postService.GetPosts(r.Context(), params)
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching posts", err)
+		return
+	}
+	if postsList == nil {
+		postsList = []posts.Post{}
+	}
+
+	writeJSON(w, http.StatusOK, apiPostList{
+		Posts:      postsList,
+		PageNumber: params.PageNumber,
+		PageSize:   params.PageSize,
+	})
+}
+
+func apiCreatePostHandler(w http.ResponseWriter, r *http.Request) {
+	var input apiPostInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Content == nil || strings.TrimSpace(*input.Content) == "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Content is required.")
+		return
+	}
+
+	newPost := posts.Post{
+		Content:   *input.Content,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		AccountId: apiAccountId(r),
+	}
+	if input.Tags != nil {
+		newPost.Tags = *input.Tags
+	}
+
+	createdPost, err := postService.CreatePost(r.Context(), newPost)
+	if err != nil {
+		writeAPIInternalError(w, r, "Error creating post", err)
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/posts/"+strconv.FormatInt(createdPost.Id, 10))
+	w.Header().Set("ETag", createdPost.ETag())
+	writeJSON(w, http.StatusCreated, createdPost)
+}
+
+func apiGetPostHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := apiLoadPost(w, r)
+	if !ok {
+		return
+	}
+
+	etag := post.ETag()
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, post)
+}
+
+func apiUpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return
+	}
+
+	var input apiPostInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Content != nil && strings.TrimSpace(*input.Content) == "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "Content cannot be empty.")
+		return
+	}
+
+	// The version is checked in the same transaction as the update, so that
+	// a concurrent edit cannot slip in between.
+	updatedPost, err := postService.EditPost(r.Context(), apiAccountId(r), id, ifMatchETags(r), input.Content, input.Tags)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+	if errors.Is(err, repository.ErrPostModified) {
+		w.Header().Set("ETag", updatedPost.ETag())
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition_failed", "The post has been modified since it was fetched.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error updating post", err)
+		return
+	}
+
+	w.Header().Set("ETag", updatedPost.ETag())
+	writeJSON(w, http.StatusOK, updatedPost)
+}
+
+func apiDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return
+	}
+
+	// As for updates, the version is checked in the same transaction as the
+	// delete.
+	post, err := postService.RemovePost(r.Context(), apiAccountId(r), id, ifMatchETags(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+	if errors.Is(err, repository.ErrPostModified) {
+		w.Header().Set("ETag", post.ETag())
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition_failed", "The post has been modified since it was fetched.")
+		return
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error deleting post", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Tag Handlers ---
+
+func apiListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := postService.GetTags(r.Context(), apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching tags", err)
+		return
+	}
+	if tags == nil {
+		tags = []posts.Tag{}
+	}
+	writeJSON(w, http.StatusOK, apiTagList{Tags: tags})
+}
+
+// apiOnThisDayHandler lists what was written on a day in earlier years:
+// today in the account's time zone, or the day given as date.
+func apiOnThisDayHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	account, err := accountService.GetAccountById(ctx, apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching account", err)
+		return
+	}
+	day := time.Now().In(account.Location())
+	if value := r.URL.Query().Get("date"); value != "" {
+		if day, err = time.ParseInLocation(time.DateOnly, value, account.Location()); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_query", "date must be YYYY-MM-DD.")
+			return
+		}
+	}
+	memories, err := postService.OnThisDay(ctx, account.Id, day)
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching memories", err)
+		return
+	}
+	if memories == nil {
+		memories = []posts.Memory{}
+	}
+	writeJSON(w, http.StatusOK, apiOnThisDay{Date: day.Format(time.DateOnly), Memories: memories})
+}
+
+func apiStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	account, err := accountService.GetAccountById(ctx, apiAccountId(r))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching account", err)
+		return
+	}
+	summary, err := statsService.GetStats(ctx, account.Id, time.Now().In(account.Location()))
+	if err != nil {
+		writeAPIInternalError(w, r, "Error computing statistics", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// --- Documentation Handlers ---
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(openapi.Document())
+}
+
+// --- API Helper Functions ---
+
+// apiAuthMiddleware authenticates requests with an `Authorization: Bearer`
+// session token or personal access token. Personal access tokens must have
+// been granted scope.
+func apiAuthMiddleware(scope string, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, scopes, ok, err := authenticateBearer(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="journal-lite"`)
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "A bearer token is required.")
+			return
+		}
+		if isCredentialError(err) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="journal-lite", error="invalid_token"`)
+			writeAPIError(w, http.StatusUnauthorized, "invalid_token", "The bearer token is invalid, revoked or expired.")
+			return
+		}
+		if err != nil {
+			writeAPIInternalError(w, r, "Error checking token", err)
+			return
+		}
+
+		setLogAccount(r, userID)
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		ctx = context.WithValue(ctx, "tokenScopes", scopes)
+		r = r.WithContext(ctx)
+
+		if scope == apiScopeSession && !hasScope(r, scope) {
+			writeAPIError(w, http.StatusForbidden, "session_required", "This route requires a session token, not a personal access token.")
+			return
+		}
+		if !hasScope(r, scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="journal-lite", error="insufficient_scope", scope="`+scope+`"`)
+			writeAPIError(w, http.StatusForbidden, "insufficient_scope", "The token is missing the "+scope+" scope.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiPasswordLoginOnly refuses next when password login is disabled.
+func apiPasswordLoginOnly(enabled bool, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled {
+			writeAPIError(w, http.StatusForbidden, "password_login_disabled", "Password login is disabled; use single sign-on and a personal access token.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiAdminMiddleware protects operator routes with the configured admin
+// token. Without one the admin API does not exist.
+func apiAdminMiddleware(token string, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeAPIError(w, http.StatusNotFound, "not_found", "No such API route.")
+			return
+		}
+		submitted, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="journal-lite-admin"`)
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "The admin token is missing or wrong.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiAccountId returns the account ID stored in the context by apiAuthMiddleware.
+func apiAccountId(r *http.Request) int64 {
+	userID, _ := r.Context().Value("userID").(string)
+	id, _ := strconv.ParseInt(userID, 10, 64)
+	return id
+}
+
+func apiQueryParams(r *http.Request) (posts.QueryParams, error) {
+	query := r.URL.Query()
+	params := posts.QueryParams{
+		AccountId:  apiAccountId(r),
+		SearchText: query.Get("searchText"),
+		DateFrom:   query.Get("dateFrom"),
+		DateTo:     query.Get("dateTo"),
+		Tag:        query.Get("tag"),
+		PageNumber: 1,
+		PageSize:   20,
+	}
+
+	for _, date := range []string{params.DateFrom, params.DateTo} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return params, errors.New("dates must use the YYYY-MM-DD format")
+		}
+	}
+
+	if value := query.Get("pageNumber"); value != "" {
+		pageNumber, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || pageNumber < 1 {
+			return params, errors.New("pageNumber must be a positive integer")
+		}
+		params.PageNumber = pageNumber
+	}
+
+	if value := query.Get("pageSize"); value != "" {
+		pageSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || pageSize < 1 || pageSize > 100 {
+			return params, errors.New("pageSize must be an integer between 1 and 100")
+		}
+		params.PageSize = pageSize
+	}
+
+	return params, nil
+}
+
+// apiLoadPost fetches the post named by the {id} path value, writing an
+// error response and returning false if it does not belong to the caller.
+func apiLoadPost(w http.ResponseWriter, r *http.Request) (posts.Post, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_id", "Invalid ID format.")
+		return posts.Post{}, false
+	}
+
+	post, err := postService.GetPost(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return post, false
+	}
+	if err != nil {
+		writeAPIInternalError(w, r, "Error fetching post", err)
+		return post, false
+	}
+
+	return post, true
+}
+
+// ifMatchETags returns the entity tags of the If-Match header, or nil when
+// any version will do.
+func ifMatchETags(r *http.Request) []string {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+	var etags []string
+	for _, etag := range strings.Split(ifMatch, ",") {
+		etags = append(etags, strings.TrimSpace(etag))
+	}
+	return etags
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeAPIError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json.")
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "Could not parse the request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, statusCode int, code string, message string) {
+	writeJSON(w, statusCode, apiErrorBody{Error: apiError{Code: code, Message: message}})
+}
+
+func writeAPIInternalError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	slog.ErrorContext(r.Context(), message, "error", err)
+	writeAPIError(w, http.StatusInternalServerError, "internal_error", message+".")
+}
+
+func toAPIAccount(account accounts.Account) apiAccount {
+	return apiAccount{
+		Id:        account.Id,
+		Username:  account.Username,
+		TimeZone:  account.TimeZone,
+		CreatedAt: account.CreatedAt,
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"journal-lite/internal/database"
+	"journal-lite/internal/openapi"
+	"journal-lite/internal/replication"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// apiReplay sends requests to apiMux and validates every response against
+// openapi.json, keeping track of the operations that were exercised.
+type apiReplay struct {
+	t       *testing.T
+	spec    *openapi.Spec
+	covered map[string]bool
+}
+
+func newAPIReplay(t *testing.T) *apiReplay {
+	t.Helper()
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &apiReplay{t: t, spec: spec, covered: map[string]bool{}}
+}
+
+// do sends a request with body as JSON, unless it is nil, and fails the
+// test unless the response has status want and matches the spec.
+func (a *apiReplay) do(method string, path string, token string, body any, header http.Header, want int) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	apiMux.ServeHTTP(recorder, req)
+
+	operation, err := a.spec.ValidateResponse(req.Method, req.URL.Path, recorder.Code, recorder.Header(), recorder.Body.Bytes())
+	if err != nil {
+		a.t.Errorf("%s %s: %v", method, path, err)
+	}
+	if recorder.Code != want {
+		a.t.Fatalf("%s %s: got status %d, want %d: %s", method, path, recorder.Code, want, recorder.Body)
+	}
+	a.covered[operation] = true
+	return recorder
+}
+
+func decodeBody[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(recorder.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+	return v
+}
+
+// TestAPIMatchesSpec replays every operation in openapi.json against the
+// API and validates the responses, so that the document cannot drift from
+// the handlers unnoticed.
+func TestAPIMatchesSpec(t *testing.T) {
+	a := newAPIReplay(t)
+	v1 := apiPrefix
+
+	credentials := map[string]string{"username": "spec-replay", "password": "correct horse battery"}
+	a.do("POST", v1+"/accounts", "", credentials, nil, http.StatusCreated)
+	a.do("POST", v1+"/accounts", "", credentials, nil, http.StatusConflict)
+	session := decodeBody[apiToken](t, a.do("POST", v1+"/auth/token", "", credentials, nil, http.StatusCreated)).Token
+
+	a.do("GET", v1+"/account", "", nil, nil, http.StatusUnauthorized)
+	a.do("GET", v1+"/account", session, nil, nil, http.StatusOK)
+	a.do("PATCH", v1+"/account", session, map[string]string{"time_zone": "Mars/Olympus_Mons"}, nil, http.StatusUnprocessableEntity)
+	a.do("PATCH", v1+"/account", session, map[string]string{"time_zone": "Europe/Berlin"}, nil, http.StatusOK)
+
+	created := decodeBody[apiCreatedToken](t, a.do("POST", v1+"/tokens", session,
+		map[string]any{"name": "reader", "scopes": []string{"posts:read"}}, nil, http.StatusCreated))
+	a.do("GET", v1+"/tokens", session, nil, nil, http.StatusOK)
+	// Personal access tokens cannot manage tokens, and need the scope.
+	a.do("GET", v1+"/tokens", created.Token, nil, nil, http.StatusForbidden)
+	a.do("POST", v1+"/posts", created.Token, map[string]any{"content": "x"}, nil, http.StatusForbidden)
+	a.do("DELETE", v1+"/tokens/"+strconv.FormatInt(created.Id, 10), session, nil, nil, http.StatusNoContent)
+
+	feed := decodeBody[apiCreatedFeed](t, a.do("POST", v1+"/feeds", session, map[string]string{"name": "reader"}, nil, http.StatusCreated))
+	a.do("GET", v1+"/feeds", session, nil, nil, http.StatusOK)
+	a.do("DELETE", v1+"/feeds/"+strconv.FormatInt(feed.Id, 10), session, nil, nil, http.StatusNoContent)
+	a.do("DELETE", v1+"/feeds/"+strconv.FormatInt(feed.Id, 10), session, nil, nil, http.StatusNotFound)
+
+	reminder := decodeBody[struct{ Id int64 }](t, a.do("POST", v1+"/reminders", session,
+		map[string]any{"time": "21:00", "weekdays": []string{"MO", "TH"}}, nil, http.StatusCreated))
+	a.do("POST", v1+"/reminders", session, map[string]any{"time": "25:00"}, nil, http.StatusUnprocessableEntity)
+	a.do("GET", v1+"/reminders", session, nil, nil, http.StatusOK)
+	a.do("DELETE", v1+"/reminders/"+strconv.FormatInt(reminder.Id, 10), session, nil, nil, http.StatusNoContent)
+
+	post := decodeBody[struct{ Id int64 }](t, a.do("POST", v1+"/posts", session,
+		map[string]any{"content": "First entry", "tags": []string{"Work"}}, nil, http.StatusCreated))
+	postPath := v1 + "/posts/" + strconv.FormatInt(post.Id, 10)
+	a.do("POST", v1+"/posts", session, map[string]any{"content": " "}, nil, http.StatusUnprocessableEntity)
+	a.do("GET", v1+"/posts?tag=work", session, nil, nil, http.StatusOK)
+	a.do("GET", v1+"/posts?dateFrom=yesterday", session, nil, nil, http.StatusBadRequest)
+	etag := a.do("GET", postPath, session, nil, nil, http.StatusOK).Header().Get("ETag")
+	a.do("GET", v1+"/posts/999999", session, nil, nil, http.StatusNotFound)
+	a.do("PATCH", postPath, session, map[string]any{"content": "Edited"}, http.Header{"If-Match": {`"stale"`}}, http.StatusPreconditionFailed)
+	a.do("PATCH", postPath, session, map[string]any{"content": "Edited", "tags": []string{"home"}}, http.Header{"If-Match": {etag}}, http.StatusOK)
+	a.do("GET", v1+"/tags", session, nil, nil, http.StatusOK)
+	a.do("GET", v1+"/on-this-day?date=2024-02-29", session, nil, nil, http.StatusOK)
+	a.do("GET", v1+"/stats", session, nil, nil, http.StatusOK)
+	// The edit above changed the post, so etag is stale now.
+	a.do("DELETE", postPath, session, nil, http.Header{"If-Match": {etag}}, http.StatusPreconditionFailed)
+	a.do("GET", postPath, session, nil, nil, http.StatusOK)
+	a.do("DELETE", postPath, session, nil, nil, http.StatusNoContent)
+	a.do("DELETE", postPath, session, nil, nil, http.StatusNotFound)
+
+	a.do("GET", v1+"/admin/backups", session, nil, nil, http.StatusUnauthorized)
+	a.do("POST", v1+"/admin/backups", testAdminToken, nil, nil, http.StatusCreated)
+	a.do("GET", v1+"/admin/backups", testAdminToken, nil, nil, http.StatusOK)
+	client, err := newReplicaClient(testConfig.Replication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicator = replication.NewReplicator(database.Db, testConfig.Database.Path, client, replication.Options{})
+	t.Cleanup(func() { replicator = nil })
+	a.do("GET", v1+"/admin/replication", testAdminToken, nil, nil, http.StatusOK)
+
+	a.do("DELETE", v1+"/account", session, nil, nil, http.StatusNoContent)
+
+	for _, operation := range a.spec.Operations() {
+		if !a.covered[operation] {
+			t.Errorf("%s is documented but was not exercised", operation)
+		}
+	}
+}
+
+// TestAPIRoutesAreDocumented fails when a route is registered without an
+// operation in openapi.json, or documented without being registered.
+func TestAPIRoutesAreDocumented(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := spec.Operations()
+
+	var registered []string
+	for _, pattern := range apiMux.patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok || !strings.HasPrefix(path, apiPrefix+"/") {
+			// The catch-all and the document itself.
+			continue
+		}
+		registered = append(registered, method+" "+path)
+	}
+
+	for _, route := range registered {
+		if !slices.Contains(documented, route) {
+			t.Errorf("%s is registered but not documented", route)
+		}
+	}
+	for _, operation := range documented {
+		if !slices.Contains(registered, operation) {
+			t.Errorf("%s is documented but not registered", operation)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// assets.go
+package main
+
+//go:generate sh scripts/vendor-assets.sh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+//go:embed views/*.html
+var viewsFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+// Assets serves the files under static/ at content-hashed URLs such as
+// /static/app.3f9a1c2e.css, so they can be cached forever. In dev mode files
+// are read from disk on every request and served without hashes.
+type Assets struct {
+	fsys    fs.FS
+	devMode bool
+	// urls maps a logical name ("app.css") to its public URL.
+	urls map[string]string
+	// files maps a hashed name ("app.3f9a1c2e.css") to its logical name.
+	files map[string]string
+}
+
+func newAssets(devMode bool) *Assets {
+	a := &Assets{
+		devMode: devMode,
+		urls:    map[string]string{},
+		files:   map[string]string{},
+	}
+
+	if devMode {
+		a.fsys = os.DirFS("static")
+		return a
+	}
+
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		fatal("Failed to open embedded static files", err)
+	}
+	a.fsys = sub
+
+	err = fs.WalkDir(a.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(a.fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		hashed := hashedName(name, hex.EncodeToString(sum[:])[:12])
+		a.urls[name] = "/static/" + hashed
+		a.files[hashed] = name
+		return nil
+	})
+	if err != nil {
+		fatal("Failed to index embedded static files", err)
+	}
+
+	return a
+}
+
+// URL returns the public URL for a logical asset name, for use from
+// templates as {{ asset "app.css" }}.
+func (a *Assets) URL(name string) string {
+	if a.devMode {
+		if _, err := fs.Stat(a.fsys, name); err == nil {
+			return "/static/" + name
+		}
+	} else if url, ok := a.urls[name]; ok {
+		return url
+	}
+	slog.Warn("Unknown static asset", "name", name)
+	return "/static/" + name
+}
+
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	requested := strings.TrimPrefix(r.URL.Path, "/static/")
+	name, immutable := a.files[requested]
+	if !immutable {
+		name = requested
+	}
+
+	content, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if immutable {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+}
+
+// hashedName inserts hash before the extension: app.css -> app.<hash>.css.
+func hashedName(name string, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// assetReference matches {{ asset "name" }} in a template.
+var assetReference = regexp.MustCompile(`\{\{\s*asset\s+"([^"]+)"\s*\}\}`)
+
+func TestTemplateAssetsAreEmbedded(t *testing.T) {
+	assets := newAssets(false)
+	templates, err := fs.Glob(viewsFS, "views/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var references int
+	for _, name := range templates {
+		content, err := fs.ReadFile(viewsFS, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range assetReference.FindAllStringSubmatch(string(content), -1) {
+			references++
+			asset := match[1]
+			url, ok := assets.urls[asset]
+			if !ok {
+				t.Errorf("%s references %q, which is not embedded under static/", name, asset)
+				continue
+			}
+			recorder := httptest.NewRecorder()
+			assets.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("%s references %q: GET %s returned %d", name, asset, url, recorder.Code)
+			}
+		}
+	}
+	if references == 0 {
+		t.Error("found no asset references in views/")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/service"
+	"journal-lite/internal/tokens"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAccount creates an account with a personal access token holding
+// scopes, returning the account's id and the plaintext token.
+func newTestAccount(t *testing.T, username string, scopes ...string) (int64, string) {
+	t.Helper()
+	ctx := context.Background()
+	accountId, err := accountService.CreateAccount(ctx, accounts.Account{Username: username, PasswordHash: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := tokenService.CreateToken(ctx, accountId, "test", scopes, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accountId, token
+}
+
+func serveHTML(method string, target string, token string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	return recorder
+}
+
+func TestHTMLRoutesUseTheTokensAccount(t *testing.T) {
+	ownerId, ownerToken := newTestAccount(t, "html-owner", tokens.ScopePostsRead, tokens.ScopePostsWrite)
+	_, otherToken := newTestAccount(t, "html-other", tokens.ScopePostsRead, tokens.ScopePostsWrite)
+
+	post, err := postService.CreatePost(context.Background(), posts.Post{
+		Content:   "Owner's entry",
+		CreatedAt: time.Now().Format(time.RFC3339),
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		AccountId: ownerId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := fmt.Sprintf("?id=%d", post.Id)
+
+	for _, tc := range []struct {
+		method string
+		target string
+		form   url.Values
+	}{
+		{"GET", "/open-edit-modal" + query, nil},
+		{"GET", "/open-delete-modal" + query, nil},
+		{"PATCH", "/posts/update" + query, url.Values{"content": {"Overwritten"}}},
+		{"DELETE", "/posts/delete" + query, nil},
+	} {
+		if got := serveHTML(tc.method, tc.target, otherToken, tc.form).Code; got != http.StatusNotFound {
+			t.Errorf("%s %s with another account's token: got status %d, want %d", tc.method, tc.target, got, http.StatusNotFound)
+		}
+	}
+
+	stored, err := postService.GetPost(context.Background(), ownerId, post.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Content != "Owner's entry" {
+		t.Errorf("post content = %q, want it unchanged", stored.Content)
+	}
+
+	if body := serveHTML("GET", "/posts", otherToken, nil).Body.String(); strings.Contains(body, "Owner&#39;s entry") {
+		t.Error("/posts lists another account's entry")
+	}
+	if got := serveHTML("DELETE", "/posts/delete"+query, ownerToken, nil).Code; got != http.StatusOK {
+		t.Errorf("DELETE /posts/delete with the owner's token: got status %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestRejectedTokensAreUnauthorized(t *testing.T) {
+	accountId, token := newTestAccount(t, "revoked-token", tokens.ScopePostsRead)
+	issued, err := tokenService.GetTokens(context.Background(), accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokenService.RevokeToken(context.Background(), accountId, issued[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"revoked":         token,
+		"unknown":         tokens.Prefix + "unknown",
+		"malformed JWT":   "not-a-jwt",
+		"unknown account": mustIssueToken(t, 999999),
+	} {
+		if got := serveHTML("GET", "/posts", token, nil).Code; got != http.StatusUnauthorized {
+			t.Errorf("%s token on /posts: got status %d, want %d", name, got, http.StatusUnauthorized)
+		}
+		req := httptest.NewRequest("GET", apiPrefix+"/posts", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		apiMux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("%s token on the API: got status %d, want %d", name, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// TestHTMLRoutesCheckTheTokensScopes holds personal access tokens on the HTML
+// routes to the scopes the API asks for: feeds, reminders and identity links
+// need a signed-in session, and settings need the account scopes.
+func TestHTMLRoutesCheckTheTokensScopes(t *testing.T) {
+	ctx := context.Background()
+	accountId, postsToken := newTestAccount(t, "html-scopes", tokens.ScopePostsRead, tokens.ScopePostsWrite)
+	_, feed, err := feedService.CreateFeed(ctx, accountId, "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountToken, _, err := tokenService.CreateToken(ctx, accountId, "settings", []string{tokens.ScopeAccountRead, tokens.ScopeAccountWrite}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		target string
+		form   url.Values
+	}{
+		{"GET", "/feeds", nil},
+		{"POST", "/feeds", url.Values{"name": {"Exfiltrated"}}},
+		{"DELETE", fmt.Sprintf("/feeds/delete?id=%d", feed.Id), nil},
+		{"POST", "/reminders", url.Values{"time": {"21:00"}}},
+		{"GET", "/settings", nil},
+		{"POST", "/settings", url.Values{"time_zone": {"Europe/Berlin"}}},
+	} {
+		if got := serveHTML(tc.method, tc.target, postsToken, tc.form).Code; got != http.StatusForbidden {
+			t.Errorf("%s %s with a posts token: got status %d, want %d", tc.method, tc.target, got, http.StatusForbidden)
+		}
+	}
+	if got := serveHTML("POST", "/feeds", accountToken, url.Values{"name": {"Exfiltrated"}}).Code; got != http.StatusForbidden {
+		t.Errorf("POST /feeds with an account token: got status %d, want %d", got, http.StatusForbidden)
+	}
+	if got := serveHTML("POST", "/settings", accountToken, url.Values{"time_zone": {"UTC"}}).Code; got != http.StatusOK {
+		t.Errorf("POST /settings with an account token: got status %d, want %d", got, http.StatusOK)
+	}
+
+	feeds, err := feedService.GetFeeds(ctx, accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].Id != feed.Id {
+		t.Errorf("feeds = %+v, want only the one created by the session", feeds)
+	}
+	if got := serveHTML("POST", "/create-post", postsToken, url.Values{"content": {"Scripted"}}).Code; got != http.StatusOK {
+		t.Errorf("POST /create-post with a posts token: got status %d, want %d", got, http.StatusOK)
+	}
+	if got := serveHTML("GET", "/posts", accountToken, nil).Code; got != http.StatusForbidden {
+		t.Errorf("GET /posts with an account token: got status %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func mustIssueToken(t *testing.T, accountId int64) string {
+	t.Helper()
+	token, err := authenticator.IssueToken(accountId, "nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestIsCredentialError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{service.ErrInvalidToken, true},
+		{service.ErrTokenRevoked, true},
+		{service.ErrTokenExpired, true},
+		{service.ErrAccountDisabled, true},
+		{sql.ErrNoRows, true},
+		{fmt.Errorf("%w: signature is invalid", errInvalidSession), true},
+		{errors.New("database is locked"), false},
+		{context.Canceled, false},
+	} {
+		if got := isCredentialError(tc.err); got != tc.want {
+			t.Errorf("isCredentialError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
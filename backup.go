@@ -0,0 +1,282 @@
+// backup.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"journal-lite/internal/backup"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"journal-lite/internal/health"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/replication"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+)
+
+var (
+	backups *backup.Manager
+	// backupMu serializes scheduled and on-demand backups.
+	backupMu sync.Mutex
+)
+
+// configureBackups sets up the backup manager used by the admin API and the
+// backup command.
+func configureBackups(cfg config.BackupConfig) error {
+	opts := backup.Options{
+		Dir:      cfg.Dir,
+		Retain:   cfg.Retain,
+		Compress: cfg.Compress,
+		Validate: validateSchema,
+	}
+	if cfg.AgeRecipient != "" {
+		recipient, err := age.ParseX25519Recipient(cfg.AgeRecipient)
+		if err != nil {
+			return fmt.Errorf("backup.age_recipient: %w", err)
+		}
+		opts.Recipient = recipient
+	}
+	backups = backup.NewManager(database.Db, opts)
+	return nil
+}
+
+// scheduleBackups starts the periodic backup job and its health check when an
+// interval is configured.
+func scheduleBackups(interval time.Duration) {
+	if interval == 0 {
+		return
+	}
+
+	jobs.Go("backup", func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = createBackup(ctx)
+			}
+		}
+	})
+
+	healthChecks.Register(health.Check{
+		Name: "backup",
+		Run: func(ctx context.Context) (string, error) {
+			snapshots, err := backups.List()
+			if err != nil {
+				return "", err
+			}
+			if len(snapshots) == 0 {
+				return "", health.Warning(errors.New("no backup yet"))
+			}
+			age := time.Since(snapshots[0].CreatedAt).Round(time.Second)
+			if age > 2*interval {
+				return "", health.Warning(fmt.Errorf("last backup is %s old", age))
+			}
+			return fmt.Sprintf("last backup %s ago", age), nil
+		},
+	})
+}
+
+func validateSchema(ctx context.Context, db *sql.DB) error {
+	return database.CheckSnapshotSchema(ctx, db)
+}
+
+func createBackup(ctx context.Context) (backup.Snapshot, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	start := time.Now()
+	snapshot, err := backups.Create(ctx)
+	metrics.Backup(err == nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Backup failed", "error", err)
+		return snapshot, err
+	}
+	slog.InfoContext(ctx, "Backup written", "snapshot", snapshot.Name, "size", snapshot.Size, "duration_ms", time.Since(start).Milliseconds())
+	return snapshot, nil
+}
+
+// --- Admin API ---
+
+func apiListBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := backups.List()
+	if err != nil {
+		writeAPIInternalError(w, r, "Error listing backups", err)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []backup.Snapshot{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]backup.Snapshot{"backups": snapshots})
+}
+
+func apiCreateBackupHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := createBackup(r.Context())
+	if err != nil {
+		writeAPIInternalError(w, r, "Error creating backup", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, snapshot)
+}
+
+// --- Commands ---
+
+// runBackupCommand takes one backup of the open database and exits.
+func runBackupCommand(_ config.Config, args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the snapshot as JSON instead of its path")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] backup [--json]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	snapshot, err := createBackup(context.Background())
+	if err != nil {
+		return 1
+	}
+	if *asJSON {
+		// The API hides where snapshots are stored; the command line may not.
+		return printJSON(struct {
+			backup.Snapshot
+			Path string `json:"path"`
+		}{snapshot, snapshot.Path})
+	}
+	fmt.Println(snapshot.Path)
+	return 0
+}
+
+type restoreResult struct {
+	Snapshot string `json:"snapshot"`
+	Valid    bool   `json:"valid"`
+	Restored bool   `json:"restored"`
+	Database string `json:"database,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// runRestoreCommand validates a snapshot and swaps it in as the database.
+// It must run while the server is stopped, before the database is opened.
+func runRestoreCommand(cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	identityFile := flags.String("identity", "", "age identity file for encrypted snapshots")
+	verifyOnly := flags.Bool("verify-only", false, "only check the snapshot, do not restore it")
+	fromReplica := flags.Bool("from-replica", false, "rebuild the database from the configured replica instead of a snapshot file")
+	at := flags.String("at", "", "with --from-replica, the RFC 3339 time to restore to (default latest)")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] restore [--identity FILE] [--verify-only] [--json] SNAPSHOT")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] restore --from-replica [--at TIME] [--verify-only] [--json]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *fromReplica != (flags.NArg() == 0) || flags.NArg() > 1 || (*at != "" && !*fromReplica) {
+		flags.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	var snapshot string
+	if *fromReplica {
+		path, code := restoreFromReplica(ctx, cfg, *at)
+		if code != 0 {
+			return code
+		}
+		defer os.Remove(path)
+		snapshot = path
+	} else {
+		snapshot = flags.Arg(0)
+	}
+
+	var identities []age.Identity
+	if *identityFile != "" {
+		f, err := os.Open(*identityFile)
+		if err != nil {
+			slog.Error("Could not open identity file", "error", err)
+			return 1
+		}
+		identities, err = age.ParseIdentities(f)
+		f.Close()
+		if err != nil {
+			slog.Error("Could not read identity file", "error", err)
+			return 1
+		}
+	}
+
+	if *verifyOnly {
+		if err := backup.Verify(ctx, snapshot, identities, validateSchema); err != nil {
+			slog.Error("Snapshot is not valid", "snapshot", snapshot, "error", err)
+			return 1
+		}
+		slog.Info("Snapshot is valid", "snapshot", snapshot)
+		if *asJSON {
+			return printJSON(restoreResult{Snapshot: snapshot, Valid: true})
+		}
+		return 0
+	}
+
+	previous, err := backup.Restore(ctx, snapshot, cfg.Database.Path, identities, validateSchema)
+	if err != nil {
+		slog.Error("Restore failed", "snapshot", snapshot, "error", err)
+		return 1
+	}
+	slog.Info("Database restored", "snapshot", snapshot, "database", cfg.Database.Path, "previous", previous)
+	if *asJSON {
+		return printJSON(restoreResult{
+			Snapshot: snapshot,
+			Valid:    true,
+			Restored: true,
+			Database: cfg.Database.Path,
+			Previous: previous,
+		})
+	}
+	return 0
+}
+
+// restoreFromReplica rebuilds the database as of at from the replica into a
+// temporary file next to the database and returns its path.
+func restoreFromReplica(ctx context.Context, cfg config.Config, at string) (string, int) {
+	if cfg.Replication.URL == "" {
+		fmt.Fprintln(os.Stderr, "replication.url is not configured")
+		return "", 2
+	}
+	var point time.Time
+	if at != "" {
+		var err error
+		if point, err = time.Parse(time.RFC3339, at); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --at time: %v\n", err)
+			return "", 2
+		}
+	}
+	client, err := newReplicaClient(cfg.Replication)
+	if err != nil {
+		slog.Error("Invalid replica", "error", err)
+		return "", 1
+	}
+
+	path := cfg.Database.Path + ".replica"
+	os.Remove(path)
+	restoredTo, err := replication.Restore(ctx, client, path, point)
+	if err != nil {
+		slog.Error("Could not rebuild the database from the replica", "replica", client.String(), "error", err)
+		return "", 1
+	}
+	slog.Info("Rebuilt the database from the replica", "replica", client.String(), "restored_to", restoredTo)
+	return path, 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"journal-lite/internal/posts"
+	"net/http"
+	"time"
+)
+
+type calendarDay struct {
+	Date     string
+	Day      int
+	Count    int64
+	InMonth  bool
+	Today    bool
+	Selected bool
+}
+
+type calendarMonth struct {
+	Label     string
+	Month     string
+	PrevMonth string
+	NextMonth string
+	PrevYear  string
+	NextYear  string
+	ThisMonth string
+	Weekdays  []string
+	Weeks     [][]calendarDay
+	Total     int64
+	// Selected is the day whose entries are shown, if any.
+	Selected      string
+	SelectedLabel string
+	Posts         []posts.Post
+}
+
+// calendarHandler renders a month with the number of entries written on
+// each day. With date, that day is selected and its entries are listed
+// below; with month (YYYY-MM), just the month is shown. Without either it
+// shows this month. Days are those of the account's time zone.
+func calendarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	account, today, err := accountToday(r)
+	if err != nil {
+		handleError(w, r, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+	accountId, loc := account.Id, account.Location()
+
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var selected time.Time
+	if value := query.Get("date"); value != "" {
+		var err error
+		if selected, err = time.Parse(time.DateOnly, value); err != nil {
+			handleError(w, r, "Invalid date.", http.StatusBadRequest)
+			return
+		}
+		month = time.Date(selected.Year(), selected.Month(), 1, 0, 0, 0, 0, time.UTC)
+	} else if value := query.Get("month"); value != "" {
+		var err error
+		if month, err = time.Parse("2006-01", value); err != nil {
+			handleError(w, r, "Invalid month.", http.StatusBadRequest)
+			return
+		}
+	}
+	if month.Year() < 1 || month.Year() > 9999 {
+		handleError(w, r, "Invalid month.", http.StatusBadRequest)
+		return
+	}
+
+	// Weeks run from Monday; the grid starts on the Monday before the 1st.
+	start := month.AddDate(0, 0, -((int(month.Weekday()) + 6) % 7))
+	next := month.AddDate(0, 1, 0)
+	// The grid is laid out in UTC, where every day is 24 hours long, and
+	// counted between midnights in the account's time zone.
+	counts, err := postService.CountPostsByDay(ctx, accountId,
+		time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, loc),
+		time.Date(next.Year(), next.Month(), 1, 0, 0, 0, 0, loc))
+	if err != nil {
+		handleError(w, r, "Error counting entries", http.StatusInternalServerError)
+		return
+	}
+	byDay := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		byDay[count.Date] = count.PostCount
+	}
+
+	cal := calendarMonth{
+		Label:     month.Format("January 2006"),
+		Month:     month.Format("2006-01"),
+		PrevMonth: month.AddDate(0, -1, 0).Format("2006-01"),
+		NextMonth: next.Format("2006-01"),
+		PrevYear:  month.AddDate(-1, 0, 0).Format("2006-01"),
+		NextYear:  month.AddDate(1, 0, 0).Format("2006-01"),
+		ThisMonth: today.Format("2006-01"),
+		Weekdays:  []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+	}
+	for day := start; day.Before(next); {
+		week := make([]calendarDay, 7)
+		for i := range week {
+			date := day.Format(time.DateOnly)
+			week[i] = calendarDay{
+				Date:     date,
+				Day:      day.Day(),
+				Count:    byDay[date],
+				InMonth:  day.Month() == month.Month(),
+				Today:    date == today.Format(time.DateOnly),
+				Selected: !selected.IsZero() && day.Equal(selected),
+			}
+			cal.Total += byDay[date]
+			day = day.AddDate(0, 0, 1)
+		}
+		cal.Weeks = append(cal.Weeks, week)
+	}
+
+	if !selected.IsZero() {
+		cal.Selected = selected.Format(time.DateOnly)
+		cal.SelectedLabel = selected.Format("Monday, January 2, 2006")
+		cal.Posts, err = postService.GetPosts(ctx, posts.QueryParams{
+			AccountId: accountId,
+			DateFrom:  cal.Selected,
+			DateTo:    cal.Selected,
+			Location:  loc,
+		})
+		if err != nil {
+			handleError(w, r, "Error fetching posts", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	renderTemplate(w, r, "calendar", cal)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/tokens"
+	"maps"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var calendarDayLabel = regexp.MustCompile(`aria-label="(\d{4}-\d{2}-\d{2}), (\d+) entries"`)
+
+// calendarCounts renders the calendar at target and returns the days that
+// have entries with their counts.
+func calendarCounts(t *testing.T, target string, token string) (map[string]int, string) {
+	t.Helper()
+	recorder := serveHTML("GET", target, token, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d\n%s", target, recorder.Code, recorder.Body)
+	}
+	body := recorder.Body.String()
+	counts := map[string]int{}
+	for _, match := range calendarDayLabel.FindAllStringSubmatch(body, -1) {
+		if n, _ := strconv.Atoi(match[2]); n > 0 {
+			counts[match[1]] = n
+		}
+	}
+	return counts, body
+}
+
+// TestCalendarCountsTheAccountsDays checks that entries are counted on the
+// days of the account's time zone, across the month's ends and a change of
+// UTC offset.
+func TestCalendarCountsTheAccountsDays(t *testing.T) {
+	ctx := context.Background()
+	accountId, token := newTestAccount(t, "calendar-days", tokens.ScopePostsRead)
+	// New Zealand is at +13 until daylight saving time ends on April 7,
+	// 2024, and at +12 after.
+	if err := accountService.SetTimeZone(ctx, accountId, "Pacific/Auckland"); err != nil {
+		t.Fatal(err)
+	}
+	for _, createdAt := range []string{
+		"2024-02-29T10:00:00Z",      // February 29, 23:00
+		"2024-02-29T11:30:00Z",      // March 1, 00:30
+		"2024-03-10T12:00:00+05:00", // March 10, 20:00
+		"2024-03-10T20:00:00-08:00", // March 11, 17:00
+		"2024-03-31T10:59:00Z",      // March 31, 23:59
+		"2024-03-31T11:00:00Z",      // April 1, 00:00
+		"2024-04-07T11:30:00Z",      // April 7, 23:30 at +12
+		"2024-04-07T12:30:00Z",      // April 8, 00:30 at +12
+	} {
+		if _, err := postService.CreatePost(ctx, posts.Post{Content: "Written at " + createdAt, CreatedAt: createdAt, UpdatedAt: createdAt, AccountId: accountId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for month, want := range map[string]map[string]int{
+		"2024-02": {"2024-02-29": 1},
+		"2024-03": {"2024-03-01": 1, "2024-03-10": 1, "2024-03-11": 1, "2024-03-31": 1},
+		"2024-04": {"2024-04-01": 1, "2024-04-07": 1, "2024-04-08": 1},
+	} {
+		counts, body := calendarCounts(t, "/calendar?month="+month, token)
+		if !maps.Equal(counts, want) {
+			t.Errorf("%s counts = %v, want %v", month, counts, want)
+		}
+		total := 0
+		for _, n := range want {
+			total += n
+		}
+		noun := " entries"
+		if total == 1 {
+			noun = " entry"
+		}
+		if !strings.Contains(body, strconv.Itoa(total)+noun+" this month") {
+			t.Errorf("%s does not total %d entries", month, total)
+		}
+	}
+
+	// The selected day lists the same entries it counts.
+	_, body := calendarCounts(t, "/calendar?date=2024-03-01", token)
+	if !strings.Contains(body, "Written at 2024-02-29T11:30:00Z") || strings.Contains(body, "Written at 2024-02-29T10:00:00Z") {
+		t.Errorf("March 1 does not list just the entry written then:\n%s", body)
+	}
+
+	for _, target := range []string{"/calendar?date=2024-02-30", "/calendar?month=2024-13", "/calendar?month=0000-01"} {
+		if recorder := serveHTML("GET", target, token, nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", target, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// commands run against the open database and exit instead of starting the
+// server. serve, restore and journal are handled in main.
+var commands = map[string]func(cfg config.Config, args []string) int{
+	"backup":  runBackupCommand,
+	"export":  runExportCommand,
+	"import":  runImportCommand,
+	"migrate": runMigrateCommand,
+	"user":    runUserCommand,
+	"vacuum":  runVacuumCommand,
+}
+
+const commandList = "serve, backup, export, import, journal, migrate, restore, user, vacuum"
+
+// printJSON writes the result of a command to stdout for scripts.
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("Could not write output", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// newTable writes aligned columns to stdout. Flush it when done.
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+type migrateResult struct {
+	Version  int                  `json:"version"`
+	Latest   int                  `json:"latest"`
+	Applied  []database.Migration `json:"applied,omitempty"`
+	Reverted []database.Migration `json:"reverted,omitempty"`
+}
+
+// runMigrateCommand shows, applies or reverts schema migrations. The
+// database is opened without migrating it first.
+func runMigrateCommand(_ config.Config, args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] migrate status [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] migrate up [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] migrate down [--to VERSION] [--force] [--json]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	action, args := args[0], args[1:]
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	var to *int
+	var force *bool
+	if action == "down" {
+		to = flags.Int("to", -1, "version to migrate down to (default the previous one)")
+		force = flags.Bool("force", false, "allow reverting the initial schema, which deletes every table")
+	}
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	current, err := database.SchemaVersion(ctx, database.Db)
+	if err != nil {
+		slog.Error("Could not read the schema version", "error", err)
+		return 1
+	}
+	result := migrateResult{Latest: database.LatestVersion()}
+
+	switch action {
+	case "status":
+		statuses, err := database.MigrationStatuses(ctx, database.Db)
+		if err != nil {
+			slog.Error("Could not read applied migrations", "error", err)
+			return 1
+		}
+		if *asJSON {
+			return printJSON(struct {
+				Version    int                        `json:"version"`
+				Latest     int                        `json:"latest"`
+				Migrations []database.MigrationStatus `json:"migrations"`
+			}{current, result.Latest, statuses})
+		}
+		table := newTable()
+		fmt.Fprintln(table, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt
+			}
+			fmt.Fprintf(table, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		table.Flush()
+		fmt.Printf("Schema version %d of %d\n", current, result.Latest)
+		return 0
+
+	case "up":
+		result.Applied, err = database.Migrate(ctx, database.Db)
+		if err != nil {
+			slog.Error("Migration failed", "error", err)
+		}
+
+	case "down":
+		target := *to
+		if target < 0 {
+			target = current - 1
+		}
+		if current == 0 {
+			fmt.Fprintln(os.Stderr, "The database has no migrations to revert")
+			return 1
+		}
+		if target == 0 && !*force {
+			fmt.Fprintln(os.Stderr, "Reverting the initial schema deletes every table and entry; pass --force to do it anyway")
+			return 1
+		}
+		result.Reverted, err = database.MigrateDown(ctx, database.Db, target)
+		if err != nil {
+			slog.Error("Reverting migrations failed", "error", err)
+		}
+
+	default:
+		usage()
+		return 2
+	}
+
+	// Report what happened even when a later migration failed.
+	if version, versionErr := database.SchemaVersion(ctx, database.Db); versionErr == nil {
+		result.Version = version
+	}
+	code := 0
+	if err != nil {
+		code = 1
+	}
+	if *asJSON {
+		if printJSON(result) != 0 {
+			return 1
+		}
+		return code
+	}
+	for _, m := range result.Applied {
+		fmt.Printf("Applied %d %s\n", m.Version, m.Name)
+	}
+	for _, m := range result.Reverted {
+		fmt.Printf("Reverted %d %s\n", m.Version, m.Name)
+	}
+	if len(result.Applied) == 0 && len(result.Reverted) == 0 && err == nil {
+		fmt.Println("Nothing to do")
+	}
+	fmt.Printf("Schema version %d of %d\n", result.Version, result.Latest)
+	return code
+}
+
+type vacuumResult struct {
+	Database   string `json:"database"`
+	SizeBefore int64  `json:"size_before"`
+	SizeAfter  int64  `json:"size_after"`
+	Reclaimed  int64  `json:"reclaimed"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// runVacuumCommand compacts the database. It can run next to the server,
+// which waits for it like for any other write.
+func runVacuumCommand(cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("vacuum", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] vacuum [--json]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	result := vacuumResult{Database: cfg.Database.Path, SizeBefore: databaseSize(cfg.Database.Path)}
+	start := time.Now()
+	// With replication on, the replicator ships the WAL before it
+	// checkpoints, so it is left alone here.
+	if err := database.Vacuum(context.Background(), database.Db, cfg.Replication.URL == ""); err != nil {
+		slog.Error("Vacuum failed", "error", err)
+		return 1
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.SizeAfter = databaseSize(cfg.Database.Path)
+	result.Reclaimed = result.SizeBefore - result.SizeAfter
+
+	if *asJSON {
+		return printJSON(result)
+	}
+	fmt.Printf("Vacuumed %s: %s before, %s after\n", result.Database, formatSize(result.SizeBefore), formatSize(result.SizeAfter))
+	return 0
+}
+
+// databaseSize is the size of the database file and its WAL on disk.
+func databaseSize(path string) int64 {
+	var size int64
+	for _, name := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(name); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return strconv.FormatFloat(float64(bytes)/(1<<20), 'f', 1, 64) + " MiB"
+	case bytes >= 1<<10:
+		return strconv.FormatFloat(float64(bytes)/(1<<10), 'f', 1, 64) + " KiB"
+	}
+	return strconv.FormatInt(bytes, 10) + " B"
+}
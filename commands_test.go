@@ -0,0 +1,266 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"journal-lite/internal/database"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// runMainEnv makes the test binary run main instead of the tests, so that
+// commands run, print and exit the way the journal-lite binary does.
+const runMainEnv = "JOURNAL_TEST_RUN_MAIN"
+
+type commandResult struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// runCommand runs journal-lite with args against the database at dbPath.
+func runCommand(t *testing.T, dbPath string, stdin string, args ...string) commandResult {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(),
+		runMainEnv+"=1",
+		"JOURNAL_DB_PATH="+dbPath,
+		"JOURNAL_BACKUP_DIR="+filepath.Join(filepath.Dir(dbPath), "backups"),
+	)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return commandResult{cmd.ProcessState.ExitCode(), stdout.String(), stderr.String()}
+}
+
+// runJSONCommand runs a command that must exit with code and decodes its
+// output into v.
+func runJSONCommand(t *testing.T, dbPath string, stdin string, code int, v any, args ...string) {
+	t.Helper()
+	result := runCommand(t, dbPath, stdin, args...)
+	if result.code != code {
+		t.Fatalf("%s: exit code %d, want %d\n%s", strings.Join(args, " "), result.code, code, result.stderr)
+	}
+	if err := json.Unmarshal([]byte(result.stdout), v); err != nil {
+		t.Fatalf("%s: output is not JSON: %v\n%s", strings.Join(args, " "), err, result.stdout)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "journal.db")
+	latest := database.LatestVersion()
+
+	type status struct {
+		Version    int                        `json:"version"`
+		Latest     int                        `json:"latest"`
+		Migrations []database.MigrationStatus `json:"migrations"`
+	}
+	checkStatus := func(version int) {
+		t.Helper()
+		var got status
+		runJSONCommand(t, db, "", 0, &got, "migrate", "status", "--json")
+		if got.Version != version || got.Latest != latest || len(got.Migrations) != latest {
+			t.Fatalf("status = %+v, want version %d of %d", got, version, latest)
+		}
+		for _, m := range got.Migrations {
+			if m.Applied != (m.Version <= version) || m.Applied != (m.AppliedAt != "") {
+				t.Errorf("migration %+v at version %d", m, version)
+			}
+		}
+	}
+	versions := func(migrations []database.Migration) []int {
+		var got []int
+		for _, m := range migrations {
+			got = append(got, m.Version)
+		}
+		return got
+	}
+
+	// migrate opens the database without applying migrations itself.
+	checkStatus(0)
+
+	var result migrateResult
+	runJSONCommand(t, db, "", 0, &result, "migrate", "up", "--json")
+	if result.Version != latest || len(result.Applied) != latest || result.Applied[0].Version != 1 {
+		t.Errorf("up = %+v, want every migration applied", result)
+	}
+	checkStatus(latest)
+
+	result = migrateResult{}
+	runJSONCommand(t, db, "", 0, &result, "migrate", "up", "--json")
+	if result.Version != latest || len(result.Applied) != 0 {
+		t.Errorf("second up = %+v, want nothing applied", result)
+	}
+	if got := runCommand(t, db, "", "migrate", "up"); got.code != 0 || !strings.Contains(got.stdout, "Nothing to do") {
+		t.Errorf("up without --json = %+v", got)
+	}
+
+	result = migrateResult{}
+	runJSONCommand(t, db, "", 0, &result, "migrate", "down", "--json")
+	if result.Version != latest-1 || len(result.Reverted) != 1 || result.Reverted[0].Version != latest {
+		t.Errorf("down = %+v, want the latest migration reverted", result)
+	}
+	checkStatus(latest - 1)
+
+	// Reverting the initial schema drops every table and needs --force.
+	got := runCommand(t, db, "", "migrate", "down", "--to", "0", "--json")
+	if got.code != 1 || !strings.Contains(got.stderr, "--force") || got.stdout != "" {
+		t.Errorf("down --to 0 without --force = %+v, want exit code 1 asking for --force", got)
+	}
+	checkStatus(latest - 1)
+
+	result = migrateResult{}
+	runJSONCommand(t, db, "", 0, &result, "migrate", "down", "--to", "0", "--force", "--json")
+	var want []int
+	for v := latest - 1; v >= 1; v-- {
+		want = append(want, v)
+	}
+	if result.Version != 0 || !slices.Equal(versions(result.Reverted), want) {
+		t.Errorf("down --to 0 --force = %+v, want versions %v reverted newest first", result, want)
+	}
+	checkStatus(0)
+
+	if got := runCommand(t, db, "", "migrate", "down", "--force"); got.code != 1 || !strings.Contains(got.stderr, "no migrations") {
+		t.Errorf("down at version 0 = %+v, want exit code 1", got)
+	}
+
+	for _, args := range [][]string{
+		{"migrate"},
+		{"migrate", "sideways"},
+		{"migrate", "up", "extra"},
+		{"migrate", "status", "--force"},
+		{"migrate", "down", "--to", "one"},
+	} {
+		if got := runCommand(t, db, "", args...); got.code != 2 {
+			t.Errorf("%s: exit code %d, want 2", strings.Join(args, " "), got.code)
+		}
+	}
+}
+
+func TestUserCommand(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "journal.db")
+
+	var users []userOutput
+	runJSONCommand(t, db, "", 0, &users, "user", "list", "--json")
+	if len(users) != 0 {
+		t.Errorf("list on a new database = %+v", users)
+	}
+
+	var alice userOutput
+	runJSONCommand(t, db, "", 0, &alice, "user", "create", "alice", "--json")
+	if alice.Id == 0 || alice.Username != "alice" || alice.Disabled || alice.CreatedAt == "" || len(alice.Password) < 20 {
+		t.Errorf("create = %+v, want a new account with a generated password", alice)
+	}
+	checkPassword(t, db, "alice", alice.Password)
+
+	var bob userOutput
+	runJSONCommand(t, db, "hunter2 but longer\n", 0, &bob, "user", "create", "--json", "bob", "--password-stdin")
+	if bob.Username != "bob" || bob.Password != "" {
+		t.Errorf("create --password-stdin = %+v, want no password printed", bob)
+	}
+	checkPassword(t, db, "bob", "hunter2 but longer")
+
+	if got := runCommand(t, db, "", "user", "create", "alice"); got.code != 1 || !strings.Contains(got.stderr, "already exists") {
+		t.Errorf("create of an existing name = %+v, want exit code 1", got)
+	}
+	if got := runCommand(t, db, "\n", "user", "create", "carol", "--password-stdin"); got.code != 1 || !strings.Contains(got.stderr, "empty") {
+		t.Errorf("create with an empty password = %+v, want exit code 1", got)
+	}
+
+	var disabled userOutput
+	runJSONCommand(t, db, "", 0, &disabled, "user", "disable", "alice", "--json")
+	if disabled.Id != alice.Id || !disabled.Disabled || disabled.DisabledAt == "" || disabled.Password != "" {
+		t.Errorf("disable = %+v", disabled)
+	}
+
+	users = nil
+	runJSONCommand(t, db, "", 0, &users, "user", "list", "--json")
+	if len(users) != 2 || users[0].Username != "alice" || !users[0].Disabled || users[1].Username != "bob" || users[1].Disabled {
+		t.Errorf("list = %+v, want alice disabled and bob active", users)
+	}
+	if got := runCommand(t, db, "", "user", "list"); got.code != 0 || !strings.Contains(got.stdout, "disabled since "+disabled.DisabledAt) {
+		t.Errorf("list without --json = %+v", got)
+	}
+
+	var reset userOutput
+	runJSONCommand(t, db, "", 0, &reset, "user", "reset-password", "alice", "--json")
+	if reset.Id != alice.Id || reset.Password == "" || reset.Password == alice.Password {
+		t.Errorf("reset-password = %+v, want a new generated password", reset)
+	}
+	checkPassword(t, db, "alice", reset.Password)
+
+	runJSONCommand(t, db, "", 0, &reset, "user", "enable", "alice", "--json")
+	if reset.Disabled {
+		t.Errorf("enable = %+v", reset)
+	}
+
+	for _, args := range [][]string{
+		{"user", "reset-password", "nobody"},
+		{"user", "disable", "nobody"},
+	} {
+		if got := runCommand(t, db, "", args...); got.code != 1 || !strings.Contains(got.stderr, `No account named "nobody"`) {
+			t.Errorf("%s = %+v, want exit code 1", strings.Join(args, " "), got)
+		}
+	}
+	for _, args := range [][]string{
+		{"user"},
+		{"user", "frobnicate"},
+		{"user", "create"},
+		{"user", "create", "dave", "erin"},
+		{"user", "disable", "alice", "--password-stdin"},
+		{"user", "list", "alice"},
+	} {
+		if got := runCommand(t, db, "", args...); got.code != 2 {
+			t.Errorf("%s: exit code %d, want 2", strings.Join(args, " "), got.code)
+		}
+	}
+}
+
+// checkPassword checks that username can sign in with password.
+func checkPassword(t *testing.T, dbPath string, username string, password string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var hash string
+	if err := db.QueryRow("SELECT password_hash FROM accounts WHERE username = ?", username).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		t.Errorf("%s's password is not %q", username, password)
+	}
+}
+
+func TestVacuumCommand(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "journal.db")
+	runCommand(t, db, "", "user", "create", "alice")
+
+	var result vacuumResult
+	runJSONCommand(t, db, "", 0, &result, "vacuum", "--json")
+	if result.Database != db || result.SizeBefore == 0 || result.SizeAfter == 0 || result.Reclaimed != result.SizeBefore-result.SizeAfter {
+		t.Errorf("vacuum = %+v", result)
+	}
+	if got := runCommand(t, db, "", "vacuum"); got.code != 0 || !strings.HasPrefix(got.stdout, "Vacuumed "+db) {
+		t.Errorf("vacuum without --json = %+v", got)
+	}
+	if got := runCommand(t, db, "", "vacuum", "now"); got.code != 2 {
+		t.Errorf("vacuum now: exit code %d, want 2", got.code)
+	}
+	if got := runCommand(t, db, "", "defragment"); got.code != 2 || !strings.Contains(got.stderr, "Unknown command") {
+		t.Errorf("unknown command = %+v, want exit code 2", got)
+	}
+}
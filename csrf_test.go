@@ -0,0 +1,116 @@
+package main
+
+import (
+	"journal-lite/internal/tokens"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	_, pat := newTestAccount(t, "csrf", tokens.ScopePostsWrite)
+	token, err := newCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		cookie string
+		header http.Header
+		form   url.Values
+		want   bool
+	}{
+		{name: "missing token", target: "/create-post", cookie: token},
+		{name: "no cookie", target: "/create-post", header: http.Header{csrfHeaderName: {token}}},
+		{name: "another signed token", target: "/create-post", cookie: token, header: http.Header{csrfHeaderName: {other}}},
+		{name: "forged cookie", target: "/create-post", cookie: "forged", header: http.Header{csrfHeaderName: {"forged"}}},
+		{name: "unsigned cookie", target: "/create-post", cookie: strings.Split(token, ".")[0], header: http.Header{csrfHeaderName: {strings.Split(token, ".")[0]}}},
+		{name: "header", target: "/create-post", cookie: token, header: http.Header{csrfHeaderName: {token}}, want: true},
+		{name: "form field", target: "/create-post", cookie: token, form: url.Values{csrfFormField: {token}}, want: true},
+		{name: "JSON API", target: apiPrefix + "/posts", want: true},
+		{name: "personal access token", target: "/create-post", header: http.Header{"Authorization": {"Bearer " + pat}}, want: true},
+		{name: "unchecked bearer", target: "/create-post", header: http.Header{"Authorization": {"Bearer " + tokens.Prefix + "forged"}}},
+		{name: "malformed bearer", target: "/create-post", header: http.Header{"Authorization": {"Bearer x"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tc.target, strings.NewReader(tc.form.Encode()))
+			if tc.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			for name, values := range tc.header {
+				for _, value := range values {
+					req.Header.Add(name, value)
+				}
+			}
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tc.cookie})
+			}
+
+			var reached bool
+			recorder := httptest.NewRecorder()
+			csrfMiddleware(true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			})).ServeHTTP(recorder, req)
+
+			if reached != tc.want {
+				t.Errorf("reached the handler = %v, want %v (status %d)", reached, tc.want, recorder.Code)
+			}
+			if !tc.want && recorder.Code != http.StatusForbidden {
+				t.Errorf("got status %d, want %d", recorder.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenIsIssuedAndReused(t *testing.T) {
+	var seen string
+	handler := csrfMiddleware(true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = csrfTokenFromRequest(r)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].Value != seen || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %v, want an HttpOnly %s cookie holding the template's token %q", cookies, csrfCookieName, seen)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if len(recorder.Result().Cookies()) != 0 || seen != cookies[0].Value {
+		t.Errorf("a valid cookie was replaced")
+	}
+}
+
+// TestBearerExemptionAppliesOnlyToTheAcceptedToken checks that the HTML
+// routes, behind the real middleware, still refuse an unchecked bearer
+// token without a CSRF token.
+func TestBearerExemptionAppliesOnlyToTheAcceptedToken(t *testing.T) {
+	_, pat := newTestAccount(t, "csrf-routes", tokens.ScopePostsWrite)
+	root := csrfMiddleware(true, http.HandlerFunc(handler))
+
+	for bearer, want := range map[string]int{
+		pat:                       http.StatusOK,
+		tokens.Prefix + "unknown": http.StatusForbidden,
+	} {
+		form := url.Values{"content": {"Posted by a script"}}
+		req := httptest.NewRequest("POST", "/create-post", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		recorder := httptest.NewRecorder()
+		root.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Errorf("POST /create-post with bearer %.10s...: got status %d, want %d", bearer, recorder.Code, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"journal-lite/internal/config"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// exportHandler streams the signed in account's journal as a ZIP file. The
+// archive is written as it is built, so a failure part way through leaves the
+// client with a truncated download rather than an error page.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := ctx.Value("userID").(string)
+	accountId, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		handleError(w, r, "Invalid session", http.StatusUnauthorized)
+		return
+	}
+	account, err := accountService.GetAccountById(ctx, accountId)
+	if err != nil {
+		handleError(w, r, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+
+	// Large journals take longer than the server's write timeout to send.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFilename(time.Now())))
+	w.Header().Set("Cache-Control", "no-store")
+	if err := exportService.WriteArchive(ctx, w, account); err != nil {
+		slog.ErrorContext(ctx, "Export failed", "error", err)
+	}
+}
+
+func exportFilename(now time.Time) string {
+	return "journal-" + now.Format("2006-01-02") + ".zip"
+}
+
+// runExportCommand writes an account's export to a file or stdout.
+func runExportCommand(_ config.Config, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	username := flags.String("account", "", "username of the account to export")
+	output := flags.String("output", "", "file to write the ZIP to (default stdout)")
+	asJSON := flags.Bool("json", false, "with --output, print the result as JSON instead of the path")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] export --account NAME [--output FILE [--json]]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" || flags.NArg() > 0 || (*asJSON && *output == "") {
+		flags.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	account, err := accountService.GetAccountByUsername(ctx, *username)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "No account named %q\n", *username)
+		return 1
+	}
+	if err != nil {
+		slog.Error("Could not load account", "error", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			slog.Error("Could not create export file", "error", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := exportService.WriteArchive(ctx, w, account); err != nil {
+		slog.Error("Export failed", "error", err)
+		if *output != "" {
+			os.Remove(*output)
+		}
+		return 1
+	}
+	if *output == "" {
+		return 0
+	}
+	if *asJSON {
+		info, err := os.Stat(*output)
+		if err != nil {
+			slog.Error("Could not read export file", "error", err)
+			return 1
+		}
+		return printJSON(struct {
+			Account string `json:"account"`
+			Path    string `json:"path"`
+			Size    int64  `json:"size"`
+		}{account.Username, *output, info.Size()})
+	}
+	fmt.Println(*output)
+	return 0
+}
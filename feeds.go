@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/reminders"
+	"journal-lite/internal/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type feedFormat struct {
+	ContentType string
+	Write       func(w io.Writer, doc feeds.Document) error
+}
+
+// feedFormats are the feed URLs by path. Every feed is published in each
+// format under the same token.
+var feedFormats = map[string]feedFormat{
+	"/feeds/journal.atom": {feeds.Atom, feeds.WriteAtom},
+	"/feeds/journal.json": {feeds.JSONFeed, feeds.WriteJSONFeed},
+}
+
+// digestFormats are the URLs of the On this day digest by path.
+var digestFormats = map[string]feedFormat{
+	"/feeds/on-this-day.atom": {feeds.Atom, feeds.WriteAtom},
+	"/feeds/on-this-day.json": {feeds.JSONFeed, feeds.WriteJSONFeed},
+}
+
+// feedURLs are the addresses of one feed, shown once when it is created.
+type feedURLs struct {
+	Atom      string `json:"atom"`
+	JSON      string `json:"json"`
+	Calendar  string `json:"calendar"`
+	OnThisDay string `json:"on_this_day"`
+}
+
+func newFeedURLs(r *http.Request, token string) feedURLs {
+	return feedURLs{
+		Atom:      feedURL(r, "/feeds/journal.atom", token, 1),
+		JSON:      feedURL(r, "/feeds/journal.json", token, 1),
+		Calendar:  feedURL(r, "/feeds/journal.ics", token, 1),
+		OnThisDay: feedURL(r, "/feeds/on-this-day.atom", token, 1),
+	}
+}
+
+func feedURL(r *http.Request, path string, token string, page int64) string {
+	query := url.Values{"token": {token}}
+	if page > 1 {
+		query.Set("page", strconv.FormatInt(page, 10))
+	}
+	return publicURL(r) + path + "?" + query.Encode()
+}
+
+// publicURLMiddleware stores server.public_url in the context for publicURL.
+func publicURLMiddleware(configured string, next http.Handler) http.Handler {
+	if configured == "" {
+		return next
+	}
+	configured = strings.TrimRight(configured, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "publicURL", configured)))
+	})
+}
+
+// publicURL is server.public_url, or else the scheme and host the request
+// was made with. Behind a proxy that terminates TLS, set server.public_url
+// or pass X-Forwarded-Proto.
+func publicURL(r *http.Request) string {
+	if configured, ok := r.Context().Value("publicURL").(string); ok {
+		return configured
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// serveFeed publishes a page of the journal of the feed whose token is in
+// the URL. Unknown and revoked tokens get 404, like pages past the end, so
+// that the URL gives nothing away.
+func serveFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	page := int64(1)
+	if value := r.URL.Query().Get("page"); value != "" {
+		var err error
+		page, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || page < 1 {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	token, feed, account, ok := authenticateFeed(w, r)
+	if !ok {
+		return
+	}
+	postList, pages, err := feedService.Page(r.Context(), account.Id, page)
+	if err != nil {
+		handleError(w, r, "Error loading feed", http.StatusInternalServerError)
+		return
+	}
+	if page > pages {
+		http.NotFound(w, r)
+		return
+	}
+
+	doc := newFeedDocument(r, feed, account, token, page, pages)
+	addFeedEntries(r, &doc, account, postList)
+
+	var body bytes.Buffer
+	if err := format.Write(&body, doc); err != nil {
+		handleError(w, r, "Error writing feed", http.StatusInternalServerError)
+		return
+	}
+	serveFeedBody(w, r, format.ContentType, doc.Updated, body.Bytes())
+}
+
+// serveCalendar publishes the whole journal of the feed whose token is in
+// the URL as an iCalendar feed, with the account's reminders. Entries fall
+// on their day in the time zone named by tz, or the account's.
+func serveCalendar(w http.ResponseWriter, r *http.Request) {
+	token, feed, account, ok := authenticateFeed(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	loc := account.Location()
+	if tz := query.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			handleError(w, r, "Unknown time zone in tz.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	postList, err := feedService.AllPosts(ctx, account.Id)
+	if err != nil {
+		handleError(w, r, "Error loading feed", http.StatusInternalServerError)
+		return
+	}
+	reminderList, err := reminderService.GetReminders(ctx, account.Id)
+	if err != nil {
+		handleError(w, r, "Error loading feed", http.StatusInternalServerError)
+		return
+	}
+
+	cal := feeds.Calendar{
+		Document:        newFeedDocument(r, feed, account, token, 1, 1),
+		IdBase:          feeds.TagURI(hostOf(publicURL(r)), account.CreatedAt),
+		Reminders:       reminderList,
+		Location:        loc,
+		Journal:         strings.EqualFold(query.Get("component"), "vjournal"),
+		RefreshInterval: time.Hour,
+	}
+	// Keep the rest of the query, so that subscribers are told the same URL.
+	cal.SelfURL = publicURL(r) + r.URL.Path + "?" + r.URL.RawQuery
+	addFeedEntries(r, &cal.Document, account, postList)
+	for _, reminder := range reminderList {
+		if created, err := time.Parse(time.RFC3339, reminder.CreatedAt); err == nil && created.After(cal.Updated) {
+			cal.Updated = created
+		}
+	}
+
+	var body bytes.Buffer
+	if err := feeds.WriteICalendar(&body, cal); err != nil {
+		handleError(w, r, "Error writing feed", http.StatusInternalServerError)
+		return
+	}
+	serveFeedBody(w, r, feeds.ICalendar, cal.Updated, body.Bytes())
+}
+
+// authenticateFeed resolves the token in the URL. Unknown and revoked tokens
+// get 404, so that the URL gives nothing away; ok is false when a response
+// was written.
+func authenticateFeed(w http.ResponseWriter, r *http.Request) (token string, feed feeds.Feed, account accounts.Account, ok bool) {
+	token = r.URL.Query().Get("token")
+	feed, account, err := feedService.Authenticate(r.Context(), token)
+	if errors.Is(err, service.ErrInvalidToken) {
+		http.NotFound(w, r)
+		return token, feed, account, false
+	}
+	if err != nil {
+		handleError(w, r, "Error loading feed", http.StatusInternalServerError)
+		return token, feed, account, false
+	}
+	return token, feed, account, true
+}
+
+func addFeedEntries(r *http.Request, doc *feeds.Document, account accounts.Account, postList []posts.Post) {
+	idBase := feeds.TagURI(hostOf(publicURL(r)), account.CreatedAt)
+	for _, post := range postList {
+		entry := feeds.NewEntry(idBase, post)
+		if entry.Updated.After(doc.Updated) {
+			doc.Updated = entry.Updated
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+}
+
+// serveFeedBody sends a rendered feed. Readers that send back the ETag or
+// Last-Modified of their copy get 304 when nothing changed.
+func serveFeedBody(w http.ResponseWriter, r *http.Request, contentType string, modified time.Time, body []byte) {
+	sum := sha256.Sum256(body)
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	// The feed is private to whoever has the token, and readers should
+	// revalidate rather than show a stale copy.
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("X-Robots-Tag", "noindex")
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+func newFeedDocument(r *http.Request, feed feeds.Feed, account accounts.Account, token string, page int64, pages int64) feeds.Document {
+	base := publicURL(r)
+	doc := feeds.Document{
+		Id:       feeds.TagURI(hostOf(base), account.CreatedAt) + "journal",
+		Title:    account.Username + "'s journal",
+		Author:   account.Username,
+		HomeURL:  base + "/feed",
+		SelfURL:  feedURL(r, r.URL.Path, token, page),
+		FirstURL: feedURL(r, r.URL.Path, token, 1),
+		LastURL:  feedURL(r, r.URL.Path, token, pages),
+	}
+	// An empty page was last modified when the feed was created.
+	doc.Updated, _ = time.Parse(time.RFC3339, feed.CreatedAt)
+	if page > 1 {
+		doc.PrevURL = feedURL(r, r.URL.Path, token, page-1)
+	}
+	if page < pages {
+		doc.NextURL = feedURL(r, r.URL.Path, token, page+1)
+	}
+	return doc
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "localhost"
+	}
+	return u.Host
+}
+
+// --- Feed Management ---
+
+func feedsPageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	feedList, err := feedService.GetFeeds(ctx, apiAccountId(r))
+	if err != nil {
+		handleError(w, r, "Error fetching feeds", http.StatusInternalServerError)
+		return
+	}
+	reminderList, err := reminderService.GetReminders(ctx, apiAccountId(r))
+	if err != nil {
+		handleError(w, r, "Error fetching reminders", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "feeds", map[string]any{
+		"Feeds":     feedList,
+		"Reminders": reminderList,
+		"Weekdays":  weekdayOptions(),
+	})
+}
+
+// createFeedHandler creates a feed and shows its URLs. They contain the
+// token, so they are only shown this once.
+func createFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		handleError(w, r, "Could not read the form.", http.StatusBadRequest)
+		return
+	}
+	token, feed, err := feedService.CreateFeed(r.Context(), apiAccountId(r), r.FormValue("name"))
+	if err != nil {
+		handleError(w, r, "Give the feed a name, such as the reader it is for.", http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, "feed-created", map[string]any{"Feed": feed, "URLs": newFeedURLs(r, token)})
+}
+
+func deleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		handleError(w, r, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+	err = feedService.DeleteFeed(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		handleError(w, r, "Feed not found.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleError(w, r, "Error revoking feed.", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "empty-div", nil)
+}
+
+// --- Reminders ---
+
+type weekdayOption struct {
+	Code  string
+	Label string
+}
+
+// weekdayOptions are the days offered for reminders, from Monday.
+func weekdayOptions() []weekdayOption {
+	var options []weekdayOption
+	for i := range reminders.Weekdays {
+		day := time.Weekday((i + 1) % 7)
+		options = append(options, weekdayOption{Code: reminders.Weekdays[day], Label: day.String()[:3]})
+	}
+	return options
+}
+
+func createReminderHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		handleError(w, r, "Could not read the form.", http.StatusBadRequest)
+		return
+	}
+	reminder, err := reminderService.CreateReminder(r.Context(), apiAccountId(r), reminders.Reminder{
+		Title:    r.FormValue("title"),
+		Time:     r.FormValue("time"),
+		Weekdays: r.Form["weekday"],
+	})
+	if err != nil {
+		handleError(w, r, "Choose a time of day, such as 21:00.", http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, "reminder-row", reminder)
+}
+
+func deleteReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		handleError(w, r, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+	err = reminderService.DeleteReminder(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		handleError(w, r, "Reminder not found.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleError(w, r, "Error deleting reminder.", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "empty-div", nil)
+}
@@ -0,0 +1,79 @@
+// health.go
+package main
+
+import (
+	"context"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"journal-lite/internal/health"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// healthChecks backs /readyz and /healthz. Optional components, such as a
+// blob store, register their own reachability checks when configured.
+var healthChecks *health.Registry
+
+func registerHealthChecks(cfg config.HealthConfig, databasePath string) {
+	healthChecks = health.NewRegistry(cfg.Timeout.Duration)
+
+	healthChecks.Register(health.Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			return "", database.Db.PingContext(ctx)
+		},
+	})
+	healthChecks.Register(health.Check{
+		Name:     "schema",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			return database.CheckSchema(ctx, database.Db)
+		},
+	})
+	healthChecks.Register(health.Check{
+		Name:     "disk",
+		Critical: true,
+		Run:      health.DiskSpace(filepath.Dir(databasePath), uint64(cfg.MinFreeDiskMB)<<20),
+	})
+}
+
+// livezHandler reports that the process is running and able to serve HTTP.
+// It checks no dependencies, so an outage never gets the process restarted.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusPass, CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+	writeHealthReport(w, r, report, false)
+}
+
+// readyzHandler reports whether the critical dependencies are usable, so
+// that traffic is only routed to instances that can serve it.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, healthChecks.Run(r.Context(), true), r.URL.Query().Has("verbose"))
+}
+
+// healthHandler runs every check. Add ?verbose for per-check results.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, healthChecks.Run(r.Context(), false), r.URL.Query().Has("verbose"))
+}
+
+// isProbe reports whether path is polled by monitoring rather than users.
+func isProbe(path string) bool {
+	switch path {
+	case "/livez", "/readyz", "/healthz", "/health", "/metrics":
+		return true
+	}
+	return false
+}
+
+func writeHealthReport(w http.ResponseWriter, r *http.Request, report health.Report, verbose bool) {
+	if !verbose {
+		report.Checks = nil
+	}
+	statusCode := http.StatusOK
+	if report.Status == health.StatusFail {
+		statusCode = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, statusCode, report)
+}
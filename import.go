@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"journal-lite/internal/config"
+	"journal-lite/internal/importer"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImportUpload limits uploaded exports. Day One and Journey exports
+// include photos, which are not imported but still have to be uploaded.
+const maxImportUpload = 512 << 20
+
+var importFormatLabels = map[string]string{
+	"dayone":   "Day One (JSON export)",
+	"journey":  "Journey (JSON export)",
+	"markdown": "Markdown files with front matter",
+}
+
+type importFormat struct {
+	Name  string
+	Label string
+}
+
+func importPageHandler(w http.ResponseWriter, r *http.Request) {
+	var formats []importFormat
+	for _, name := range importer.Formats() {
+		label := importFormatLabels[name]
+		if label == "" {
+			label = name
+		}
+		formats = append(formats, importFormat{Name: name, Label: label})
+	}
+	renderTemplate(w, r, "import", map[string]any{"Formats": formats})
+}
+
+// importHandler imports an uploaded export, or previews the import when
+// dry_run is true, and renders the report.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := ctx.Value("userID").(string)
+	accountId, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		handleError(w, r, "Invalid session", http.StatusUnauthorized)
+		return
+	}
+
+	// Large exports take longer to upload than the server's read timeout.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			handleError(w, r, fmt.Sprintf("The file is larger than %d MB.", maxImportUpload>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		handleError(w, r, "Could not read the upload.", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		handleError(w, r, "Choose a file to import.", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if _, ok := importer.Lookup(format); !ok {
+		handleError(w, r, "Unknown import format.", http.StatusBadRequest)
+		return
+	}
+	dryRun := r.FormValue("dry_run") == "true"
+
+	src := importer.ReaderSource(header.Filename, file, header.Size)
+	report, err := importService.Import(ctx, accountId, format, src, dryRun)
+	if err != nil {
+		slog.ErrorContext(ctx, "Import failed", "format", format, "error", err)
+		handleError(w, r, "The import failed and nothing was imported. Check that the file and format match.", http.StatusUnprocessableEntity)
+		return
+	}
+	slog.InfoContext(ctx, "Import finished", "format", format, "dry_run", dryRun,
+		"total", report.Total, "imported", report.Imported, "duplicates", report.Duplicates, "invalid", report.Invalid)
+	renderTemplate(w, r, "import-report", report)
+}
+
+// runImportCommand imports an export from a file or directory into an
+// account and prints a summary, or the whole report as JSON.
+func runImportCommand(_ config.Config, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	username := flags.String("account", "", "username of the account to import into")
+	format := flags.String("format", "", "export format: "+strings.Join(importer.Formats(), ", "))
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without writing anything")
+	asJSON := flags.Bool("json", false, "print the report with every entry as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] import --account NAME --format FORMAT [--dry-run] [--json] PATH")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" || *format == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if _, ok := importer.Lookup(*format); !ok {
+		fmt.Fprintf(os.Stderr, "Unknown format %q. Formats: %s\n", *format, strings.Join(importer.Formats(), ", "))
+		return 2
+	}
+
+	ctx := context.Background()
+	account, err := accountService.GetAccountByUsername(ctx, *username)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "No account named %q\n", *username)
+		return 1
+	}
+	if err != nil {
+		slog.Error("Could not load account", "error", err)
+		return 1
+	}
+
+	report, err := importService.Import(ctx, account.Id, *format, importer.PathSource(flags.Arg(0)), *dryRun)
+	if err != nil {
+		slog.Error("Import failed; nothing was imported", "error", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(report)
+	}
+	for _, entry := range report.Entries {
+		if entry.Status == importer.StatusInvalid {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", entry.Source, entry.Reason)
+		}
+	}
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d of %d entries (%d duplicates, %d invalid)\n", verb, report.Imported, report.Total, report.Duplicates, report.Invalid)
+	return 0
+}
@@ -1,12 +1,34 @@
 package accounts
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Account struct {
+	Id           int64  `json:"id"`
 	Username     string `json:"username"`
 	PasswordHash string `json:"password_hash"`
+	CreatedAt    string `json:"created_at"`
+	// DisabledAt is set while an operator has disabled the account.
+	DisabledAt string `json:"disabled_at,omitempty"`
+	// TimeZone is the IANA name of the zone whose days the account's
+	// memories follow. Empty means the server's.
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// Location returns the account's time zone, or the server's if it has none
+// or it is no longer known.
+func (a Account) Location() *time.Location {
+	if a.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(a.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
 }
 
 func HashPassword(password string) (string, error) {
@@ -0,0 +1,10 @@
+package accounts
+
+// Identity links an account to a subject at an external OpenID Connect issuer.
+type Identity struct {
+	Id        int64  `json:"id"`
+	AccountId int64  `json:"account_id"`
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	CreatedAt string `json:"created_at"`
+}
@@ -1,15 +1,46 @@
 package auth
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"journal-lite/internal/tracing"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-const secret = "your-secret-key" //  Use environment variable in production
+// Authenticator issues and validates session tokens and signed values with
+// a shared secret.
+type Authenticator struct {
+	secret        []byte
+	tokenLifetime time.Duration
+}
+
+// NewAuthenticator creates an Authenticator. An empty secret is replaced by a
+// random one, which invalidates sessions whenever the process restarts.
+func NewAuthenticator(secret string, tokenLifetime time.Duration) (*Authenticator, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Authenticator{secret: key, tokenLifetime: tokenLifetime}, nil
+}
+
+// TokenLifetime is how long issued session tokens stay valid.
+func (a *Authenticator) TokenLifetime() time.Duration {
+	return a.tokenLifetime
+}
 
 type Account struct {
 	Id           string
@@ -22,21 +53,27 @@ type MyCustomClaims struct {
 	jwt.RegisteredClaims
 }
 
-func Login(db *sql.DB, username string, password string) (string, error) {
+func (a *Authenticator) Login(ctx context.Context, db *sql.DB, username string, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "Authenticator.Login")
+	defer span.End()
+
 	var account Account
 
-	err := db.QueryRow("SELECT id, username, password_hash FROM accounts WHERE username = ?", username).
+	err := db.QueryRowContext(ctx, "SELECT id, username, password_hash FROM accounts WHERE username = ? AND disabled_at = ''", username).
 		Scan(&account.Id, &account.Username, &account.PasswordHash)
 	if err != nil {
 		return "", errors.New("Invalid username or password.")
 	}
 
+	// bcrypt is deliberately slow, so it gets a span of its own.
+	_, bcryptSpan := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
 	err = bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password))
+	bcryptSpan.End()
 	if err != nil {
 		return "", errors.New("Invalid username or password.")
 	}
 
-	token, err := generateToken(account)
+	token, err := a.generateToken(account)
 	if err != nil {
 		return "", errors.New("Error occurred while generating the token.")
 	}
@@ -44,8 +81,14 @@ func Login(db *sql.DB, username string, password string) (string, error) {
 	return token, nil
 }
 
-func generateToken(account Account) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
+// IssueToken signs a session token for an account that was authenticated by
+// other means, such as single sign-on.
+func (a *Authenticator) IssueToken(accountId int64, username string) (string, error) {
+	return a.generateToken(Account{Id: strconv.FormatInt(accountId, 10), Username: username})
+}
+
+func (a *Authenticator) generateToken(account Account) (string, error) {
+	expirationTime := time.Now().Add(a.tokenLifetime)
 
 	claims := MyCustomClaims{
 		UserID: account.Id,
@@ -58,17 +101,39 @@ func generateToken(account Account) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(secret)) //  byte slice of secret
+	signedToken, err := token.SignedString(a.secret)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-func ValidateToken(tokenString string) (*MyCustomClaims, error) {
+// SignValue appends an HMAC of value so it can be stored client side, for
+// example in a cookie, without being tampered with.
+func (a *Authenticator) SignValue(value string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(value))
+	return value + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignedValue returns the value signed by SignValue, or an error if the
+// signature does not match.
+func (a *Authenticator) VerifySignedValue(signed string) (string, error) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", errors.New("invalid signed value")
+	}
+	value := signed[:i]
+	if !hmac.Equal([]byte(a.SignValue(value)), []byte(signed)) {
+		return "", errors.New("invalid signed value")
+	}
+	return value, nil
+}
+
+func (a *Authenticator) ValidateToken(tokenString string) (*MyCustomClaims, error) {
 	claims := &MyCustomClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil //  Return the secret key
+		return a.secret, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,374 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	_ "modernc.org/sqlite" // Import the SQLite driver
+)
+
+const (
+	filePrefix = "journal-"
+	// timeLayout names snapshots to the millisecond, so that backups taken
+	// within the same second do not collide.
+	timeLayout = "20060102T150405.000Z"
+	// secondsLayout is how snapshots were named before, to the second.
+	secondsLayout = "20060102T150405Z"
+)
+
+// Options configure where snapshots go and how they are stored.
+type Options struct {
+	Dir string
+	// Retain is how many snapshots to keep; older ones are deleted after
+	// every successful backup. Zero keeps all of them.
+	Retain   int
+	Compress bool
+	// Recipient, if set, encrypts snapshots to an age public key. The
+	// matching identity is only needed to restore.
+	Recipient age.Recipient
+	// Validate, if set, runs against every new snapshot after the integrity
+	// check, for example to confirm the schema.
+	Validate func(ctx context.Context, db *sql.DB) error
+}
+
+// Snapshot describes one backup file.
+type Snapshot struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	Size       int64     `json:"size"`
+	Compressed bool      `json:"compressed"`
+	Encrypted  bool      `json:"encrypted"`
+}
+
+// Manager takes online snapshots of a live database.
+type Manager struct {
+	db   *sql.DB
+	opts Options
+}
+
+func NewManager(db *sql.DB, opts Options) *Manager {
+	return &Manager{db: db, opts: opts}
+}
+
+// Create writes a consistent snapshot with VACUUM INTO while the database
+// stays available, verifies it, compresses and encrypts it as configured and
+// then applies the retention policy.
+func (m *Manager) Create(ctx context.Context) (Snapshot, error) {
+	if err := os.MkdirAll(m.opts.Dir, 0o700); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	raw := filepath.Join(m.opts.Dir, ".vacuum-"+createdAt.Format(timeLayout)+".db")
+	defer os.Remove(raw)
+
+	if _, err := m.db.ExecContext(ctx, "VACUUM INTO ?", raw); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if err := verify(ctx, raw, m.opts.Validate); err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot failed verification: %w", err)
+	}
+
+	snapshot := Snapshot{
+		CreatedAt:  createdAt,
+		Compressed: m.opts.Compress,
+		Encrypted:  m.opts.Recipient != nil,
+	}
+	snapshot.Name = fileName(snapshot)
+	snapshot.Path = filepath.Join(m.opts.Dir, snapshot.Name)
+
+	if err := m.encode(raw, snapshot.Path); err != nil {
+		return Snapshot{}, err
+	}
+	info, err := os.Stat(snapshot.Path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot.Size = info.Size()
+
+	if err := m.rotate(); err != nil {
+		return snapshot, fmt.Errorf("backup written but rotation failed: %w", err)
+	}
+	return snapshot, nil
+}
+
+// encode copies src to dst through gzip and age as configured. dst only
+// appears once it is complete.
+func (m *Manager) encode(src string, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	var w io.WriteCloser = nopCloser{out}
+	if m.opts.Recipient != nil {
+		if w, err = age.Encrypt(out, m.opts.Recipient); err != nil {
+			return fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+	}
+	encrypted := w
+	if m.opts.Compress {
+		w = gzip.NewWriter(encrypted)
+	}
+
+	if _, err = io.Copy(w, in); err != nil {
+		return err
+	}
+	if m.opts.Compress {
+		if err = w.Close(); err != nil {
+			return err
+		}
+	}
+	if err = encrypted.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if _, err = os.Lstat(dst); err == nil {
+		return fmt.Errorf("backup %s already exists", filepath.Base(dst))
+	}
+	return os.Rename(tmp, dst)
+}
+
+// List returns the snapshots in the backup directory, newest first.
+func (m *Manager) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(m.opts.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		snapshot, ok := parseFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			snapshot.Size = info.Size()
+		}
+		snapshot.Path = filepath.Join(m.opts.Dir, snapshot.Name)
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func (m *Manager) rotate() error {
+	if m.opts.Retain <= 0 {
+		return nil
+	}
+	snapshots, err := m.List()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := m.opts.Retain; i < len(snapshots); i++ {
+		errs = append(errs, os.Remove(snapshots[i].Path))
+	}
+	return errors.Join(errs...)
+}
+
+// Restore decodes the snapshot at path, checks its integrity and runs
+// validate against it before swapping it in as target. The previous database
+// is kept as target + ".pre-restore-" and the time of the restore, and its
+// path is returned; it is empty if there was no database. The server must
+// not be running.
+func Restore(ctx context.Context, path string, target string, identities []age.Identity, validate func(ctx context.Context, db *sql.DB) error) (string, error) {
+	snapshot := formatOf(path)
+	if snapshot.Encrypted && len(identities) == 0 {
+		return "", errors.New("snapshot is encrypted; an age identity is required")
+	}
+
+	tmp := target + ".restore"
+	if err := decode(path, tmp, snapshot, identities); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := verify(ctx, tmp, validate); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("snapshot failed verification, database left unchanged: %w", err)
+	}
+
+	previous := target + ".pre-restore-" + time.Now().UTC().Format(timeLayout)
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if _, err := os.Lstat(previous + suffix); err == nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("%s already exists, database left unchanged", previous+suffix)
+		}
+	}
+	// Move the database together with its WAL files, so that the previous
+	// state can still be opened.
+	moved := false
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(target+suffix, previous+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return "", err
+		}
+		moved = moved || (err == nil && suffix == "")
+	}
+	if !moved {
+		previous = ""
+	}
+	return previous, os.Rename(tmp, target)
+}
+
+func decode(src string, dst string, snapshot Snapshot, identities []age.Identity) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if snapshot.Encrypted {
+		if r, err = age.Decrypt(r, identities...); err != nil {
+			return fmt.Errorf("failed to decrypt snapshot: %w", err)
+		}
+	}
+	if snapshot.Compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to decompress snapshot: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Verify checks the integrity of the snapshot at path without restoring it.
+func Verify(ctx context.Context, path string, identities []age.Identity, validate func(ctx context.Context, db *sql.DB) error) error {
+	snapshot := formatOf(path)
+	if snapshot.Encrypted && len(identities) == 0 {
+		return errors.New("snapshot is encrypted; an age identity is required")
+	}
+	tmp, err := os.CreateTemp("", "journal-verify-*.db")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := decode(path, tmp.Name(), snapshot, identities); err != nil {
+		return err
+	}
+	return verify(ctx, tmp.Name(), validate)
+}
+
+// verify opens the database file read-only and runs PRAGMA integrity_check
+// and the optional validate function against it.
+func verify(ctx context.Context, path string, validate func(ctx context.Context, db *sql.DB) error) error {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+	if validate != nil {
+		return validate(ctx, db)
+	}
+	return nil
+}
+
+func fileName(s Snapshot) string {
+	name := filePrefix + s.CreatedAt.Format(timeLayout) + ".db"
+	if s.Compressed {
+		name += ".gz"
+	}
+	if s.Encrypted {
+		name += ".age"
+	}
+	return name
+}
+
+func parseFileName(name string) (Snapshot, bool) {
+	snapshot := Snapshot{Name: name}
+	rest, ok := strings.CutPrefix(name, filePrefix)
+	if !ok {
+		return snapshot, false
+	}
+	rest, snapshot.Encrypted = strings.CutSuffix(rest, ".age")
+	rest, snapshot.Compressed = strings.CutSuffix(rest, ".gz")
+	rest, ok = strings.CutSuffix(rest, ".db")
+	if !ok {
+		return snapshot, false
+	}
+	createdAt, err := time.Parse(timeLayout, rest)
+	if err != nil {
+		if createdAt, err = time.Parse(secondsLayout, rest); err != nil {
+			return snapshot, false
+		}
+	}
+	snapshot.CreatedAt = createdAt
+	return snapshot, true
+}
+
+// formatOf reads compression and encryption from the file name suffixes, so
+// that renamed snapshots can still be restored.
+func formatOf(path string) Snapshot {
+	name := filepath.Base(path)
+	snapshot := Snapshot{Name: name, Path: path}
+	name, snapshot.Encrypted = strings.CutSuffix(name, ".age")
+	_, snapshot.Compressed = strings.CutSuffix(name, ".gz")
+	return snapshot
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+func openDB(t *testing.T, path string, notes ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS notes (body TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, note := range notes {
+		if _, err := db.Exec("INSERT INTO notes (body) VALUES (?)", note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func readNotes(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT body FROM notes ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var notes []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			t.Fatal(err)
+		}
+		notes = append(notes, body)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return notes
+}
+
+func TestCreateVerifyRestore(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, opts := range map[string]Options{
+		"plain":                 {},
+		"compressed":            {Compress: true},
+		"encrypted":             {Recipient: identity.Recipient()},
+		"compressed, encrypted": {Compress: true, Recipient: identity.Recipient()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			db := openDB(t, filepath.Join(dir, "journal.db"), "first", "second")
+			opts.Dir = filepath.Join(dir, "backups")
+			var validated int
+			opts.Validate = func(ctx context.Context, db *sql.DB) error {
+				validated++
+				return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Err()
+			}
+
+			snapshot, err := NewManager(db, opts).Create(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if validated != 1 {
+				t.Errorf("Validate ran %d times, want once", validated)
+			}
+			if snapshot.Compressed != opts.Compress || snapshot.Encrypted != (opts.Recipient != nil) || snapshot.Size == 0 {
+				t.Errorf("snapshot = %+v, want it stored as configured", snapshot)
+			}
+			if parsed, ok := parseFileName(snapshot.Name); !ok || !parsed.CreatedAt.Equal(snapshot.CreatedAt.Truncate(time.Millisecond)) {
+				t.Errorf("parseFileName(%q) = %+v, %v", snapshot.Name, parsed, ok)
+			}
+
+			identities := []age.Identity{identity}
+			if err := Verify(ctx, snapshot.Path, identities, nil); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if snapshot.Encrypted {
+				if err := Verify(ctx, snapshot.Path, nil, nil); err == nil {
+					t.Error("Verify of an encrypted snapshot without an identity succeeded")
+				}
+			}
+
+			target := filepath.Join(dir, "restored.db")
+			openDB(t, target, "replaced").Close()
+			previous, err := Restore(ctx, snapshot.Path, target, identities, opts.Validate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readNotes(t, target); !slices.Equal(got, []string{"first", "second"}) {
+				t.Errorf("restored notes = %q", got)
+			}
+			if got := readNotes(t, previous); !slices.Equal(got, []string{"replaced"}) {
+				t.Errorf("previous database %s holds %q, want the replaced notes", previous, got)
+			}
+		})
+	}
+}
+
+func TestCreateKeepsBackupsTakenInTheSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, filepath.Join(dir, "journal.db"), "note")
+	m := NewManager(db, Options{Dir: filepath.Join(dir, "backups"), Retain: 3})
+
+	var created []string
+	for range 5 {
+		snapshot, err := m.Create(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, snapshot.Name)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	snapshots, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, snapshot := range snapshots {
+		listed = append(listed, snapshot.Name)
+	}
+	slices.Reverse(created)
+	if want := created[:3]; !slices.Equal(listed, want) {
+		t.Errorf("List after rotation = %q, want the newest three %q", listed, want)
+	}
+}
+
+func TestEncodeRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.db")
+	openDB(t, src, "note").Close()
+	dst := filepath.Join(dir, "journal-20260101T120000.000Z.db")
+	if err := os.WriteFile(dst, []byte("earlier"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&Manager{}).encode(src, dst); err == nil {
+		t.Fatal("encode over an existing backup succeeded")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "earlier" {
+		t.Errorf("existing backup was overwritten")
+	}
+}
+
+func TestRestoreKeepsEveryPreviousDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := openDB(t, filepath.Join(dir, "journal.db"), "snapshot")
+	snapshot, err := NewManager(db, Options{Dir: filepath.Join(dir, "backups")}).Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "target.db")
+	openDB(t, target, "oldest").Close()
+	first, err := Restore(ctx, snapshot.Path, target, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	second, err := Restore(ctx, snapshot.Path, target, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second || !strings.HasPrefix(first, target+".pre-restore-") {
+		t.Errorf("previous databases kept as %q and %q", first, second)
+	}
+	if got := readNotes(t, first); !slices.Equal(got, []string{"oldest"}) {
+		t.Errorf("first previous database holds %q, want the oldest notes", got)
+	}
+	if got := readNotes(t, second); !slices.Equal(got, []string{"snapshot"}) {
+		t.Errorf("second previous database holds %q, want the first restore", got)
+	}
+
+	fresh := filepath.Join(dir, "fresh.db")
+	if previous, err := Restore(ctx, snapshot.Path, fresh, nil, nil); err != nil || previous != "" {
+		t.Errorf("Restore without a database = %q, %v, want no previous database", previous, err)
+	}
+}
+
+func TestRestoreLeavesTheDatabaseAloneWhenTheSnapshotIsBad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	target := filepath.Join(dir, "journal.db")
+	openDB(t, target, "kept").Close()
+	corrupt := filepath.Join(dir, "journal-20260101T120000.000Z.db")
+	if err := os.WriteFile(corrupt, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(ctx, corrupt, nil, nil); err == nil {
+		t.Error("Verify of a corrupt snapshot succeeded")
+	}
+	if _, err := Restore(ctx, corrupt, target, nil, nil); err == nil {
+		t.Error("Restore of a corrupt snapshot succeeded")
+	}
+	rejected := errors.New("wrong schema")
+	db := openDB(t, filepath.Join(dir, "other.db"), "other")
+	snapshot, err := NewManager(db, Options{Dir: filepath.Join(dir, "backups")}).Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(ctx, snapshot.Path, target, nil, func(context.Context, *sql.DB) error { return rejected }); !errors.Is(err, rejected) {
+		t.Errorf("Restore with a failing validation: got %v, want %v", err, rejected)
+	}
+
+	if got := readNotes(t, target); !slices.Equal(got, []string{"kept"}) {
+		t.Errorf("database holds %q after failed restores, want it unchanged", got)
+	}
+	if matches, _ := filepath.Glob(target + ".pre-restore-*"); len(matches) != 0 {
+		t.Errorf("failed restores left %q", matches)
+	}
+}
+
+func TestParseFileNameReadsSecondNames(t *testing.T) {
+	snapshot, ok := parseFileName("journal-20260101T120000Z.db.gz.age")
+	if !ok || !snapshot.CreatedAt.Equal(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)) || !snapshot.Compressed || !snapshot.Encrypted {
+		t.Errorf("parseFileName = %+v, %v", snapshot, ok)
+	}
+}
@@ -0,0 +1,175 @@
+// Package client talks to a journal-lite server through its JSON API with a
+// personal access token.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"journal-lite/internal/posts"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// Client sends authenticated requests to one server.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func New(baseURL string, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// BaseURL is the address of the server the client talks to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Error is an error response from the API.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return e.Message
+}
+
+// PostList is one page of posts.
+type PostList struct {
+	Posts      []posts.Post `json:"posts"`
+	PageNumber int64        `json:"page_number"`
+	PageSize   int64        `json:"page_size"`
+}
+
+type postInput struct {
+	Content *string   `json:"content,omitempty"`
+	Tags    *[]string `json:"tags,omitempty"`
+}
+
+// ListPosts returns the newest posts matching params. AccountId is ignored;
+// the server uses the account of the token.
+func (c *Client) ListPosts(ctx context.Context, params posts.QueryParams) (PostList, error) {
+	query := url.Values{}
+	set := func(key string, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("searchText", params.SearchText)
+	set("dateFrom", params.DateFrom)
+	set("dateTo", params.DateTo)
+	set("tag", params.Tag)
+	if params.PageNumber > 0 {
+		query.Set("pageNumber", strconv.FormatInt(params.PageNumber, 10))
+	}
+	if params.PageSize > 0 {
+		query.Set("pageSize", strconv.FormatInt(params.PageSize, 10))
+	}
+
+	var list PostList
+	path := apiPrefix + "/posts"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	_, err := c.do(ctx, http.MethodGet, path, nil, nil, &list)
+	return list, err
+}
+
+// GetPost returns a post and its ETag, which UpdatePost needs.
+func (c *Client) GetPost(ctx context.Context, id int64) (posts.Post, string, error) {
+	var post posts.Post
+	header, err := c.do(ctx, http.MethodGet, postPath(id), nil, nil, &post)
+	if err != nil {
+		return post, "", err
+	}
+	return post, header.Get("ETag"), nil
+}
+
+func (c *Client) CreatePost(ctx context.Context, content string, tags []string) (posts.Post, error) {
+	var post posts.Post
+	_, err := c.do(ctx, http.MethodPost, apiPrefix+"/posts", nil, postInput{Content: &content, Tags: &tags}, &post)
+	return post, err
+}
+
+// UpdatePost replaces the content and tags of a post. The update is refused
+// with status 412 if the post changed since etag was read.
+func (c *Client) UpdatePost(ctx context.Context, id int64, etag string, content string, tags []string) (posts.Post, error) {
+	var post posts.Post
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	_, err := c.do(ctx, http.MethodPatch, postPath(id), header, postInput{Content: &content, Tags: &tags}, &post)
+	return post, err
+}
+
+func postPath(id int64) string {
+	return apiPrefix + "/posts/" + strconv.FormatInt(id, 10)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, header http.Header, in any, out any) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{Status: resp.StatusCode}
+		var envelope struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope) == nil {
+			apiErr.Code, apiErr.Message = envelope.Error.Code, envelope.Error.Message
+		}
+		return resp.Header, apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, fmt.Errorf("reading response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
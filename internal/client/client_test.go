@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"journal-lite/internal/posts"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// request is what the test server received.
+type request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   string
+}
+
+// newTestServer answers every request with respond and records it.
+func newTestServer(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) (*Client, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Clone(), string(body)})
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL+"/", "jl_secret"), &requests
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestListPosts(t *testing.T) {
+	c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"posts":       []posts.Post{{Id: 7, Content: "Hello", Tags: []string{"a"}}},
+			"page_number": 2,
+			"page_size":   5,
+		})
+	})
+
+	list, err := c.ListPosts(context.Background(), posts.QueryParams{AccountId: 99, SearchText: "run & swim", Tag: "health", PageNumber: 2, PageSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Posts) != 1 || list.Posts[0].Id != 7 || list.Posts[0].Content != "Hello" || list.PageNumber != 2 || list.PageSize != 5 {
+		t.Errorf("ListPosts = %+v", list)
+	}
+
+	got := (*requests)[0]
+	if got.Method != "GET" || got.Path != "/api/v1/posts" || got.Query != "pageNumber=2&pageSize=5&searchText=run+%26+swim&tag=health" {
+		t.Errorf("request = %s %s?%s", got.Method, got.Path, got.Query)
+	}
+	if got.Header.Get("Authorization") != "Bearer jl_secret" || got.Header.Get("Accept") != "application/json" {
+		t.Errorf("headers = %v", got.Header)
+	}
+
+	if _, err := c.ListPosts(context.Background(), posts.QueryParams{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*requests)[1]; got.Query != "" || got.Body != "" || got.Header.Get("Content-Type") != "" {
+		t.Errorf("request without parameters = %+v", got)
+	}
+}
+
+func TestCreateAndUpdatePost(t *testing.T) {
+	c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		status := http.StatusOK
+		if r.Method == "POST" {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, posts.Post{Id: 3, Content: "Saved"})
+	})
+	ctx := context.Background()
+
+	post, err := c.CreatePost(ctx, "New entry", []string{"walk"})
+	if err != nil || post.Id != 3 {
+		t.Fatalf("CreatePost = %+v, %v", post, err)
+	}
+	post, etag, err := c.GetPost(ctx, 3)
+	if err != nil || post.Content != "Saved" || etag != `"v2"` {
+		t.Fatalf("GetPost = %+v, %q, %v", post, etag, err)
+	}
+	if _, err := c.UpdatePost(ctx, 3, etag, "Edited", []string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []struct {
+		method  string
+		path    string
+		body    string
+		ifMatch string
+	}{
+		{"POST", "/api/v1/posts", `{"content":"New entry","tags":["walk"]}`, ""},
+		{"GET", "/api/v1/posts/3", "", ""},
+		// Clearing the tags sends an empty list rather than leaving them out.
+		{"PATCH", "/api/v1/posts/3", `{"content":"Edited","tags":[]}`, `"v2"`},
+	} {
+		got := (*requests)[i]
+		if got.Method != want.method || got.Path != want.path || got.Body != want.body || got.Header.Get("If-Match") != want.ifMatch {
+			t.Errorf("request %d = %s %s %s If-Match %q\nwant %s %s %s If-Match %q", i, got.Method, got.Path, got.Body, got.Header.Get("If-Match"), want.method, want.path, want.body, want.ifMatch)
+		}
+		if hasBody := want.body != ""; hasBody != (got.Header.Get("Content-Type") == "application/json") {
+			t.Errorf("request %d has Content-Type %q", i, got.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		respond func(w http.ResponseWriter, r *http.Request)
+		status  int
+		code    string
+		message string
+	}{
+		{"API error", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]any{"error": map[string]string{"code": "precondition_failed", "message": "The post changed since it was read."}})
+		}, http.StatusPreconditionFailed, "precondition_failed", "The post changed since it was read."},
+		{"proxy error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "<html>Bad Gateway</html>", http.StatusBadGateway)
+		}, http.StatusBadGateway, "", "server responded 502 Bad Gateway"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestServer(t, tc.respond)
+			_, err := c.UpdatePost(context.Background(), 3, `"v1"`, "Edited", nil)
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("UpdatePost error = %v, want an *Error", err)
+			}
+			if apiErr.Status != tc.status || apiErr.Code != tc.code || apiErr.Error() != tc.message {
+				t.Errorf("error = %+v (%q)", apiErr, apiErr.Error())
+			}
+		})
+	}
+
+	t.Run("invalid response", func(t *testing.T) {
+		c, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "not JSON")
+		})
+		if _, _, err := c.GetPost(context.Background(), 3); err == nil || !strings.Contains(err.Error(), "reading response") {
+			t.Errorf("GetPost error = %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		c, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := c.ListPosts(ctx, posts.QueryParams{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("ListPosts error = %v, want %v", err, context.Canceled)
+		}
+		if len(*requests) != 0 {
+			t.Error("cancelled request was sent")
+		}
+	})
+}
+
+func TestDraftRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		tags    []string
+	}{
+		{"Went for a run.\n\nAlong the river.", []string{"health", "running"}},
+		{"Tags YAML would misread.", []string{"key: value", "yes", "#hash", `say "hi"`, "[list]"}},
+		{"No tags.", []string{}},
+		{"", []string{"empty"}},
+	} {
+		content, tags, err := ParseDraft(FormatDraft(tc.content, tc.tags))
+		if err != nil {
+			t.Errorf("ParseDraft(FormatDraft(%q, %q)): %v", tc.content, tc.tags, err)
+			continue
+		}
+		if content != tc.content || !slices.Equal(tags, tc.tags) {
+			t.Errorf("round trip of %q, %q = %q, %q", tc.content, tc.tags, content, tags)
+		}
+	}
+
+	// Without front matter everything is content.
+	content, tags, err := ParseDraft("  Just text.\n")
+	if err != nil || content != "Just text." || tags == nil || len(tags) != 0 {
+		t.Errorf("ParseDraft of plain text = %q, %q, %v", content, tags, err)
+	}
+}
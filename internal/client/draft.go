@@ -0,0 +1,49 @@
+package client
+
+import (
+	"journal-lite/internal/importer"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A draft is an entry as it is edited in $EDITOR: the tags in front matter,
+// then the content.
+//
+//	---
+//	tags: [health, running]
+//	---
+//
+//	Went for a run along the river.
+
+// FormatDraft renders content and tags for editing.
+func FormatDraft(content string, tags []string) string {
+	var b strings.Builder
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		// Quotes tags that YAML would otherwise read differently.
+		out, _ := yaml.Marshal(tag)
+		quoted[i] = strings.TrimSpace(string(out))
+	}
+	b.WriteString("---\ntags: [")
+	b.WriteString(strings.Join(quoted, ", "))
+	b.WriteString("]\n---\n\n")
+	if content != "" {
+		b.WriteString(strings.TrimRight(content, "\n"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ParseDraft reads back a draft. Text without front matter is all content.
+func ParseDraft(text string) (content string, tags []string, err error) {
+	meta, body, err := importer.SplitFrontMatter([]byte(text))
+	if err != nil {
+		return "", nil, err
+	}
+	tags = importer.MetaTags(meta["tags"])
+	if tags == nil {
+		tags = []string{}
+	}
+	return strings.TrimSpace(body), tags, nil
+}
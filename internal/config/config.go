@@ -0,0 +1,528 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds every runtime setting. Values are resolved in increasing order
+// of precedence from the defaults, the TOML config file, JOURNAL_* environment
+// variables and command line flags.
+//
+// Each field declares its environment variable and flag name in struct tags;
+// fields tagged secret:"true" are redacted by Redacted.
+type Config struct {
+	Server      ServerConfig      `toml:"server"`
+	Database    DatabaseConfig    `toml:"database"`
+	Auth        AuthConfig        `toml:"auth"`
+	OIDC        OIDCConfig        `toml:"oidc"`
+	Log         LogConfig         `toml:"log"`
+	Metrics     MetricsConfig     `toml:"metrics"`
+	Tracing     TracingConfig     `toml:"tracing"`
+	Health      HealthConfig      `toml:"health"`
+	Backup      BackupConfig      `toml:"backup"`
+	Replication ReplicationConfig `toml:"replication"`
+	Vault       VaultConfig       `toml:"vault"`
+	Admin       AdminConfig       `toml:"admin"`
+	Client      ClientConfig      `toml:"client"`
+}
+
+type ServerConfig struct {
+	Addr         string `toml:"addr" env:"JOURNAL_ADDR" flag:"addr" usage:"address to listen on"`
+	CookieSecure bool   `toml:"cookie_secure" env:"JOURNAL_COOKIE_SECURE" flag:"cookie-secure" usage:"mark cookies Secure (HTTPS only)"`
+	DevMode      bool   `toml:"dev_mode" env:"JOURNAL_DEV_MODE" flag:"dev" usage:"serve templates and static files from disk"`
+	PublicURL    string `toml:"public_url" env:"JOURNAL_PUBLIC_URL" flag:"public-url" usage:"URL the server is reached at, used for links in feeds; taken from each request if empty"`
+
+	ReadTimeout       Duration `toml:"read_timeout" env:"JOURNAL_READ_TIMEOUT" flag:"read-timeout" usage:"maximum time to read a request including its body"`
+	ReadHeaderTimeout Duration `toml:"read_header_timeout" env:"JOURNAL_READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"maximum time to read request headers"`
+	WriteTimeout      Duration `toml:"write_timeout" env:"JOURNAL_WRITE_TIMEOUT" flag:"write-timeout" usage:"maximum time to write a response"`
+	IdleTimeout       Duration `toml:"idle_timeout" env:"JOURNAL_IDLE_TIMEOUT" flag:"idle-timeout" usage:"how long idle keep-alive connections stay open"`
+	MaxHeaderBytes    int      `toml:"max_header_bytes" env:"JOURNAL_MAX_HEADER_BYTES" flag:"max-header-bytes" usage:"maximum size of request headers in bytes"`
+	ShutdownTimeout   Duration `toml:"shutdown_timeout" env:"JOURNAL_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to drain in-flight requests on SIGINT or SIGTERM"`
+}
+
+type DatabaseConfig struct {
+	Path string `toml:"path" env:"JOURNAL_DB_PATH" flag:"db" usage:"path to the SQLite database file"`
+}
+
+type AuthConfig struct {
+	JWTSecret            string   `toml:"jwt_secret" env:"JOURNAL_JWT_SECRET" flag:"jwt-secret" secret:"true" usage:"secret used to sign session tokens (random per process if empty)"`
+	TokenLifetime        Duration `toml:"token_lifetime" env:"JOURNAL_TOKEN_LIFETIME" flag:"token-lifetime" usage:"lifetime of session tokens and cookies"`
+	PasswordLoginEnabled bool     `toml:"password_login" env:"JOURNAL_PASSWORD_LOGIN" flag:"password-login" usage:"allow username and password login and registration"`
+}
+
+type OIDCConfig struct {
+	IssuerURL    string `toml:"issuer_url" env:"JOURNAL_OIDC_ISSUER_URL" flag:"oidc-issuer-url" usage:"OpenID Connect issuer; enables single sign-on"`
+	ClientID     string `toml:"client_id" env:"JOURNAL_OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"OpenID Connect client ID"`
+	ClientSecret string `toml:"client_secret" env:"JOURNAL_OIDC_CLIENT_SECRET" flag:"oidc-client-secret" secret:"true" usage:"OpenID Connect client secret"`
+	RedirectURL  string `toml:"redirect_url" env:"JOURNAL_OIDC_REDIRECT_URL" flag:"oidc-redirect-url" usage:"OpenID Connect callback URL"`
+}
+
+type LogConfig struct {
+	Level string `toml:"level" env:"JOURNAL_LOG_LEVEL" flag:"log-level" usage:"minimum log level: debug, info, warn or error"`
+}
+
+// SlogLevel returns the configured level, or info if it is invalid.
+func (c LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// MetricsConfig controls the Prometheus endpoint. It is served on its own
+// listener when Addr is set, on the main listener behind a bearer token when
+// only Token is set, and not at all otherwise.
+type MetricsConfig struct {
+	Addr  string `toml:"addr" env:"JOURNAL_METRICS_ADDR" flag:"metrics-addr" usage:"separate address to serve /metrics on, such as :9090"`
+	Token string `toml:"token" env:"JOURNAL_METRICS_TOKEN" flag:"metrics-token" secret:"true" usage:"bearer token required for /metrics on the main listener"`
+}
+
+// TracingConfig controls the OpenTelemetry exporter. Spans are only
+// exported when Endpoint is set.
+type TracingConfig struct {
+	Endpoint    string  `toml:"endpoint" env:"JOURNAL_OTLP_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP traces endpoint, such as http://otel-collector:4318/v1/traces"`
+	Headers     string  `toml:"headers" env:"JOURNAL_OTLP_HEADERS" flag:"otlp-headers" secret:"true" usage:"extra OTLP request headers as key1=value1,key2=value2"`
+	ServiceName string  `toml:"service_name" env:"JOURNAL_OTLP_SERVICE_NAME" flag:"otlp-service-name" usage:"service.name resource attribute"`
+	SampleRatio float64 `toml:"sample_ratio" env:"JOURNAL_OTLP_SAMPLE_RATIO" flag:"otlp-sample-ratio" usage:"fraction of new traces to sample, from 0 to 1"`
+}
+
+type HealthConfig struct {
+	Timeout       Duration `toml:"timeout" env:"JOURNAL_HEALTH_TIMEOUT" flag:"health-timeout" usage:"time limit for each health check"`
+	MinFreeDiskMB int      `toml:"min_free_disk_mb" env:"JOURNAL_HEALTH_MIN_FREE_DISK_MB" flag:"health-min-free-disk-mb" usage:"free space below which the database disk is reported unhealthy"`
+}
+
+type BackupConfig struct {
+	Dir          string   `toml:"dir" env:"JOURNAL_BACKUP_DIR" flag:"backup-dir" usage:"directory database snapshots are written to"`
+	Interval     Duration `toml:"interval" env:"JOURNAL_BACKUP_INTERVAL" flag:"backup-interval" usage:"time between scheduled backups; 0 disables them"`
+	Retain       int      `toml:"retain" env:"JOURNAL_BACKUP_RETAIN" flag:"backup-retain" usage:"number of snapshots to keep; 0 keeps all"`
+	Compress     bool     `toml:"compress" env:"JOURNAL_BACKUP_COMPRESS" flag:"backup-compress" usage:"gzip snapshots"`
+	AgeRecipient string   `toml:"age_recipient" env:"JOURNAL_BACKUP_AGE_RECIPIENT" flag:"backup-age-recipient" usage:"age public key (age1...) to encrypt snapshots to"`
+}
+
+// ReplicationConfig enables continuous replication of the database to a
+// directory or an S3 compatible bucket.
+type ReplicationConfig struct {
+	URL              string   `toml:"url" env:"JOURNAL_REPLICATION_URL" flag:"replication-url" usage:"replica location: a directory, file:///path or s3://bucket/prefix; replication is disabled if empty"`
+	Endpoint         string   `toml:"endpoint" env:"JOURNAL_REPLICATION_ENDPOINT" flag:"replication-endpoint" usage:"S3 endpoint URL, e.g. http://localhost:9000 for MinIO (AWS if empty)"`
+	Region           string   `toml:"region" env:"JOURNAL_REPLICATION_REGION" flag:"replication-region" usage:"S3 region"`
+	AccessKeyID      string   `toml:"access_key_id" env:"JOURNAL_REPLICATION_ACCESS_KEY_ID" flag:"replication-access-key-id" usage:"S3 access key ID"`
+	SecretAccessKey  string   `toml:"secret_access_key" env:"JOURNAL_REPLICATION_SECRET_ACCESS_KEY" flag:"replication-secret-access-key" secret:"true" usage:"S3 secret access key"`
+	SyncInterval     Duration `toml:"sync_interval" env:"JOURNAL_REPLICATION_SYNC_INTERVAL" flag:"replication-sync-interval" usage:"how often new WAL frames are shipped"`
+	SnapshotInterval Duration `toml:"snapshot_interval" env:"JOURNAL_REPLICATION_SNAPSHOT_INTERVAL" flag:"replication-snapshot-interval" usage:"how often a full snapshot starts a new generation"`
+	Retention        Duration `toml:"retention" env:"JOURNAL_REPLICATION_RETENTION" flag:"replication-retention" usage:"how far back point-in-time restore must reach"`
+}
+
+// VaultConfig mirrors one account's journal to a folder of daily notes, such
+// as an Obsidian or Logseq vault, in both directions.
+type VaultConfig struct {
+	Path       string   `toml:"path" env:"JOURNAL_VAULT_PATH" flag:"vault-path" usage:"folder of daily-note Markdown files kept in sync with the journal; sync is disabled if empty"`
+	Account    string   `toml:"account" env:"JOURNAL_VAULT_ACCOUNT" flag:"vault-account" usage:"username of the account whose journal is synced"`
+	FileFormat string   `toml:"file_format" env:"JOURNAL_VAULT_FILE_FORMAT" flag:"vault-file-format" usage:"Go time layout of daily note names: 2006-01-02 for Obsidian, 2006_01_02 for Logseq"`
+	Interval   Duration `toml:"interval" env:"JOURNAL_VAULT_INTERVAL" flag:"vault-interval" usage:"how often the journal is checked for changes made in the app"`
+}
+
+// AdminConfig protects the operator endpoints under /api/v1/admin.
+type AdminConfig struct {
+	Token string `toml:"token" env:"JOURNAL_ADMIN_TOKEN" flag:"admin-token" secret:"true" usage:"bearer token for the admin API; the admin API is disabled if empty"`
+}
+
+// ClientConfig is used by the journal command, which talks to a running
+// server through the JSON API instead of opening the database.
+type ClientConfig struct {
+	URL   string `toml:"url" env:"JOURNAL_URL" flag:"url" usage:"base URL of the server for the journal command; defaults to server.addr on localhost"`
+	Token string `toml:"token" env:"JOURNAL_TOKEN" flag:"token" secret:"true" usage:"personal access token the journal command authenticates with"`
+}
+
+// Duration is a time.Duration that reads and writes strings such as "24h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			CookieSecure:      true,
+			ReadTimeout:       Duration{15 * time.Second},
+			ReadHeaderTimeout: Duration{5 * time.Second},
+			WriteTimeout:      Duration{30 * time.Second},
+			IdleTimeout:       Duration{2 * time.Minute},
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   Duration{30 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Path: "local.db",
+		},
+		Auth: AuthConfig{
+			TokenLifetime:        Duration{24 * time.Hour},
+			PasswordLoginEnabled: true,
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: "http://localhost:8080/auth/oidc/callback",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "journal-lite",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			Timeout:       Duration{2 * time.Second},
+			MinFreeDiskMB: 100,
+		},
+		Backup: BackupConfig{
+			Dir:      "backups",
+			Retain:   7,
+			Compress: true,
+		},
+		Replication: ReplicationConfig{
+			Region:           "us-east-1",
+			SyncInterval:     Duration{time.Second},
+			SnapshotInterval: Duration{24 * time.Hour},
+			Retention:        Duration{72 * time.Hour},
+		},
+		Vault: VaultConfig{
+			FileFormat: "2006-01-02",
+			Interval:   Duration{5 * time.Second},
+		},
+	}
+}
+
+// Options are the command line switches that are not settings themselves.
+type Options struct {
+	ConfigFile  string
+	PrintConfig bool
+}
+
+// Load resolves the configuration for the given command line arguments
+// (without the program name) and returns the arguments left after the flags.
+// The config file is read from --config or JOURNAL_CONFIG if either is set.
+func Load(name string, args []string) (Config, Options, []string, error) {
+	cfg := Default()
+	var opts Options
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.ConfigFile, "config", os.Getenv("JOURNAL_CONFIG"), "path to a TOML config file")
+	flags.BoolVar(&opts.PrintConfig, "print-config", false, "print the resolved configuration with secrets redacted and exit")
+
+	// Flags only collect raw strings here; they are parsed like environment
+	// variables once the file has been applied.
+	flagValues := map[string]*settingFlag{}
+	eachField(&cfg, func(field reflect.StructField, value reflect.Value) {
+		if name := field.Tag.Get("flag"); name != "" {
+			usage := field.Tag.Get("usage") + " (env " + field.Tag.Get("env") + ")"
+			flagValues[name] = &settingFlag{isBool: value.Kind() == reflect.Bool}
+			flags.Var(flagValues[name], name, usage)
+		}
+	})
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, opts, nil, err
+	}
+
+	if opts.ConfigFile != "" {
+		md, err := toml.DecodeFile(opts.ConfigFile, &cfg)
+		if err != nil {
+			return cfg, opts, nil, fmt.Errorf("failed to read config file %s: %w", opts.ConfigFile, err)
+		}
+		// A misspelled key would otherwise leave its setting at the default
+		// without a word.
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return cfg, opts, nil, fmt.Errorf("unknown settings in config file %s: %s", opts.ConfigFile, strings.Join(keys, ", "))
+		}
+	}
+
+	var err error
+	eachField(&cfg, func(field reflect.StructField, value reflect.Value) {
+		if env := field.Tag.Get("env"); env != "" {
+			if raw, ok := os.LookupEnv(env); ok && err == nil {
+				if setErr := setValue(value, raw); setErr != nil {
+					err = fmt.Errorf("invalid %s: %w", env, setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return cfg, opts, nil, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		eachField(&cfg, func(field reflect.StructField, value reflect.Value) {
+			if field.Tag.Get("flag") == f.Name && err == nil {
+				if setErr := setValue(value, flagValues[f.Name].value); setErr != nil {
+					err = fmt.Errorf("invalid --%s: %w", f.Name, setErr)
+				}
+			}
+		})
+	})
+	if err != nil {
+		return cfg, opts, nil, err
+	}
+
+	return cfg, opts, flags.Args(), cfg.Validate()
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr: %w", err))
+	}
+	timeouts := []struct {
+		name string
+		d    Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"health.timeout", c.Health.Timeout},
+	}
+	for _, t := range timeouts {
+		if t.d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", t.name))
+		}
+	}
+	if c.Server.MaxHeaderBytes < 4096 {
+		errs = append(errs, errors.New("server.max_header_bytes must be at least 4096"))
+	}
+	if c.Server.PublicURL != "" {
+		if u, err := url.Parse(c.Server.PublicURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New("server.public_url must be an http or https URL"))
+		}
+	}
+	if strings.TrimSpace(c.Database.Path) == "" {
+		errs = append(errs, errors.New("database.path must not be empty"))
+	}
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+		errs = append(errs, errors.New("auth.jwt_secret must be at least 32 characters"))
+	}
+	if c.Auth.TokenLifetime.Duration < time.Minute || c.Auth.TokenLifetime.Duration > 365*24*time.Hour {
+		errs = append(errs, errors.New("auth.token_lifetime must be between 1m and 8760h"))
+	}
+
+	if c.OIDC.IssuerURL != "" {
+		if u, err := url.Parse(c.OIDC.IssuerURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.New("oidc.issuer_url must be an absolute URL"))
+		}
+		if c.OIDC.ClientID == "" {
+			errs = append(errs, errors.New("oidc.client_id is required when oidc.issuer_url is set"))
+		}
+		if u, err := url.Parse(c.OIDC.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.New("oidc.redirect_url must be an absolute URL"))
+		}
+	}
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.addr: %w", err))
+		} else if c.Metrics.Addr == c.Server.Addr {
+			errs = append(errs, errors.New("metrics.addr must differ from server.addr"))
+		}
+	}
+	if c.Metrics.Token != "" && len(c.Metrics.Token) < 16 {
+		errs = append(errs, errors.New("metrics.token must be at least 16 characters"))
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.New("tracing.endpoint must be an absolute URL"))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+
+	if c.Health.MinFreeDiskMB < 0 {
+		errs = append(errs, errors.New("health.min_free_disk_mb must not be negative"))
+	}
+
+	if strings.TrimSpace(c.Backup.Dir) == "" {
+		errs = append(errs, errors.New("backup.dir must not be empty"))
+	}
+	if c.Backup.Interval.Duration < 0 || (c.Backup.Interval.Duration > 0 && c.Backup.Interval.Duration < time.Minute) {
+		errs = append(errs, errors.New("backup.interval must be 0 or at least 1m"))
+	}
+	if c.Backup.Retain < 0 {
+		errs = append(errs, errors.New("backup.retain must not be negative"))
+	}
+	if c.Backup.AgeRecipient != "" && !strings.HasPrefix(c.Backup.AgeRecipient, "age1") {
+		errs = append(errs, errors.New("backup.age_recipient must be an age public key starting with age1"))
+	}
+	if c.Replication.URL != "" {
+		if u, err := url.Parse(c.Replication.URL); err != nil {
+			errs = append(errs, fmt.Errorf("replication.url: %w", err))
+		} else if u.Scheme == "s3" && u.Host == "" {
+			errs = append(errs, errors.New("replication.url must name a bucket, as in s3://bucket/prefix"))
+		} else if u.Scheme != "" && u.Scheme != "s3" && u.Scheme != "file" {
+			errs = append(errs, errors.New("replication.url must be a directory, a file:// URL or an s3:// URL"))
+		}
+		if c.Replication.Endpoint != "" {
+			if u, err := url.Parse(c.Replication.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				errs = append(errs, errors.New("replication.endpoint must be an http or https URL"))
+			}
+		}
+		if c.Replication.SyncInterval.Duration < 100*time.Millisecond {
+			errs = append(errs, errors.New("replication.sync_interval must be at least 100ms"))
+		}
+		if c.Replication.SnapshotInterval.Duration < time.Minute {
+			errs = append(errs, errors.New("replication.snapshot_interval must be at least 1m"))
+		}
+		if c.Replication.Retention.Duration < c.Replication.SnapshotInterval.Duration {
+			errs = append(errs, errors.New("replication.retention must not be shorter than replication.snapshot_interval"))
+		}
+	}
+	if c.Vault.Path != "" {
+		if c.Vault.Account == "" {
+			errs = append(errs, errors.New("vault.account must be set when vault.path is"))
+		}
+		if layout := c.Vault.FileFormat; !strings.Contains(layout, "2006") || !strings.Contains(layout, "01") || !strings.Contains(layout, "02") || strings.ContainsAny(layout, `/\`) {
+			errs = append(errs, errors.New("vault.file_format must be a Go time layout with year 2006, month 01 and day 02, and no slashes"))
+		}
+		if c.Vault.Interval.Duration < 100*time.Millisecond {
+			errs = append(errs, errors.New("vault.interval must be at least 100ms"))
+		}
+	}
+	if c.Client.URL != "" {
+		if u, err := url.Parse(c.Client.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New("client.url must be an http or https URL"))
+		} else if u.Scheme == "http" && !IsLoopback(u.Hostname()) {
+			errs = append(errs, errors.New("client.url must use https unless the server is on localhost"))
+		}
+	}
+	if c.Admin.Token != "" && len(c.Admin.Token) < 32 {
+		errs = append(errs, errors.New("admin.token must be at least 32 characters"))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, errors.New("log.level must be debug, info, warn or error"))
+	}
+	if !c.Auth.PasswordLoginEnabled && !c.SSOEnabled() {
+		errs = append(errs, errors.New("auth.password_login cannot be disabled unless oidc.issuer_url is set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SSOEnabled reports whether OpenID Connect login is configured.
+func (c Config) SSOEnabled() bool {
+	return c.OIDC.IssuerURL != ""
+}
+
+// Redacted returns a copy with every secret setting masked.
+func (c Config) Redacted() Config {
+	eachField(&c, func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString("REDACTED")
+		}
+	})
+	return c
+}
+
+// TOML renders the configuration as a config file.
+func (c Config) TOML() (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// settingFlag collects a flag's raw value; boolean settings may be given
+// without a value, as in --dev.
+type settingFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *settingFlag) String() string     { return f.value }
+func (f *settingFlag) Set(s string) error { f.value = s; return nil }
+func (f *settingFlag) IsBoolFlag() bool   { return f.isBool }
+
+// eachField calls fn for every setting in the two-level Config struct.
+func eachField(cfg *Config, fn func(field reflect.StructField, value reflect.Value)) {
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			fn(section.Type().Field(j), section.Field(j))
+		}
+	}
+}
+
+func setValue(value reflect.Value, raw string) error {
+	if d, ok := value.Addr().Interface().(*Duration); ok {
+		return d.UnmarshalText([]byte(raw))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// IsLoopback reports whether host names this machine, so that plain HTTP
+// to it never leaves the machine.
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
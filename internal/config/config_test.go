@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+[server]
+addr = ":1000"
+read_timeout = "1m"
+
+[log]
+level = "debug"
+
+[backup]
+retain = 3
+`)
+	t.Setenv("JOURNAL_ADDR", ":2000")
+	t.Setenv("JOURNAL_BACKUP_RETAIN", "4")
+	t.Setenv("JOURNAL_DEV_MODE", "true")
+
+	cfg, opts, args, err := Load("journal-lite", []string{"--config", path, "--addr", ":3000", "--dev=false", "user", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.ConfigFile != path || opts.PrintConfig {
+		t.Errorf("opts = %+v", opts)
+	}
+	if !slices.Equal(args, []string{"user", "list"}) {
+		t.Errorf("args = %q, want the command after the flags", args)
+	}
+
+	for _, tc := range []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env and file", cfg.Server.Addr, ":3000"},
+		{"false flag over true env", cfg.Server.DevMode, false},
+		{"env over file", cfg.Backup.Retain, 4},
+		{"file over default", cfg.Log.Level, "debug"},
+		{"file duration", cfg.Server.ReadTimeout.Duration, time.Minute},
+		{"default", cfg.Database.Path, "local.db"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadReadsTheConfigFileNamedInTheEnvironment(t *testing.T) {
+	t.Setenv("JOURNAL_CONFIG", writeConfigFile(t, "[database]\npath = \"from-env.db\"\n"))
+
+	cfg, _, _, err := Load("journal-lite", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Path != "from-env.db" {
+		t.Errorf("database.path = %q, want the file's", cfg.Database.Path)
+	}
+}
+
+func TestLoadRejectsUnknownSettings(t *testing.T) {
+	path := writeConfigFile(t, `
+[server]
+adr = ":1000"
+
+[bakup]
+dir = "elsewhere"
+`)
+	_, _, _, err := Load("journal-lite", []string{"--config", path})
+	if err == nil {
+		t.Fatal("Load with misspelled settings succeeded")
+	}
+	for _, key := range []string{"server.adr", "bakup"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not name %s", err, key)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"env", map[string]string{"JOURNAL_BACKUP_RETAIN": "many"}, nil, "JOURNAL_BACKUP_RETAIN"},
+		{"flag", nil, []string{"--read-timeout", "soon"}, "--read-timeout"},
+		{"file", nil, []string{"--config", filepath.Join(t.TempDir(), "missing.toml")}, "missing.toml"},
+		{"validation", map[string]string{"JOURNAL_OTLP_SAMPLE_RATIO": "2"}, nil, "tracing.sample_ratio"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			_, _, _, err := Load("journal-lite", tc.args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load: got %v, want an error naming %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryInvalidSetting(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = "8080"
+	cfg.Auth.JWTSecret = "short"
+	cfg.Auth.PasswordLoginEnabled = false
+	cfg.Backup.Interval = Duration{time.Second}
+	cfg.Client.URL = "http://journal.example.com"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, setting := range []string{"server.addr", "auth.jwt_secret", "auth.password_login", "backup.interval", "client.url"} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Errorf("error does not name %s:\n%v", setting, err)
+		}
+	}
+
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Default().Validate() = %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "jwt-secret-value"
+	cfg.OIDC.ClientID = "client-id-value"
+	cfg.OIDC.ClientSecret = "oidc-secret-value"
+	cfg.Metrics.Token = "metrics-token-value"
+	cfg.Tracing.Headers = "authorization=otlp-secret-value"
+	cfg.Replication.SecretAccessKey = "s3-secret-value"
+	cfg.Admin.Token = "admin-token-value"
+	cfg.Client.Token = "client-token-value"
+
+	redacted := cfg.Redacted()
+	for name, value := range map[string]string{
+		"auth.jwt_secret":               redacted.Auth.JWTSecret,
+		"oidc.client_secret":            redacted.OIDC.ClientSecret,
+		"metrics.token":                 redacted.Metrics.Token,
+		"tracing.headers":               redacted.Tracing.Headers,
+		"replication.secret_access_key": redacted.Replication.SecretAccessKey,
+		"admin.token":                   redacted.Admin.Token,
+		"client.token":                  redacted.Client.Token,
+	} {
+		if value != "REDACTED" {
+			t.Errorf("%s = %q, want it redacted", name, value)
+		}
+	}
+	if redacted.OIDC.ClientID != cfg.OIDC.ClientID || redacted.Server.Addr != cfg.Server.Addr {
+		t.Error("Redacted changed settings that are not secret")
+	}
+	if cfg.Admin.Token != "admin-token-value" {
+		t.Error("Redacted changed the original")
+	}
+	if Default().Redacted().Admin.Token != "" {
+		t.Error("Redacted filled in an unset secret")
+	}
+
+	out, err := redacted.TOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"jwt-secret-value", "oidc-secret-value", "metrics-token-value", "otlp-secret-value", "s3-secret-value", "admin-token-value", "client-token-value"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed config contains %q", secret)
+		}
+	}
+}
+
+// TestPrintedConfigLoads checks that --print-config output can be used as a
+// config file as it is.
+func TestPrintedConfigLoads(t *testing.T) {
+	cfg := Default()
+	cfg.Server.PublicURL = "https://journal.example.com"
+	cfg.Backup.Interval = Duration{6 * time.Hour}
+	out, err := cfg.TOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _, _, err := Load("journal-lite", []string{"--config", writeConfigFile(t, out)})
+	if err != nil {
+		t.Fatalf("Load of the printed config: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("loaded %+v\nwant %+v", loaded, cfg)
+	}
+}
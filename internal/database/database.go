@@ -1,11 +1,18 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
-	"log"
+	"journal-lite/internal/tracing"
+	"strings"
 	"sync"
 
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	_ "modernc.org/sqlite" // Import the SQLite driver
 )
 
@@ -15,30 +22,53 @@ var (
 	errDB error // Renamed to avoid shadowing the 'err' inside once.Do
 )
 
-func Initialize() error {
+// Options tune how the database is opened.
+type Options struct {
+	// ManualCheckpoints turns off SQLite's automatic WAL checkpoints, so that
+	// the database file only changes when the replicator checkpoints it.
+	ManualCheckpoints bool
+	// SkipMigrations leaves the schema as it is, for the migrate command.
+	SkipMigrations bool
+}
+
+// Initialize opens the SQLite database file at path in WAL mode and migrates
+// the schema to the latest version.
+func Initialize(path string, opts Options) error {
 	once.Do(func() {
-		// Use a local SQLite database file.
-		connString := "file:local.db?_pragma=foreign_keys(1)" // Enable foreign keys
+		connString := "file:" + path + "?_pragma=foreign_keys(1)" + // Enable foreign keys
+			"&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+		if opts.ManualCheckpoints {
+			connString += "&_pragma=wal_autocheckpoint(0)"
+		}
 
 		var db *sql.DB
-		db, errDB = sql.Open("sqlite", connString)
+		// Statements are traced with their literals redacted; bound
+		// parameters are never recorded.
+		db, errDB = otelsql.Open("sqlite", connString,
+			otelsql.WithAttributes(semconv.DBSystemSqlite),
+			otelsql.WithSpanOptions(otelsql.SpanOptions{DisableQuery: true, OmitConnResetSession: true, OmitRows: true}),
+			otelsql.WithAttributesGetter(func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) []attribute.KeyValue {
+				if query == "" {
+					return nil
+				}
+				return []attribute.KeyValue{semconv.DBQueryText(tracing.RedactSQL(query))}
+			}),
+		)
 		if errDB != nil {
-			errDB = fmt.Errorf("failed to open db (local.db): %w", errDB)
-			log.Println(errDB)
+			errDB = fmt.Errorf("failed to open db (%s): %w", path, errDB)
 			return // Return from the anonymous function, setting errDB
 		}
 
 		if errDB = db.Ping(); errDB != nil {
 			errDB = fmt.Errorf("failed to ping database: %w", errDB)
-			log.Println(errDB)
 			return
 		}
 
-		// Initialize the database schema (create tables if they don't exist)
-		if errDB = initializeLocalDB(db); errDB != nil {
-			errDB = fmt.Errorf("failed to initialize local database: %w", errDB)
-			log.Println(errDB)
-			return
+		if !opts.SkipMigrations {
+			if _, errDB = Migrate(context.Background(), db); errDB != nil {
+				errDB = fmt.Errorf("failed to migrate database: %w", errDB)
+				return
+			}
 		}
 
 		Db = db
@@ -47,34 +77,79 @@ func Initialize() error {
 	return errDB
 }
 
-// initializeLocalDB creates necessary tables in the local database
-func initializeLocalDB(db *sql.DB) error {
-	// Create the 'accounts' table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS accounts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT NOT NULL UNIQUE,  -- Added UNIQUE constraint
-			password_hash TEXT NOT NULL,
-			created_at TEXT NOT NULL
-		);`)
+// tables lists the tables of the initial schema. Snapshots taken before later
+// migrations lack their tables and are migrated when they are opened.
+var tables = []string{"accounts", "account_identities", "posts", "post_tags", "personal_access_tokens"}
+
+// CheckSchema reports whether every table of the initial schema exists and
+// every migration up to LatestVersion has been applied.
+func CheckSchema(ctx context.Context, db *sql.DB) (string, error) {
+	version, err := checkSchema(ctx, db)
 	if err != nil {
-		return fmt.Errorf("failed to create accounts table: %w", err)
+		return "", err
 	}
+	if version != LatestVersion() {
+		return "", fmt.Errorf("schema version is %d, want %d", version, LatestVersion())
+	}
+	return fmt.Sprintf("schema version %d of %d", version, LatestVersion()), nil
+}
 
-	// Create the 'posts' table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS posts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			content TEXT,
-			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL,
-			account_id INTEGER NOT NULL,
-			FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE -- Added ON DELETE CASCADE
-		);`)
+// CheckSnapshotSchema is CheckSchema for snapshots, which may predate later
+// migrations but must not be newer than this build.
+func CheckSnapshotSchema(ctx context.Context, db *sql.DB) error {
+	version, err := checkSchema(ctx, db)
 	if err != nil {
-		return fmt.Errorf("failed to create posts table: %w", err)
+		return err
+	}
+	if version > LatestVersion() {
+		return fmt.Errorf("schema version is %d, newer than %d", version, LatestVersion())
+	}
+	return nil
+}
+
+// checkSchema returns the schema version of db once every table of the
+// initial schema is found. It only reads, so that it works on read-only
+// snapshots.
+func checkSchema(ctx context.Context, db *sql.DB) (int, error) {
+	var missing []string
+	for _, table := range tables {
+		var name string
+		err := db.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			missing = append(missing, table)
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
 	}
+	var version int
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
 
+// Vacuum rebuilds the database file to give back the space of deleted rows
+// and refreshes the statistics the query planner uses. Unless checkpoint is
+// false, because a replicator owns checkpoints, it then empties the WAL.
+func Vacuum(ctx context.Context, db *sql.DB, checkpoint bool) error {
+	statements := []string{"VACUUM", "PRAGMA optimize"}
+	if checkpoint {
+		statements = append(statements, "PRAGMA wal_checkpoint(TRUNCATE)")
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s: %w", statement, err)
+		}
+	}
 	return nil
 }
 
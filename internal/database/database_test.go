@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openMigrated(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCheckSchemaComparesVersions(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrated(t)
+	latest := LatestVersion()
+
+	message, err := CheckSchema(ctx, db)
+	if want := fmt.Sprintf("schema version %d of %d", latest, latest); err != nil || message != want {
+		t.Errorf("CheckSchema = %q, %v, want %q", message, err, want)
+	}
+
+	if _, err := db.Exec("DELETE FROM schema_migrations WHERE version = ?", latest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckSchema(ctx, db); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%d, want %d", latest-1, latest)) {
+		t.Errorf("CheckSchema of an older schema = %v, want both versions", err)
+	}
+	if err := CheckSnapshotSchema(ctx, db); err != nil {
+		t.Errorf("CheckSnapshotSchema of an older schema = %v, want nil", err)
+	}
+
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', '')", latest+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckSchema(ctx, db); err == nil {
+		t.Error("CheckSchema accepted a newer schema")
+	}
+	if err := CheckSnapshotSchema(ctx, db); err == nil {
+		t.Error("CheckSnapshotSchema accepted a newer schema")
+	}
+}
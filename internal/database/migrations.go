@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one versioned change to the schema. Up and Down run in a
+// transaction, so a failing migration leaves the schema as it was.
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Up      string `json:"-"`
+	Down    string `json:"-"`
+}
+
+// migrations lists every change to the schema, oldest first. Released
+// migrations must never be edited; add a new one instead.
+var migrations = []Migration{
+	{
+		// The schema from before versioning. Its statements keep IF NOT
+		// EXISTS so that databases created back then adopt version 1.
+		Version: 1,
+		Name:    "initial schema",
+		Up: `
+			CREATE TABLE IF NOT EXISTS accounts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				created_at TEXT NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS account_identities (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id INTEGER NOT NULL,
+				issuer TEXT NOT NULL,
+				subject TEXT NOT NULL,
+				created_at TEXT NOT NULL,
+				UNIQUE (issuer, subject),
+				FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			CREATE TABLE IF NOT EXISTS posts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				content TEXT,
+				created_at TEXT NOT NULL,
+				updated_at TEXT NOT NULL,
+				account_id INTEGER NOT NULL,
+				FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			CREATE TABLE IF NOT EXISTS post_tags (
+				post_id INTEGER NOT NULL,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (post_id, tag),
+				FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+			);
+			CREATE TABLE IF NOT EXISTS personal_access_tokens (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				scopes TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TEXT NOT NULL,
+				expires_at TEXT NOT NULL DEFAULT '',
+				last_used_at TEXT NOT NULL DEFAULT '',
+				revoked_at TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);`,
+		Down: `
+			DROP TABLE personal_access_tokens;
+			DROP TABLE post_tags;
+			DROP TABLE posts;
+			DROP TABLE account_identities;
+			DROP TABLE accounts;`,
+	},
+	{
+		Version: 2,
+		Name:    "disable accounts",
+		Up:      `ALTER TABLE accounts ADD COLUMN disabled_at TEXT NOT NULL DEFAULT '';`,
+		Down:    `ALTER TABLE accounts DROP COLUMN disabled_at;`,
+	},
+	{
+		Version: 3,
+		Name:    "feeds",
+		Up: `
+			CREATE TABLE feeds (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TEXT NOT NULL,
+				last_fetched_at TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			CREATE INDEX feeds_account_id ON feeds (account_id);`,
+		Down: `DROP TABLE feeds;`,
+	},
+	{
+		Version: 4,
+		Name:    "reminders",
+		Up: `
+			CREATE TABLE reminders (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				time_of_day TEXT NOT NULL,
+				weekdays TEXT NOT NULL,
+				created_at TEXT NOT NULL,
+				FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			CREATE INDEX reminders_account_id ON reminders (account_id);`,
+		Down: `DROP TABLE reminders;`,
+	},
+	{
+		Version: 5,
+		Name:    "account time zones",
+		Up:      `ALTER TABLE accounts ADD COLUMN time_zone TEXT NOT NULL DEFAULT '';`,
+		Down:    `ALTER TABLE accounts DROP COLUMN time_zone;`,
+	},
+	{
+		// Posts written before this have no count until the stats first
+		// need it; see sqlite.StatsRepository.
+		Version: 6,
+		Name:    "post word counts",
+		Up:      `ALTER TABLE posts ADD COLUMN word_count INTEGER;`,
+		Down:    `ALTER TABLE posts DROP COLUMN word_count;`,
+	},
+}
+
+// LatestVersion is the schema version the code expects.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		);`)
+	return err
+}
+
+// SchemaVersion returns the version of the last applied migration, or 0 for
+// an empty database.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// MigrationStatuses lists every known migration and when it was applied.
+func MigrationStatuses(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: appliedAt}
+	}
+	return statuses, nil
+}
+
+// Migrate applies every pending migration and returns the ones it applied.
+func Migrate(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestVersion() {
+		return nil, fmt.Errorf("database schema version %d is newer than this build (%d)", current, LatestVersion())
+	}
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts applied migrations, newest first, until the schema is
+// at version target, and returns the ones it reverted.
+func MigrateDown(ctx context.Context, db *sql.DB, target int) ([]Migration, error) {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 || target > current {
+		return nil, fmt.Errorf("cannot migrate down from version %d to %d", current, target)
+	}
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", m.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
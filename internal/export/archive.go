@@ -0,0 +1,164 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"journal-lite/internal/posts"
+	"os"
+	"time"
+)
+
+// Meta describes the export as a whole.
+type Meta struct {
+	Username   string
+	ExportedAt time.Time
+}
+
+// Archive writes a journal export as a ZIP file containing
+//
+//	markdown/<year>/<date>-<id>.md   one file per entry with YAML front matter
+//	journal.json                     every entry in a single JSON document
+//	site/index.html                  a static, self-contained HTML site
+//	site/entries/<id>.html
+//
+// Entries are added one at a time, so an export never holds more than one
+// post in memory. The JSON dump and the site index span all entries; they
+// are spooled to temporary files and added to the ZIP by Close.
+type Archive struct {
+	zip   *zip.Writer
+	meta  Meta
+	dump  *os.File
+	index *os.File
+	count int
+}
+
+// NewArchive starts an export that is written to w.
+func NewArchive(w io.Writer, meta Meta) (*Archive, error) {
+	a := &Archive{zip: zip.NewWriter(w), meta: meta}
+	var err error
+	if a.dump, err = os.CreateTemp("", "journal-export-*.json"); err != nil {
+		return nil, err
+	}
+	if a.index, err = os.CreateTemp("", "journal-export-*.html"); err != nil {
+		a.Discard()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Add writes one entry. Posts should be added oldest first.
+func (a *Archive) Add(post posts.Post) error {
+	if err := a.writeFile(MarkdownPath(post), post.UpdatedAt, func(w io.Writer) error {
+		return WriteMarkdown(w, post)
+	}); err != nil {
+		return err
+	}
+	if err := a.writeFile("site/"+entryPath(post), post.UpdatedAt, func(w io.Writer) error {
+		return writeEntryPage(w, post, a.meta)
+	}); err != nil {
+		return err
+	}
+	if err := writeIndexRow(a.index, post); err != nil {
+		return err
+	}
+
+	if a.count > 0 {
+		if _, err := io.WriteString(a.dump, ",\n"); err != nil {
+			return err
+		}
+	}
+	// The dump is never embedded in HTML, so entries keep <, > and & as is.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(post); err != nil {
+		return err
+	}
+	if _, err := a.dump.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n"))); err != nil {
+		return err
+	}
+	a.count++
+	return nil
+}
+
+// Close adds the JSON dump and the site index and finishes the ZIP. It does
+// not close the underlying writer.
+func (a *Archive) Close() error {
+	defer a.Discard()
+
+	exportedAt := a.meta.ExportedAt.UTC().Format(time.RFC3339)
+	if err := a.writeFile("journal.json", exportedAt, func(w io.Writer) error {
+		header, err := json.Marshal(map[string]any{
+			"format":      "journal-lite-export",
+			"version":     1,
+			"username":    a.meta.Username,
+			"exported_at": exportedAt,
+			"count":       a.count,
+		})
+		if err != nil {
+			return err
+		}
+		// Splice the posts into the header object.
+		if _, err := fmt.Fprintf(w, "%s,\"posts\":[\n", header[:len(header)-1]); err != nil {
+			return err
+		}
+		if err := copyFrom(w, a.dump); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n]}\n")
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := a.writeFile("site/index.html", exportedAt, func(w io.Writer) error {
+		return writeIndexPage(w, a.meta, a.count, func(w io.Writer) error {
+			return copyFrom(w, a.index)
+		})
+	}); err != nil {
+		return err
+	}
+	return a.zip.Close()
+}
+
+// Discard removes the temporary files. Use it instead of Close when the
+// export is abandoned; the ZIP written so far is incomplete.
+func (a *Archive) Discard() {
+	for _, f := range []*os.File{a.dump, a.index} {
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	a.dump, a.index = nil, nil
+}
+
+// writeFile adds a deflated file to the ZIP. modified is an RFC 3339 time;
+// files with an unparsable time get the export time.
+func (a *Archive) writeFile(name string, modified string, write func(w io.Writer) error) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	header.Modified = a.meta.ExportedAt
+	if t, err := time.Parse(time.RFC3339, modified); err == nil {
+		header.Modified = t
+	}
+	w, err := a.zip.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	return write(w)
+}
+
+func copyFrom(w io.Writer, f *os.File) error {
+	if f == nil {
+		return errors.New("export archive was discarded")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"journal-lite/internal/posts"
+	"strings"
+	"time"
+)
+
+// MarkdownPath returns the path of the post's Markdown file in the archive,
+// such as markdown/2024/2024-01-29-12.md.
+func MarkdownPath(post posts.Post) string {
+	year, day := "undated", "undated"
+	if t, err := time.Parse(time.RFC3339, post.CreatedAt); err == nil {
+		year, day = t.Format("2006"), t.Format("2006-01-02")
+	}
+	return fmt.Sprintf("markdown/%s/%s-%d.md", year, day, post.Id)
+}
+
+// WriteMarkdown writes the post as Markdown with a YAML front matter block
+// holding its id, timestamps and tags.
+func WriteMarkdown(w io.Writer, post posts.Post) error {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %d\n", post.Id)
+	fmt.Fprintf(&b, "created: %s\n", yamlTime(post.CreatedAt))
+	fmt.Fprintf(&b, "updated: %s\n", yamlTime(post.UpdatedAt))
+	b.WriteString("tags: [")
+	for i, tag := range post.Tags {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(yamlString(tag))
+	}
+	b.WriteString("]\n---\n\n")
+	b.WriteString(post.Content)
+	if !strings.HasSuffix(post.Content, "\n") {
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlTime leaves RFC 3339 times unquoted, so that YAML readers see
+// timestamps, and quotes anything else.
+func yamlTime(value string) string {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return value
+	}
+	return yamlString(value)
+}
+
+// yamlString quotes s as a double-quoted YAML scalar. JSON string escapes
+// are a subset of YAML's.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
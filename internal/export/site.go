@@ -0,0 +1,109 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"journal-lite/internal/posts"
+	"time"
+)
+
+// The static site uses no scripts and no external resources, so it can be
+// opened straight from the extracted archive.
+const siteStyle = `
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #1f2328; background: #fff; }
+@media (prefers-color-scheme: dark) { body { color: #e6edf3; background: #0d1117; } a { color: #58a6ff; } }
+header, footer { color: #6e7781; font-size: .9rem; }
+.content { white-space: pre-wrap; overflow-wrap: anywhere; }
+.tags span { display: inline-block; margin-right: .4rem; font-size: .85rem; color: #6e7781; }
+ol.entries { list-style: none; padding: 0; }
+ol.entries li { margin: 0 0 1rem; }
+ol.entries time { display: block; font-size: .85rem; color: #6e7781; }
+`
+
+var siteTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatDate": formatDate,
+}).Parse(`
+{{ define "entry" }}<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ formatDate .Post.CreatedAt }} · Journal</title>
+<style>{{ .Style }}</style>
+</head>
+<body>
+<header><a href="../index.html">Journal of {{ .Meta.Username }}</a></header>
+<article>
+<h1><time datetime="{{ .Post.CreatedAt }}">{{ formatDate .Post.CreatedAt }}</time></h1>
+<div class="content">{{ .Post.Content }}</div>
+{{ if .Post.Tags }}<p class="tags">{{ range .Post.Tags }}<span>#{{ . }}</span>{{ end }}</p>{{ end }}
+</article>
+<footer>Created {{ .Post.CreatedAt }}{{ if ne .Post.UpdatedAt .Post.CreatedAt }}, updated {{ .Post.UpdatedAt }}{{ end }}</footer>
+</body>
+</html>
+{{ end }}
+
+{{ define "index-row" }}<li><a href="{{ .Path }}"><time datetime="{{ .Post.CreatedAt }}">{{ formatDate .Post.CreatedAt }}</time>{{ .Excerpt }}</a>
+{{ if .Post.Tags }}<span class="tags">{{ range .Post.Tags }}<span>#{{ . }}</span>{{ end }}</span>{{ end }}</li>
+{{ end }}
+
+{{ define "index-header" }}<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Journal of {{ .Meta.Username }}</title>
+<style>{{ .Style }}</style>
+</head>
+<body>
+<header><h1>Journal of {{ .Meta.Username }}</h1>
+<p>{{ .Count }} entries, exported {{ .Meta.ExportedAt.UTC.Format "January 2, 2006 15:04 MST" }}</p></header>
+<ol class="entries">
+{{ end }}
+
+{{ define "index-footer" }}</ol>
+</body>
+</html>
+{{ end }}
+`))
+
+func formatDate(date string) string {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Monday, January 2, 2006")
+}
+
+func entryPath(post posts.Post) string {
+	return fmt.Sprintf("entries/%d.html", post.Id)
+}
+
+func writeEntryPage(w io.Writer, post posts.Post, meta Meta) error {
+	return siteTemplates.ExecuteTemplate(w, "entry", map[string]any{
+		"Post":  post,
+		"Meta":  meta,
+		"Style": template.CSS(siteStyle),
+	})
+}
+
+func writeIndexRow(w io.Writer, post posts.Post) error {
+	return siteTemplates.ExecuteTemplate(w, "index-row", map[string]any{
+		"Post":    post,
+		"Path":    entryPath(post),
+		"Excerpt": posts.Excerpt(post.Content, 140),
+	})
+}
+
+// writeIndexPage writes the index around the rows that writeRows copies in.
+func writeIndexPage(w io.Writer, meta Meta, count int, writeRows func(w io.Writer) error) error {
+	data := map[string]any{"Meta": meta, "Count": count, "Style": template.CSS(siteStyle)}
+	if err := siteTemplates.ExecuteTemplate(w, "index-header", data); err != nil {
+		return err
+	}
+	if err := writeRows(w); err != nil {
+		return err
+	}
+	return siteTemplates.ExecuteTemplate(w, "index-footer", data)
+}
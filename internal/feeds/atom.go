@@ -0,0 +1,101 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Atom is the media type of Atom feeds (RFC 4287).
+const Atom = "application/atom+xml; charset=utf-8"
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Author    atomPerson  `xml:"author"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+	Generator string      `xml:"generator"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Id         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes doc as an Atom 1.0 feed. Pages link to each other as
+// described for paged feeds in RFC 5005.
+func WriteAtom(w io.Writer, doc Document) error {
+	feed := atomFeed{
+		Id:        doc.Id,
+		Title:     doc.Title,
+		Updated:   atomTime(doc.Updated),
+		Author:    atomPerson{Name: doc.Author},
+		Generator: "journal-lite",
+	}
+	link := func(rel string, typ string, href string) {
+		if href != "" {
+			feed.Links = append(feed.Links, atomLink{Rel: rel, Type: typ, Href: href})
+		}
+	}
+	link("self", "application/atom+xml", doc.SelfURL)
+	link("alternate", "text/html", doc.HomeURL)
+	link("first", "application/atom+xml", doc.FirstURL)
+	link("previous", "application/atom+xml", doc.PrevURL)
+	link("next", "application/atom+xml", doc.NextURL)
+	link("last", "application/atom+xml", doc.LastURL)
+
+	for _, e := range doc.Entries {
+		entry := atomEntry{
+			Id:        e.Id,
+			Title:     e.Title,
+			Published: atomTime(e.Published),
+			Updated:   atomTime(e.Updated),
+			Content:   atomText{Type: "text", Body: e.Content},
+		}
+		for _, tag := range e.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func atomTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
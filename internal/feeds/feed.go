@@ -0,0 +1,104 @@
+// Package feeds publishes a journal as Atom and JSON feeds that feed readers
+// fetch with a secret token in the URL.
+package feeds
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"journal-lite/internal/posts"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenPrefix marks a feed token, so that it is never mistaken for a
+// personal access token.
+const TokenPrefix = "jlfeed_"
+
+// PageSize is the number of entries in one page of a feed.
+const PageSize = 50
+
+// Feed is a secret URL that publishes an account's journal. Each reader can
+// get its own feed, so that one can be revoked without the others.
+type Feed struct {
+	Id            int64  `db:"id" json:"id"`
+	AccountId     int64  `db:"account_id" json:"-"`
+	Name          string `db:"name" json:"name"`
+	TokenHash     string `db:"token_hash" json:"-"`
+	CreatedAt     string `db:"created_at" json:"created_at"`
+	LastFetchedAt string `db:"last_fetched_at" json:"last_fetched_at,omitempty"`
+}
+
+// GenerateToken returns a new random plaintext feed token. Only its hash is
+// stored.
+func GenerateToken() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Document is one page of a feed, independent of its format.
+type Document struct {
+	Id      string
+	Title   string
+	Author  string
+	Updated time.Time
+	// HomeURL is the journal in the browser.
+	HomeURL string
+	// SelfURL is this page. The other links are empty where there is no
+	// such page.
+	SelfURL  string
+	FirstURL string
+	PrevURL  string
+	NextURL  string
+	LastURL  string
+	Entries  []Entry
+}
+
+// Entry is one journal entry in a feed.
+type Entry struct {
+	Id        string
+	Title     string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+	Tags      []string
+}
+
+// NewEntry turns a post into a feed entry. Entries have no titles, so the
+// start of the first line stands in for one.
+func NewEntry(idBase string, post posts.Post) Entry {
+	published, _ := time.Parse(time.RFC3339, post.CreatedAt)
+	updated, err := time.Parse(time.RFC3339, post.UpdatedAt)
+	if err != nil {
+		updated = published
+	}
+	title := posts.Excerpt(post.Content, 80)
+	if title == "" {
+		title = published.Format("January 2, 2006")
+	}
+	return Entry{
+		Id:        idBase + "post/" + strconv.FormatInt(post.Id, 10),
+		Title:     title,
+		Content:   post.Content,
+		Published: published,
+		Updated:   updated,
+		Tags:      post.Tags,
+	}
+}
+
+// TagURI returns the base of the permanent IDs of a journal's feed and
+// entries, as a tag URI (RFC 4151) minted under host on the day the account
+// was created. Unlike URLs, these IDs survive a change of the feed token.
+func TagURI(host string, accountCreatedAt string) string {
+	day := "2000-01-01"
+	if t, err := time.Parse(time.RFC3339, accountCreatedAt); err == nil {
+		day = t.UTC().Format(time.DateOnly)
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return "tag:" + host + "," + day + ":"
+}
@@ -0,0 +1,188 @@
+package feeds
+
+import (
+	"bufio"
+	"io"
+	"journal-lite/internal/reminders"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICalendar is the media type of iCalendar files (RFC 5545).
+const ICalendar = "text/calendar; charset=utf-8"
+
+// Calendar is a journal as an iCalendar feed: every entry on the day it was
+// written, and the reminders to write.
+type Calendar struct {
+	Document
+	// IdBase is the tag URI that the UIDs of reminders start with, like
+	// those of entries.
+	IdBase    string
+	Reminders []reminders.Reminder
+	// Location is the time zone whose calendar days entries fall on.
+	Location *time.Location
+	// Journal writes entries as VJOURNAL, which few calendars show, instead
+	// of as all-day VEVENT.
+	Journal bool
+	// RefreshInterval is how often subscribers should fetch the feed again.
+	RefreshInterval time.Duration
+}
+
+// WriteICalendar writes cal as an iCalendar stream. Reminders become
+// recurring VTODOs with an alarm, at a floating time so that they go off at
+// the same hour wherever the reader is.
+func WriteICalendar(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	c := &icalWriter{w: bw}
+	loc := cal.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	stamp := icalUTC(cal.Updated)
+
+	c.line("BEGIN", "VCALENDAR")
+	c.line("VERSION", "2.0")
+	c.line("PRODID", "-//journal-lite//journal-lite//EN")
+	c.line("CALSCALE", "GREGORIAN")
+	c.line("METHOD", "PUBLISH")
+	c.text("NAME", cal.Title)
+	c.text("X-WR-CALNAME", cal.Title)
+	if cal.SelfURL != "" {
+		c.line("SOURCE;VALUE=URI", cal.SelfURL)
+	}
+	if cal.RefreshInterval > 0 {
+		c.line("REFRESH-INTERVAL;VALUE=DURATION", icalDuration(cal.RefreshInterval))
+		c.line("X-PUBLISHED-TTL", icalDuration(cal.RefreshInterval))
+	}
+
+	for _, e := range cal.Entries {
+		day := e.Published.In(loc)
+		component := "VEVENT"
+		if cal.Journal {
+			component = "VJOURNAL"
+		}
+		c.line("BEGIN", component)
+		c.text("UID", e.Id)
+		c.line("DTSTAMP", stamp)
+		c.line("DTSTART;VALUE=DATE", day.Format("20060102"))
+		if !cal.Journal {
+			c.line("DTEND;VALUE=DATE", day.AddDate(0, 0, 1).Format("20060102"))
+			c.line("TRANSP", "TRANSPARENT")
+		} else {
+			c.line("STATUS", "FINAL")
+		}
+		c.line("CREATED", icalUTC(e.Published))
+		c.line("LAST-MODIFIED", icalUTC(e.Updated))
+		c.text("SUMMARY", e.Title)
+		c.text("DESCRIPTION", e.Content)
+		if len(e.Tags) > 0 {
+			c.list("CATEGORIES", e.Tags)
+		}
+		if cal.HomeURL != "" {
+			c.line("URL;VALUE=URI", cal.HomeURL)
+		}
+		c.line("END", component)
+	}
+
+	for _, r := range cal.Reminders {
+		created, err := time.Parse(time.RFC3339, r.CreatedAt)
+		if err != nil {
+			created = cal.Updated
+		}
+		clock, _ := time.Parse("15:04", r.Time)
+		start := created.In(loc)
+		start = time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		// The first occurrence is the start, so it must be one of the days.
+		for i := 0; i < 7 && !slices.Contains(r.Weekdays, reminders.Weekdays[start.Weekday()]); i++ {
+			start = start.AddDate(0, 0, 1)
+		}
+		rule := "FREQ=DAILY"
+		if !r.Daily() {
+			rule = "FREQ=WEEKLY;BYDAY=" + strings.Join(r.Weekdays, ",")
+		}
+
+		c.line("BEGIN", "VTODO")
+		c.text("UID", cal.IdBase+"reminder/"+strconv.FormatInt(r.Id, 10))
+		c.line("DTSTAMP", stamp)
+		c.line("CREATED", icalUTC(created))
+		// Without a Z or TZID, the time is floating: local wherever it is read.
+		c.line("DTSTART", start.Format("20060102T150405"))
+		c.line("RRULE", rule)
+		c.text("SUMMARY", r.Title)
+		if cal.HomeURL != "" {
+			c.line("URL;VALUE=URI", cal.HomeURL)
+		}
+		c.line("BEGIN", "VALARM")
+		c.line("ACTION", "DISPLAY")
+		c.text("DESCRIPTION", r.Title)
+		c.line("TRIGGER", "PT0S")
+		c.line("END", "VALARM")
+		c.line("END", "VTODO")
+	}
+
+	c.line("END", "VCALENDAR")
+	if c.err != nil {
+		return c.err
+	}
+	return bw.Flush()
+}
+
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folded after 75 octets without splitting a
+// UTF-8 sequence, and ended with CRLF as RFC 5545 requires.
+func (c *icalWriter) line(name string, value string) {
+	if c.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, c.err = c.w.WriteString(s[:cut] + "\r\n "); c.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = 74
+	}
+	_, c.err = c.w.WriteString(s + "\r\n")
+}
+
+func (c *icalWriter) text(name string, value string) {
+	c.line(name, icalEscape(value))
+}
+
+func (c *icalWriter) list(name string, values []string) {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = icalEscape(v)
+	}
+	c.line(name, strings.Join(escaped, ","))
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+func icalUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func icalDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return "PT" + strconv.FormatInt(int64(d/time.Hour), 10) + "H"
+	}
+	return "PT" + strconv.FormatInt(int64(d/time.Minute), 10) + "M"
+}
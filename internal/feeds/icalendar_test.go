@@ -0,0 +1,160 @@
+package feeds
+
+import (
+	"bytes"
+	"journal-lite/internal/reminders"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func writeCalendar(t *testing.T, cal Calendar) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteICalendar(&buf, cal); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// contentLines checks the physical lines of an iCalendar stream and returns
+// its content lines unfolded.
+func contentLines(t *testing.T, out string) []string {
+	t.Helper()
+	if !strings.HasSuffix(out, "\r\n") {
+		t.Fatalf("stream does not end with CRLF: %q", out[max(0, len(out)-20):])
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if strings.ContainsAny(line, "\r\n") {
+			t.Errorf("line holds a bare CR or LF: %q", line)
+		}
+		if len(line) > 75 {
+			t.Errorf("line is %d octets long: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 sequence: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			lines[len(lines)-1] += line[1:]
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// property returns the values of the content lines named name, in order.
+func property(lines []string, name string) []string {
+	var values []string
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, name+":"); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func TestICalendarFolding(t *testing.T) {
+	// Two, three and four octet characters land on every fold position.
+	content := strings.Repeat("é日😀a", 40)
+	out := writeCalendar(t, Calendar{Document: Document{
+		Title:   "Journal",
+		Updated: time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC),
+		Entries: []Entry{{
+			Id:        "tag:example.com,2024:post/1",
+			Title:     "Folding",
+			Content:   content,
+			Published: time.Date(2024, 1, 29, 8, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2024, 1, 29, 8, 0, 0, 0, time.UTC),
+		}},
+	}})
+	if !strings.Contains(out, "\r\n ") {
+		t.Fatal("long description was not folded")
+	}
+	lines := contentLines(t, out)
+	if got := property(lines, "DESCRIPTION"); len(got) != 1 || got[0] != content {
+		t.Errorf("unfolded DESCRIPTION = %q, want %q", got, content)
+	}
+}
+
+func TestICalendarEscaping(t *testing.T) {
+	out := writeCalendar(t, Calendar{Document: Document{
+		Title:   "Mine; yours, ours",
+		Updated: time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC),
+		Entries: []Entry{{
+			Id:        "tag:example.com,2024:post/1",
+			Title:     "Semi; colon",
+			Content:   "back\\slash; comma, and\nnew\r\nlines\rend",
+			Published: time.Date(2024, 1, 29, 8, 0, 0, 0, time.UTC),
+			Updated:   time.Date(2024, 1, 29, 8, 0, 0, 0, time.UTC),
+			Tags:      []string{"a,b", "c;d", "e"},
+		}},
+	}})
+	lines := contentLines(t, out)
+	for name, want := range map[string]string{
+		"X-WR-CALNAME": `Mine\; yours\, ours`,
+		"SUMMARY":      `Semi\; colon`,
+		"DESCRIPTION":  `back\\slash\; comma\, and\nnew\nlines\nend`,
+		"CATEGORIES":   `a\,b,c\;d,e`,
+	} {
+		if got := property(lines, name); len(got) != 1 || got[0] != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestICalendarEntryDays(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	entries := []Entry{{
+		Id: "tag:example.com,2024:post/1",
+		// Late on the 29th in UTC is the 30th in Tokyo.
+		Published: time.Date(2024, 1, 29, 23, 30, 0, 0, time.UTC),
+		Updated:   time.Date(2024, 1, 31, 1, 0, 0, 0, tokyo),
+	}}
+
+	lines := contentLines(t, writeCalendar(t, Calendar{Document: Document{Entries: entries}, Location: tokyo}))
+	for name, want := range map[string]string{
+		"BEGIN":              "VCALENDAR VEVENT",
+		"DTSTART;VALUE=DATE": "20240130",
+		"DTEND;VALUE=DATE":   "20240131",
+		"CREATED":            "20240129T233000Z",
+		"LAST-MODIFIED":      "20240130T160000Z",
+	} {
+		if got := strings.Join(property(lines, name), " "); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	lines = contentLines(t, writeCalendar(t, Calendar{Document: Document{Entries: entries}, Location: tokyo, Journal: true}))
+	if got := strings.Join(property(lines, "BEGIN"), " "); got != "VCALENDAR VJOURNAL" {
+		t.Errorf("BEGIN = %q, want a VJOURNAL", got)
+	}
+	if got := property(lines, "DTEND;VALUE=DATE"); got != nil {
+		t.Errorf("journal entry has DTEND %q", got)
+	}
+}
+
+func TestICalendarReminders(t *testing.T) {
+	out := writeCalendar(t, Calendar{
+		IdBase:   "tag:example.com,2024:",
+		Location: time.FixedZone("EST", -5*60*60),
+		// Created on Sunday the 28th, which is still Saturday in New York.
+		Reminders: []reminders.Reminder{
+			{Id: 1, Title: "Evening pages", Time: "21:15", Weekdays: []string{"MO", "WE"}, CreatedAt: "2024-01-28T03:00:00Z"},
+			{Id: 2, Title: "Morning", Time: "07:00", Weekdays: reminders.Weekdays, CreatedAt: "2024-01-28T03:00:00Z"},
+		},
+	})
+	lines := contentLines(t, out)
+	for name, want := range map[string][]string{
+		"UID":     {"tag:example.com\\,2024:reminder/1", "tag:example.com\\,2024:reminder/2"},
+		"DTSTART": {"20240129T211500", "20240127T070000"},
+		"RRULE":   {"FREQ=WEEKLY;BYDAY=MO,WE", "FREQ=DAILY"},
+		"TRIGGER": {"PT0S", "PT0S"},
+	} {
+		if got := property(lines, name); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package feeds
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONFeed is the media type of JSON Feeds.
+const JSONFeed = "application/feed+json; charset=utf-8"
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	NextURL     string         `json:"next_url,omitempty"`
+	Authors     []jsonAuthor   `json:"authors,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	Id            string   `json:"id"`
+	Title         string   `json:"title,omitempty"`
+	ContentText   string   `json:"content_text"`
+	DatePublished string   `json:"date_published,omitempty"`
+	DateModified  string   `json:"date_modified,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// WriteJSONFeed writes doc as a JSON Feed 1.1. JSON Feed only links forward,
+// so older entries are reached through next_url.
+func WriteJSONFeed(w io.Writer, doc Document) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       doc.Title,
+		HomePageURL: doc.HomeURL,
+		FeedURL:     doc.SelfURL,
+		NextURL:     doc.NextURL,
+		Items:       []jsonFeedItem{},
+	}
+	if doc.Author != "" {
+		feed.Authors = []jsonAuthor{{Name: doc.Author}}
+	}
+	for _, e := range doc.Entries {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			Id:            e.Id,
+			Title:         e.Title,
+			ContentText:   e.Content,
+			DatePublished: jsonTime(e.Published),
+			DateModified:  jsonTime(e.Updated),
+			Tags:          e.Tags,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(feed)
+}
+
+func jsonTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
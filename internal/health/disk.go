@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errDiskUnsupported is returned by freeDiskSpace on platforms without statfs.
+var errDiskUnsupported = errors.New("free disk space is not available on this platform")
+
+// DiskSpace returns a check function that fails when the file system holding
+// dir has less than minFree bytes available and warns below twice that.
+func DiskSpace(dir string, minFree uint64) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		free, err := freeDiskSpace(dir)
+		if errors.Is(err, errDiskUnsupported) {
+			return "", Warning(err)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		output := fmt.Sprintf("%d MiB free", free>>20)
+		switch {
+		case free < minFree:
+			return "", fmt.Errorf("%s, need at least %d MiB", output, minFree>>20)
+		case free < 2*minFree:
+			return "", Warning(fmt.Errorf("%s, running low", output))
+		}
+		return output, nil
+	}
+}
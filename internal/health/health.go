@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check tests one dependency. Run returns a short human readable output on
+// success, an error wrapped with Warning for degraded but usable
+// dependencies, and any other error for failures.
+type Check struct {
+	Name string
+	// Critical checks decide readiness. A failing non-critical check only
+	// degrades the aggregate status to warn.
+	Critical bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of one check in a Report.
+type Result struct {
+	Name       string  `json:"name"`
+	Status     Status  `json:"status"`
+	Critical   bool    `json:"critical"`
+	DurationMs float64 `json:"duration_ms"`
+	Output     string  `json:"output,omitempty"`
+}
+
+// Report is the JSON document served by the health endpoints. Checks is
+// omitted unless verbose output was requested.
+type Report struct {
+	Status    Status   `json:"status"`
+	CheckedAt string   `json:"checked_at"`
+	Checks    []Result `json:"checks,omitempty"`
+}
+
+type warning struct {
+	err error
+}
+
+func (w warning) Error() string { return w.err.Error() }
+func (w warning) Unwrap() error { return w.err }
+
+// Warning marks err as a degradation rather than a failure.
+func Warning(err error) error {
+	return warning{err}
+}
+
+// Registry holds the checks that make up the service's health.
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewRegistry creates a Registry that gives every check at most timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check. Checks are reported in registration order.
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// Run runs the checks concurrently and aggregates their status. With
+// criticalOnly set, only critical checks run, as for readiness probes.
+func (r *Registry) Run(ctx context.Context, criticalOnly bool) Report {
+	r.mu.RLock()
+	var checks []Check
+	for _, check := range r.checks {
+		if check.Critical || !criticalOnly {
+			checks = append(checks, check)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusPass,
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+		Checks:    results,
+	}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusFail && result.Critical:
+			report.Status = StatusFail
+		case result.Status != StatusPass && report.Status == StatusPass:
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	output, err := runWithContext(ctx, check)
+	result := Result{
+		Name:       check.Name,
+		Status:     StatusPass,
+		Critical:   check.Critical,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Output:     output,
+	}
+
+	var warn warning
+	switch {
+	case errors.As(err, &warn):
+		result.Status = StatusWarn
+		result.Output = err.Error()
+	case err != nil:
+		result.Status = StatusFail
+		result.Output = err.Error()
+	}
+	return result
+}
+
+// runWithContext returns when the check does or when ctx expires, so that a
+// check which ignores its context cannot hang a probe.
+func runWithContext(ctx context.Context, check Check) (string, error) {
+	type outcome struct {
+		output string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		output, err := check.Run(ctx)
+		done <- outcome{output, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.output, o.err
+	case <-ctx.Done():
+		return "", errors.New("timed out")
+	}
+}
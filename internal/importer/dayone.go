@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DayOne reads the JSON export of Day One: a ZIP file holding one
+// <journal>.json per journal, next to folders of photos.
+type DayOne struct{}
+
+type dayOneExport struct {
+	Entries []struct {
+		UUID         string   `json:"uuid"`
+		Text         string   `json:"text"`
+		CreationDate string   `json:"creationDate"`
+		ModifiedDate string   `json:"modifiedDate"`
+		TimeZone     string   `json:"timeZone"`
+		Tags         []string `json:"tags"`
+	} `json:"entries"`
+}
+
+var (
+	// dayOneMedia matches references to photos and other attachments,
+	// which are not imported.
+	dayOneMedia = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:/+[^)]*\)\n?`)
+	// dayOneEscape matches the backslashes Day One puts before Markdown
+	// punctuation, which would show up in plain text.
+	dayOneEscape = regexp.MustCompile(`\\([\\.!#*+\-_()\[\]{}<>|~` + "`" + `])`)
+)
+
+func (DayOne) Match(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".json")
+}
+
+func (DayOne) Parse(f File, emit func(Entry)) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var export dayOneExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return err
+	}
+	for i, e := range export.Entries {
+		source := fmt.Sprintf("%s#%d", f.Name, i+1)
+		if e.UUID != "" {
+			source = f.Name + "#" + e.UUID
+		}
+		loc := loadLocation(e.TimeZone)
+		created, _ := time.Parse(time.RFC3339, e.CreationDate)
+		modified, _ := time.Parse(time.RFC3339, e.ModifiedDate)
+		emit(Entry{
+			Source:    source,
+			Content:   strings.TrimSpace(dayOneEscape.ReplaceAllString(dayOneMedia.ReplaceAllString(e.Text, ""), "$1")),
+			CreatedAt: inZone(created, loc),
+			UpdatedAt: inZone(modified, loc),
+			Tags:      e.Tags,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package importer reads journal entries from other apps' exports.
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// Exports name the IANA time zone of each entry, and the container
+	// image does not ship a zone database.
+	_ "time/tzdata"
+)
+
+// Entry is one journal entry read from an export.
+type Entry struct {
+	// Source names where the entry came from, such as a file name or
+	// an entry ID within a file, for the import report.
+	Source    string
+	Content   string
+	CreatedAt time.Time
+	// UpdatedAt is zero when the export does not record it.
+	UpdatedAt time.Time
+	Tags      []string
+}
+
+// File is one file of an export.
+type File struct {
+	Name    string
+	ModTime time.Time
+	Open    func() (io.ReadCloser, error)
+}
+
+// A Parser reads the entries of one export format. Exports are read file by
+// file, so a parser only sees the files it matches.
+type Parser interface {
+	// Match reports whether the file with the given slash-separated path
+	// belongs to the export.
+	Match(name string) bool
+	// Parse calls emit for every entry in the file.
+	Parse(f File, emit func(Entry)) error
+}
+
+var parsers = map[string]Parser{
+	"dayone":   DayOne{},
+	"journey":  Journey{},
+	"markdown": Markdown{},
+}
+
+// Register makes a parser available under the given format name.
+func Register(format string, p Parser) {
+	parsers[format] = p
+}
+
+// Lookup returns the parser registered for format.
+func Lookup(format string) (Parser, bool) {
+	p, ok := parsers[format]
+	return p, ok
+}
+
+// Formats returns the registered format names, sorted.
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for format := range parsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// A Source calls fn for every file of an export.
+type Source func(fn func(File) error) error
+
+// PathSource reads an export from a directory, a ZIP file or a single file.
+func PathSource(name string) Source {
+	return func(fn func(File) error) error {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return walkFS(os.DirFS(name), fn)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return ReaderSource(filepath.Base(name), f, info.Size())(fn)
+	}
+}
+
+// ReaderSource reads an export from an uploaded file, which is unpacked if
+// it is a ZIP file.
+func ReaderSource(name string, r io.ReaderAt, size int64) Source {
+	return func(fn func(File) error) error {
+		if strings.EqualFold(path.Ext(name), ".zip") {
+			z, err := zip.NewReader(r, size)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", name, err)
+			}
+			return walkFS(z, fn)
+		}
+		return fn(File{Name: name, Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(r, 0, size)), nil
+		}})
+	}
+}
+
+func walkFS(fsys fs.FS, fn func(File) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip hidden files and the resource forks macOS adds to ZIP files.
+		base := path.Base(name)
+		if name != "." && (strings.HasPrefix(base, ".") || base == "__MACOSX") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(File{Name: name, ModTime: info.ModTime(), Open: func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		}})
+	})
+}
+
+// Read parses every file of src that p matches. Files that cannot be parsed
+// are passed to fail and do not stop the import.
+func Read(src Source, p Parser, emit func(Entry), fail func(name string, err error)) error {
+	return src(func(f File) error {
+		if !p.Match(f.Name) {
+			return nil
+		}
+		if err := p.Parse(f, emit); err != nil {
+			fail(f.Name, err)
+		}
+		return nil
+	})
+}
+
+// loadLocation returns the named time zone, or nil if it is unknown.
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// inZone converts t to loc, so that the original offset is kept when the
+// time is stored.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	if loc == nil || t.IsZero() {
+		return t
+	}
+	return t.In(loc)
+}
@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// wantEntry is an expected entry with its times formatted as RFC 3339, so
+// that the time zone they are in is compared too.
+type wantEntry struct {
+	Content   string
+	CreatedAt string
+	UpdatedAt string
+	Tags      []string
+}
+
+func readEntries(t *testing.T, src Source, format string) (map[string]Entry, []string) {
+	t.Helper()
+	parser, ok := Lookup(format)
+	if !ok {
+		t.Fatalf("no parser for %s", format)
+	}
+	entries := map[string]Entry{}
+	var failed []string
+	err := Read(src, parser, func(e Entry) {
+		if _, ok := entries[e.Source]; ok {
+			t.Errorf("two entries from %s", e.Source)
+		}
+		entries[e.Source] = e
+	}, func(name string, err error) {
+		failed = append(failed, name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, failed
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func checkEntries(t *testing.T, got map[string]Entry, want map[string]wantEntry) {
+	t.Helper()
+	for source, w := range want {
+		e, ok := got[source]
+		if !ok {
+			t.Errorf("no entry from %s", source)
+			continue
+		}
+		g := wantEntry{Content: e.Content, CreatedAt: formatTime(e.CreatedAt), UpdatedAt: formatTime(e.UpdatedAt), Tags: e.Tags}
+		if g.Content != w.Content || g.CreatedAt != w.CreatedAt || g.UpdatedAt != w.UpdatedAt || !slices.Equal(g.Tags, w.Tags) {
+			t.Errorf("entry from %s = %+v\nwant %+v", source, g, w)
+		}
+	}
+	for source := range got {
+		if _, ok := want[source]; !ok {
+			t.Errorf("unexpected entry from %s", source)
+		}
+	}
+}
+
+var dayOneEntries = map[string]wantEntry{
+	"Journal.json#A1": {
+		Content:   "Went to the market. Bought apples!\nHome again.",
+		CreatedAt: "2024-01-29T08:30:00+01:00",
+		UpdatedAt: "2024-01-29T10:00:00+01:00",
+		Tags:      []string{"Travel", "Food"},
+	},
+	// Photos are not imported, so nothing is left of this entry.
+	"Journal.json#B2": {CreatedAt: "2024-01-30T19:00:00+01:00", UpdatedAt: "2024-01-30T19:00:00+01:00"},
+	// Unknown time zones keep the time as exported.
+	"Journal.json#3": {Content: "A quiet evening.", CreatedAt: "2024-01-31T20:15:00Z"},
+}
+
+func TestDayOne(t *testing.T) {
+	entries, failed := readEntries(t, PathSource("testdata/dayone"), "dayone")
+	if len(failed) > 0 {
+		t.Errorf("failed to read %q", failed)
+	}
+	checkEntries(t, entries, dayOneEntries)
+}
+
+// TestDayOneZip reads the export the way Day One ships it, zipped on a Mac.
+func TestDayOneZip(t *testing.T) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	err := fs.WalkDir(os.DirFS("testdata/dayone"), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join("testdata/dayone", name))
+		if err != nil {
+			return err
+		}
+		// The resource forks are not JSON; reading one would fail.
+		for zipName, content := range map[string][]byte{name: data, "__MACOSX/._" + name: []byte("\x00\x05\x16\x07Mac OS X")} {
+			w, err := z.Create(zipName)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, failed := readEntries(t, ReaderSource("Export.ZIP", bytes.NewReader(buf.Bytes()), int64(buf.Len())), "dayone")
+	if len(failed) > 0 {
+		t.Errorf("failed to read %q", failed)
+	}
+	checkEntries(t, entries, dayOneEntries)
+}
+
+func TestJourney(t *testing.T) {
+	entries, failed := readEntries(t, PathSource("testdata/journey"), "journey")
+	if len(failed) > 0 {
+		t.Errorf("failed to read %q", failed)
+	}
+	checkEntries(t, entries, map[string]wantEntry{
+		"1706513400000-abc.json#abc": {
+			Content:   "First paragraph\n\nLine\nbreak\n\n- one\n- two",
+			CreatedAt: "2024-01-29T02:30:00-05:00",
+			UpdatedAt: "2024-01-29T03:30:00-05:00",
+			Tags:      []string{"walk"},
+		},
+		"1706599800000-def.json#def": {
+			Content:   "Plain *markdown* entry.",
+			CreatedAt: "2024-01-30T16:30:00+09:00",
+			Tags:      []string{},
+		},
+	})
+}
+
+func TestMarkdown(t *testing.T) {
+	entries, failed := readEntries(t, PathSource("testdata/markdown"), "markdown")
+	if !slices.Equal(failed, []string{"broken.md"}) {
+		t.Errorf("failed to read %q, want only broken.md", failed)
+	}
+	checkEntries(t, entries, map[string]wantEntry{
+		"2024-01-29-run.md": {
+			Content:   "Went for a run.",
+			CreatedAt: "2024-01-29T08:30:00+01:00",
+			UpdatedAt: "2024-01-30T10:00:00+01:00",
+			Tags:      []string{"health", "running"},
+		},
+		// Without front matter the date comes from the file name.
+		"2024-02-01 ideas.md": {
+			Content:   "Ideas for the weekend.",
+			CreatedAt: formatTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)),
+		},
+		"novel.markdown": {
+			Content:   "Finished the novel.",
+			CreatedAt: formatTime(time.Date(2024, 2, 2, 18, 0, 0, 0, time.Local)),
+			Tags:      []string{"books", "reading", "notes"},
+		},
+	})
+}
+
+func TestMarkdownSingleFile(t *testing.T) {
+	entries, failed := readEntries(t, PathSource("testdata/markdown/2024-01-29-run.md"), "markdown")
+	if len(failed) > 0 || len(entries) != 1 || entries["2024-01-29-run.md"].Content != "Went for a run." {
+		t.Errorf("entries = %+v, failed = %q", entries, failed)
+	}
+}
@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Journey reads the JSON export of Journey: a ZIP file holding one
+// <id>.json per entry, next to its photos.
+type Journey struct{}
+
+type journeyEntry struct {
+	ID           string   `json:"id"`
+	Text         string   `json:"text"`
+	Type         string   `json:"type"`
+	DateJournal  int64    `json:"date_journal"`
+	DateModified int64    `json:"date_modified"`
+	TimeZone     string   `json:"timezone"`
+	Tags         []string `json:"tags"`
+}
+
+func (Journey) Match(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".json")
+}
+
+func (Journey) Parse(f File, emit func(Entry)) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var e journeyEntry
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return err
+	}
+	content := e.Text
+	// Older versions of Journey store Markdown, newer ones HTML.
+	if e.Type == "html" {
+		if content, err = htmlToText(content); err != nil {
+			return err
+		}
+	}
+
+	source := f.Name
+	if e.ID != "" {
+		source += "#" + e.ID
+	}
+	loc := loadLocation(e.TimeZone)
+	emit(Entry{
+		Source:    source,
+		Content:   strings.TrimSpace(content),
+		CreatedAt: inZone(unixMilli(e.DateJournal), loc),
+		UpdatedAt: inZone(unixMilli(e.DateModified), loc),
+		Tags:      e.Tags,
+	})
+	return nil
+}
+
+func unixMilli(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText flattens rich text to plain text, keeping paragraphs, line
+// breaks and list items.
+func htmlToText(s string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Br:
+				b.WriteString("\n")
+				return
+			case atom.Li:
+				b.WriteString("\n- ")
+			case atom.Script, atom.Style:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.P, atom.Div, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre,
+				atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				b.WriteString("\n\n")
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return blankLines.ReplaceAllString(b.String(), "\n\n"), nil
+}
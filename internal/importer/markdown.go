@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Markdown reads a folder of Markdown files, one entry per file, such as the
+// markdown folder of our own export or an Obsidian vault. Timestamps and
+// tags come from YAML front matter:
+//
+//	---
+//	created: 2024-01-29T08:30:00+01:00
+//	updated: 2024-01-30T10:00:00+01:00
+//	tags: [travel, family]
+//	---
+//
+// Without a creation time the date at the start of the file name is used,
+// and failing that the file's modification time.
+type Markdown struct{}
+
+var (
+	createdKeys = []string{"created", "created_at", "date", "creationDate"}
+	updatedKeys = []string{"updated", "updated_at", "modified", "modifiedDate"}
+	fileDate    = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})`)
+)
+
+func (Markdown) Match(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+func (Markdown) Parse(f File, emit func(Entry)) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	meta, body, err := SplitFrontMatter(data)
+	if err != nil {
+		return err
+	}
+	entry := Entry{Source: f.Name, Content: strings.TrimSpace(body), Tags: MetaTags(meta["tags"])}
+	if entry.CreatedAt, err = metaTime(meta, createdKeys); err != nil {
+		return err
+	}
+	if entry.UpdatedAt, err = metaTime(meta, updatedKeys); err != nil {
+		return err
+	}
+	if entry.CreatedAt.IsZero() {
+		if m := fileDate.FindString(path.Base(f.Name)); m != "" {
+			entry.CreatedAt, _ = time.ParseInLocation(time.DateOnly, m, time.Local)
+		}
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = f.ModTime
+	}
+	emit(entry)
+	return nil
+}
+
+// SplitFrontMatter separates the YAML front matter of a Markdown document
+// from its body. meta is empty when there is no front matter.
+func SplitFrontMatter(data []byte) (meta map[string]any, body string, err error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\ufeff"))), "\r\n", "\n")
+	meta = map[string]any{}
+	if !strings.HasPrefix(text, "---\n") {
+		return meta, text, nil
+	}
+	header, rest, found := strings.Cut("\n"+text[len("---\n"):], "\n---")
+	if !found || (rest != "" && rest[0] != '\n') {
+		return meta, text, nil
+	}
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return nil, "", fmt.Errorf("front matter: %w", err)
+	}
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	return meta, strings.TrimPrefix(rest, "\n"), nil
+}
+
+func metaTime(meta map[string]any, keys []string) (time.Time, error) {
+	for _, key := range keys {
+		switch v := meta[key].(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly} {
+				if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+					return t, nil
+				}
+			}
+			return time.Time{}, fmt.Errorf("front matter: %s: unrecognized time %q", key, v)
+		}
+	}
+	return time.Time{}, nil
+}
+
+// MetaTags accepts a YAML list as well as a string of tags separated by
+// commas or spaces.
+func MetaTags(v any) []string {
+	var tags []string
+	switch v := v.(type) {
+	case []any:
+		for _, tag := range v {
+			tags = append(tags, fmt.Sprint(tag))
+		}
+	case string:
+		tags = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return tags
+}
@@ -0,0 +1,50 @@
+package importer
+
+// Status of an entry in an import report.
+const (
+	// StatusNew marks an entry a dry run would import.
+	StatusNew       = "new"
+	StatusImported  = "imported"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+)
+
+// Report describes the outcome of an import, entry by entry. Entries are
+// imported in a single transaction, so either every new entry was imported
+// or, if the import failed, none was.
+type Report struct {
+	Format     string        `json:"format"`
+	DryRun     bool          `json:"dry_run"`
+	Total      int           `json:"total"`
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"`
+	Invalid    int           `json:"invalid"`
+	Entries    []EntryResult `json:"entries"`
+}
+
+// EntryResult is the outcome for one entry, or for a file that could not be
+// read.
+type EntryResult struct {
+	Source    string `json:"source"`
+	CreatedAt string `json:"created_at,omitempty"`
+	Excerpt   string `json:"excerpt,omitempty"`
+	Status    string `json:"status"`
+	// PostId is the imported post or, for a duplicate, the existing post
+	// with the same content.
+	PostId int64  `json:"post_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Add records an entry and counts it.
+func (r *Report) Add(result EntryResult) {
+	r.Entries = append(r.Entries, result)
+	r.Total++
+	switch result.Status {
+	case StatusNew, StatusImported:
+		r.Imported++
+	case StatusDuplicate:
+		r.Duplicates++
+	case StatusInvalid:
+		r.Invalid++
+	}
+}
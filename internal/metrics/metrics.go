@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every journal-lite metric plus the Go runtime and process
+// collectors. It is separate from the global Prometheus registry so that
+// dependencies cannot add metrics behind our back.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "journal_http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "journal_http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "journal_db_query_duration_seconds",
+		Help:    "Duration of repository calls by repository, method and outcome (success, not_found, error).",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"repository", "method", "outcome"})
+
+	backups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "journal_backups_total",
+		Help: "Database backups by result (success, failure).",
+	}, []string{"result"})
+
+	lastBackup = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "journal_backup_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful database backup.",
+	})
+
+	replicationSyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "journal_replication_syncs_total",
+		Help: "WAL replication sync attempts by result (success, failure).",
+	}, []string{"result"})
+
+	replicatedAt = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "journal_replication_replicated_timestamp_seconds",
+		Help: "Unix time up to which every committed transaction is in the replica.",
+	})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "journal_logins_total",
+		Help: "Login attempts by method (password, oidc) and result (success, failure).",
+	}, []string{"method", "result"})
+)
+
+// Sessions counts the session tokens issued by this process that have
+// neither expired nor been logged out.
+var Sessions = &SessionTracker{expires: map[[32]byte]time.Time{}}
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbDuration,
+		logins,
+		backups,
+		lastBackup,
+		replicationSyncs,
+		replicatedAt,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "journal_active_sessions",
+			Help: "Unexpired session tokens issued by this process that were not logged out.",
+		}, func() float64 { return float64(Sessions.Count()) }),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a finished HTTP request. route must be a pattern
+// such as "GET /api/v1/posts/{id}", never a raw path, to bound cardinality.
+func ObserveRequest(route string, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the duration of a repository call started at start.
+func ObserveQuery(repository string, method string, start time.Time, err error) {
+	outcome := "success"
+	if errors.Is(err, sql.ErrNoRows) {
+		outcome = "not_found"
+	} else if err != nil {
+		outcome = "error"
+	}
+	dbDuration.WithLabelValues(repository, method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// Login records a login attempt.
+func Login(method string, success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// Backup records the outcome of a database backup.
+func Backup(success bool) {
+	if success {
+		backups.WithLabelValues("success").Inc()
+		lastBackup.SetToCurrentTime()
+		return
+	}
+	backups.WithLabelValues("failure").Inc()
+}
+
+// ReplicationSync records the outcome of a WAL replication sync. On success
+// the replica holds every transaction committed before readAt.
+func ReplicationSync(readAt time.Time, success bool) {
+	if success {
+		replicationSyncs.WithLabelValues("success").Inc()
+		replicatedAt.Set(float64(readAt.UnixNano()) / 1e9)
+		return
+	}
+	replicationSyncs.WithLabelValues("failure").Inc()
+}
+
+// SessionTracker remembers issued session tokens by hash until they expire.
+type SessionTracker struct {
+	mu      sync.Mutex
+	expires map[[32]byte]time.Time
+}
+
+// Start records a session token that is valid until expiresAt.
+func (s *SessionTracker) Start(token string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	s.expires[sha256.Sum256([]byte(token))] = expiresAt
+}
+
+// End forgets a session token, for example on logout.
+func (s *SessionTracker) End(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expires, sha256.Sum256([]byte(token)))
+}
+
+// Count returns the number of sessions that have not expired.
+func (s *SessionTracker) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return len(s.expires)
+}
+
+func (s *SessionTracker) prune() {
+	now := time.Now()
+	for key, expiresAt := range s.expires {
+		if now.After(expiresAt) {
+			delete(s.expires, key)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the raw OpenAPI 3.1 document describing the JSON API.
+func Document() []byte {
+	return document
+}
+
+// Spec is a parsed OpenAPI document that responses can be validated against.
+type Spec struct {
+	root  map[string]interface{}
+	paths map[string]map[string]interface{}
+}
+
+// Load parses the embedded OpenAPI document.
+func Load() (*Spec, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+
+	spec := &Spec{root: root, paths: map[string]map[string]interface{}{}}
+	paths, _ := root["paths"].(map[string]interface{})
+	for template, item := range paths {
+		operations, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path %s is not an object", template)
+		}
+		spec.paths[template] = operations
+	}
+
+	return spec, nil
+}
+
+// Operations returns "METHOD /path/template" for every operation in the spec,
+// sorted, so callers can check that each one is exercised.
+func (s *Spec) Operations() []string {
+	var operations []string
+	for template, item := range s.paths {
+		for method := range item {
+			switch method {
+			case "get", "post", "put", "patch", "delete":
+				operations = append(operations, strings.ToUpper(method)+" "+template)
+			}
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// ValidateResponse checks that a response for method and path is documented
+// and that its body matches the documented schema. It returns the operation
+// that matched, so that callers can track which ones were exercised.
+func (s *Spec) ValidateResponse(method, path string, statusCode int, header http.Header, body []byte) (string, error) {
+	template, item := s.matchPath(path)
+	if item == nil {
+		return "", fmt.Errorf("%s %s: path is not documented", method, path)
+	}
+	operation := strings.ToUpper(method) + " " + template
+
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		return operation, fmt.Errorf("%s: method is not documented", operation)
+	}
+
+	responses, _ := op["responses"].(map[string]interface{})
+	response, ok := responses[strconv.Itoa(statusCode)]
+	if !ok {
+		response, ok = responses["default"]
+	}
+	if !ok {
+		return operation, fmt.Errorf("%s: status %d is not documented", operation, statusCode)
+	}
+	responseObj, _ := s.resolve(response).(map[string]interface{})
+
+	if headers, ok := responseObj["headers"].(map[string]interface{}); ok {
+		for name := range headers {
+			if header.Get(name) == "" {
+				return operation, fmt.Errorf("%s %d: missing documented header %s", operation, statusCode, name)
+			}
+		}
+	}
+
+	content, ok := responseObj["content"].(map[string]interface{})
+	if !ok {
+		if len(body) != 0 {
+			return operation, fmt.Errorf("%s %d: expected an empty body, got %q", operation, statusCode, body)
+		}
+		return operation, nil
+	}
+
+	mediaType, _ := content["application/json"].(map[string]interface{})
+	if mediaType == nil {
+		return operation, fmt.Errorf("%s %d: only application/json responses can be validated", operation, statusCode)
+	}
+	if contentType := header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		return operation, fmt.Errorf("%s %d: expected Content-Type application/json, got %q", operation, statusCode, contentType)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return operation, fmt.Errorf("%s %d: invalid JSON body: %w", operation, statusCode, err)
+	}
+	if err := s.validate(mediaType["schema"], value, "$"); err != nil {
+		return operation, fmt.Errorf("%s %d: %w", operation, statusCode, err)
+	}
+
+	return operation, nil
+}
+
+// matchPath finds the path template that matches a concrete request path,
+// preferring templates with fewer parameters and, among those, the one whose
+// first parameter comes latest, as in /posts/tags over /posts/{id}.
+func (s *Spec) matchPath(path string) (string, map[string]interface{}) {
+	if item, ok := s.paths[path]; ok {
+		return path, item
+	}
+
+	segments := strings.Split(path, "/")
+	var candidates [][]string
+	for template := range s.paths {
+		templateSegments := strings.Split(template, "/")
+		if len(templateSegments) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range templateSegments {
+			if isParameter(segment) {
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			candidates = append(candidates, templateSegments)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if pa, pb := countParameters(a), countParameters(b); pa != pb {
+			return pa < pb
+		}
+		for k := range a {
+			if pa, pb := isParameter(a[k]), isParameter(b[k]); pa != pb {
+				return pb
+			}
+		}
+		return strings.Join(a, "/") < strings.Join(b, "/")
+	})
+	template := strings.Join(candidates[0], "/")
+	return template, s.paths[template]
+}
+
+func isParameter(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func countParameters(segments []string) int {
+	n := 0
+	for _, segment := range segments {
+		if isParameter(segment) {
+			n++
+		}
+	}
+	return n
+}
+
+// resolve follows a local "$ref" pointer such as "#/components/schemas/Post".
+func (s *Spec) resolve(node interface{}) interface{} {
+	for {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return node
+		}
+
+		var target interface{} = s.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := target.(map[string]interface{})
+			target = m[part]
+		}
+		node = target
+	}
+}
@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// validate checks value against the subset of JSON Schema used by the
+// document: type, properties, required, additionalProperties, items, enum,
+// minimum, maximum, minLength and the date and date-time formats.
+func (s *Spec) validate(schemaNode interface{}, value interface{}, at string) error {
+	schema, ok := s.resolve(schemaNode).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if err := checkType(schema["type"], value, at); err != nil {
+		return err
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if candidate == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", at, name)
+				}
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propertySchema, ok := properties[name]
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %q", at, name)
+				}
+				continue
+			}
+			if err := s.validate(propertySchema, v[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for i, item := range v {
+			if err := s.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(v)) < minLength {
+			return fmt.Errorf("%s: shorter than %v characters", at, minLength)
+		}
+		switch schema["format"] {
+		case "date":
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return fmt.Errorf("%s: %q is not a date", at, v)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, v)
+			}
+		}
+
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && v < minimum {
+			return fmt.Errorf("%s: %v is less than %v", at, v, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && v > maximum {
+			return fmt.Errorf("%s: %v is greater than %v", at, v, maximum)
+		}
+	}
+
+	return nil
+}
+
+func checkType(typeNode interface{}, value interface{}, at string) error {
+	var types []string
+	switch t := typeNode.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			types = append(types, name.(string))
+		}
+	default:
+		return nil
+	}
+
+	for _, name := range types {
+		if hasType(name, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %v, got %T", at, types, value)
+}
+
+func hasType(name string, value interface{}) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+package posts
+
+// DayCount is the number of posts written on one day, as YYYY-MM-DD.
+type DayCount struct {
+	Date      string `db:"day" json:"date"`
+	PostCount int64  `db:"post_count" json:"post_count"`
+}
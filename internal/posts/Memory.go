@@ -0,0 +1,10 @@
+package posts
+
+// Memory is what was written on the same day some years ago.
+type Memory struct {
+	YearsAgo int `json:"years_ago"`
+	// Date is the day it was written, as YYYY-MM-DD in the account's time
+	// zone.
+	Date  string `json:"date"`
+	Posts []Post `json:"posts"`
+}
@@ -1,9 +1,55 @@
 package posts
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 type Post struct {
-	Id        int64  `db:"id"`
-	Content   string `db:"content"`
-	CreatedAt string `db:"created_at"`
-	UpdatedAt string `db:"updated_at"`
-	AccountId int64  `db:"account_id"`
+	Id        int64    `db:"id" json:"id"`
+	Content   string   `db:"content" json:"content"`
+	CreatedAt string   `db:"created_at" json:"created_at"`
+	UpdatedAt string   `db:"updated_at" json:"updated_at"`
+	AccountId int64    `db:"account_id" json:"account_id"`
+	Tags      []string `db:"-" json:"tags"`
+}
+
+// ETag returns a strong entity tag identifying this version of the post.
+func (p Post) ETag() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(p.Id, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(p.UpdatedAt))
+	h.Write([]byte{0})
+	h.Write([]byte(p.Content))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(p.Tags, ",")))
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// ContentHash identifies the content of a post regardless of line endings
+// and surrounding whitespace. Imports use it to find duplicates.
+func ContentHash(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	sum := sha256.Sum256([]byte(strings.TrimSpace(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CountWords returns the number of words in content: runs of characters
+// between white space.
+func CountWords(content string) int {
+	return len(strings.Fields(content))
+}
+
+// Excerpt returns the first line of content, cut to at most n characters.
+func Excerpt(content string, n int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	if utf8.RuneCountInString(line) <= n {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:n])) + "…"
 }
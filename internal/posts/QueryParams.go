@@ -1,10 +1,16 @@
 package posts
 
+import "time"
+
 type QueryParams struct {
 	AccountId  int64  `query:"accountId"`
 	SearchText string `query:"searchText"`
 	DateFrom   string `query:"dateFrom"`
 	DateTo     string `query:"dateTo"`
+	Tag        string `query:"tag"`
 	PageNumber int64  `query:"pageNumber"`
 	PageSize   int64  `query:"pageSize"`
+	// Location is where the days DateFrom and DateTo start and end, the
+	// account's time zone. It is UTC when nil.
+	Location *time.Location `query:"-"`
 }
@@ -0,0 +1,30 @@
+package posts
+
+import (
+	"sort"
+	"strings"
+)
+
+type Tag struct {
+	Name      string `db:"tag" json:"name"`
+	PostCount int64  `db:"post_count" json:"post_count"`
+}
+
+// NormalizeTags lowercases, trims and de-duplicates tag names, dropping
+// empty ones and a leading '#'. Commas are not allowed inside a tag.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag = strings.TrimPrefix(tag, "#")
+		tag = strings.ReplaceAll(tag, ",", "")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,81 @@
+// Package reminders holds the recurring reminders to write in the journal,
+// which are published in the calendar feed.
+package reminders
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Weekdays are the days a reminder can repeat on, as iCalendar BYDAY codes
+// in the order of time.Weekday.
+var Weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// DefaultTitle is the title of reminders created without one.
+const DefaultTitle = "Write in the journal"
+
+// Reminder repeats at a time of day on some days of the week. The time has
+// no time zone: calendars show it at that time wherever the reader is.
+type Reminder struct {
+	Id        int64    `db:"id" json:"id"`
+	AccountId int64    `db:"account_id" json:"-"`
+	Title     string   `db:"title" json:"title"`
+	Time      string   `db:"time_of_day" json:"time"`
+	Weekdays  []string `db:"weekdays" json:"weekdays"`
+	CreatedAt string   `db:"created_at" json:"created_at"`
+}
+
+// Normalize checks a reminder and fills in the defaults: the title, and
+// every day when no days are given. Days are sorted from Sunday.
+func (r *Reminder) Normalize() error {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Title == "" {
+		r.Title = DefaultTitle
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(r.Time))
+	if err != nil {
+		return errors.New("time must be HH:MM, such as 21:00")
+	}
+	r.Time = t.Format("15:04")
+
+	if len(r.Weekdays) == 0 {
+		r.Weekdays = slices.Clone(Weekdays)
+		return nil
+	}
+	for _, d := range r.Weekdays {
+		if !slices.Contains(Weekdays, strings.ToUpper(d)) {
+			return errors.New("weekdays must be some of " + strings.Join(Weekdays, ", "))
+		}
+	}
+	var days []string
+	for _, day := range Weekdays {
+		if slices.ContainsFunc(r.Weekdays, func(d string) bool { return strings.EqualFold(d, day) }) {
+			days = append(days, day)
+		}
+	}
+	r.Weekdays = days
+	return nil
+}
+
+// Daily reports whether the reminder repeats every day.
+func (r Reminder) Daily() bool {
+	return len(r.Weekdays) == len(Weekdays)
+}
+
+// Schedule describes the days the reminder repeats on, such as "Mon, Thu".
+func (r Reminder) Schedule() string {
+	if r.Daily() {
+		return "Every day"
+	}
+	// Listed from Monday, like the days are offered.
+	var names []string
+	for i := 1; i <= len(Weekdays); i++ {
+		day := time.Weekday(i % 7)
+		if slices.Contains(r.Weekdays, Weekdays[day]) {
+			names = append(names, day.String()[:3])
+		}
+	}
+	return strings.Join(names, ", ")
+}
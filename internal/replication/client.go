@@ -0,0 +1,160 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Client stores replica objects under slash separated keys.
+type Client interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the keys starting with prefix in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, keys []string) error
+	// Ping checks that the replica is reachable and accessible.
+	Ping(ctx context.Context) error
+	// String describes the replica location without credentials.
+	String() string
+}
+
+// ClientOptions locate the replica. URL is a directory, a file:// URL or an
+// s3://bucket/prefix URL; the other fields only apply to S3.
+type ClientOptions struct {
+	URL             string
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewClient returns the Client for opts.URL.
+func NewClient(opts ClientOptions) (Client, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3Client(u.Host, strings.Trim(u.Path, "/"), opts)
+	case "file":
+		return &fileClient{dir: u.Path}, nil
+	case "":
+		return &fileClient{dir: opts.URL}, nil
+	}
+	return nil, fmt.Errorf("unsupported replica URL scheme %q", u.Scheme)
+}
+
+// fileClient keeps the replica in a local directory, typically a mounted
+// network volume.
+type fileClient struct {
+	dir string
+}
+
+func (c *fileClient) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+func (c *fileClient) Put(ctx context.Context, key string, r io.Reader, size int64) (err error) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	if _, err = io.Copy(f, r); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (c *fileClient) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(c.path(key))
+}
+
+func (c *fileClient) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return err
+		}
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (c *fileClient) Delete(ctx context.Context, keys []string) error {
+	var errs []error
+	dirs := map[string]bool{}
+	for _, key := range keys {
+		if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		dirs[filepath.Dir(c.path(key))] = true
+	}
+	// Remove directories left empty, deepest first.
+	paths := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		paths = append(paths, dir)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	for _, dir := range paths {
+		for dir != c.dir && strings.HasPrefix(dir, c.dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *fileClient) Ping(ctx context.Context) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, ".ping-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (c *fileClient) String() string {
+	return c.dir
+}
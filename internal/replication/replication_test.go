@@ -0,0 +1,153 @@
+package replication
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// openWAL opens a database the way the server does when it replicates: in
+// WAL mode, with automatic checkpoints turned off.
+func openWAL(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func readNotes(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT body FROM notes ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var notes []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			t.Fatal(err)
+		}
+		notes = append(notes, body)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return notes
+}
+
+// testPointInTimeRestore writes, ships, writes more and ships again, then
+// restores the replica to a time between the two writes and to the latest
+// state.
+func testPointInTimeRestore(t *testing.T, client Client, opts Options) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.db")
+	db := openWAL(t, path)
+	r := NewReplicator(db, path, client, opts)
+	t.Cleanup(func() { r.Close(context.Background()) })
+
+	write := func(notes ...string) {
+		t.Helper()
+		for _, note := range notes {
+			if _, err := db.Exec("INSERT INTO notes (body) VALUES (?)", note); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("in the snapshot")
+	write("first", "second")
+	// Segments are named to the millisecond.
+	time.Sleep(5 * time.Millisecond)
+	between := time.Now().UTC()
+	time.Sleep(5 * time.Millisecond)
+	write("third")
+
+	restored := filepath.Join(dir, "between.db")
+	restoredTo, err := Restore(ctx, client, restored, between)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"in the snapshot", "first", "second"}; !slices.Equal(readNotes(t, restored), want) {
+		t.Errorf("restored to %s: got %q, want %q", between, readNotes(t, restored), want)
+	}
+	if restoredTo.After(between) {
+		t.Errorf("restored to %s, after the requested %s", restoredTo, between)
+	}
+
+	latest := filepath.Join(dir, "latest.db")
+	if _, err := Restore(ctx, client, latest, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"in the snapshot", "first", "second", "third"}; !slices.Equal(readNotes(t, latest), want) {
+		t.Errorf("restored the latest state: got %q, want %q", readNotes(t, latest), want)
+	}
+}
+
+func TestRestoreDirectoryReplica(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"without checkpoints":     {SnapshotInterval: time.Hour},
+		"checkpointing each sync": {SnapshotInterval: time.Hour, CheckpointFrames: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(ClientOptions{URL: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			testPointInTimeRestore(t, client, opts)
+		})
+	}
+}
+
+// TestRestoreS3Replica runs against an S3 compatible store such as MinIO when
+// JOURNAL_TEST_S3_URL is set to an s3://bucket/prefix URL. Each run writes
+// under a prefix of its own and deletes it afterwards.
+func TestRestoreS3Replica(t *testing.T) {
+	replicaURL := os.Getenv("JOURNAL_TEST_S3_URL")
+	if replicaURL == "" {
+		t.Skip("JOURNAL_TEST_S3_URL is not set")
+	}
+	client, err := NewClient(ClientOptions{
+		URL:             replicaURL + "/" + strconv.FormatInt(time.Now().UnixNano(), 10),
+		Endpoint:        os.Getenv("JOURNAL_TEST_S3_ENDPOINT"),
+		Region:          os.Getenv("JOURNAL_TEST_S3_REGION"),
+		AccessKeyID:     os.Getenv("JOURNAL_TEST_S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("JOURNAL_TEST_S3_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, err := client.List(ctx, "")
+		if err == nil {
+			err = client.Delete(ctx, keys)
+		}
+		if err != nil {
+			t.Errorf("cleaning up the replica: %v", err)
+		}
+	})
+	testPointInTimeRestore(t, client, Options{SnapshotInterval: time.Hour})
+}
@@ -0,0 +1,337 @@
+package replication
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options control how often the replicator ships, snapshots and prunes.
+type Options struct {
+	// SnapshotInterval is how long a generation lasts before a new snapshot
+	// starts the next one. Restoring replays at most this much WAL.
+	SnapshotInterval time.Duration
+	// Retention is how far back point-in-time restore must reach. Older
+	// generations are deleted once a newer one covers that window.
+	Retention time.Duration
+	// CheckpointFrames is the number of shipped WAL frames after which the
+	// replicator checkpoints the WAL into the database file.
+	CheckpointFrames int64
+}
+
+// Status describes the replication state for the status page and API.
+type Status struct {
+	Replica             string     `json:"replica"`
+	Generation          string     `json:"generation,omitempty"`
+	GenerationStartedAt *time.Time `json:"generation_started_at,omitempty"`
+	Segments            int        `json:"segments"`
+	LastSyncAt          *time.Time `json:"last_sync_at,omitempty"`
+	// ReplicatedAt is the last time every committed transaction was in the
+	// replica. The lag is the time since then.
+	ReplicatedAt *time.Time `json:"replicated_at,omitempty"`
+	LagSeconds   float64    `json:"lag_seconds"`
+	PendingBytes int64      `json:"pending_bytes"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// errGenerationBroken means frames may have been lost from the WAL stream,
+// so replication must continue from a new snapshot.
+var errGenerationBroken = errors.New("WAL was restarted before it was replicated")
+
+// Replicator continuously copies a SQLite database to a replica, Litestream
+// style: a snapshot of the database file starts a generation, and every
+// committed WAL frame after it is shipped as a segment.
+//
+// The database must be in WAL mode with automatic checkpoints turned off, so
+// that the database file only changes when the replicator checkpoints it
+// after shipping the frames involved.
+type Replicator struct {
+	db     *sql.DB
+	path   string
+	client Client
+	opts   Options
+
+	// syncMu serializes Sync and guards the fields below it.
+	syncMu sync.Mutex
+	// conn is held for the replicator's lifetime so that SQLite never sees
+	// its last connection close, which would checkpoint and remove the WAL.
+	conn       *sql.Conn
+	generation string
+	started    time.Time
+	index      int
+	pos        walPosition
+	hasPos     bool
+	// restartOK is set once every frame of the WAL has been shipped and
+	// checkpointed, so that SQLite may start the log over.
+	restartOK bool
+
+	mu     sync.Mutex
+	status Status
+}
+
+func NewReplicator(db *sql.DB, path string, client Client, opts Options) *Replicator {
+	if opts.CheckpointFrames <= 0 {
+		opts.CheckpointFrames = 1000
+	}
+	return &Replicator{
+		db:     db,
+		path:   path,
+		client: client,
+		opts:   opts,
+		status: Status{Replica: client.String()},
+	}
+}
+
+// Sync ships the WAL frames committed since the last call, checkpoints the
+// WAL once it has grown and starts a new generation when one is due.
+func (r *Replicator) Sync(ctx context.Context) (err error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	readAt := time.Now().UTC()
+	defer func() { r.recordSync(readAt, err) }()
+
+	if r.conn == nil {
+		if r.conn, err = r.db.Conn(ctx); err != nil {
+			return err
+		}
+	}
+	if r.generation == "" || time.Since(r.started) >= r.opts.SnapshotInterval {
+		return r.startGeneration(ctx)
+	}
+
+	err = r.ship(ctx)
+	if errors.Is(err, errGenerationBroken) {
+		return r.startGeneration(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if r.pos.frames() >= r.opts.CheckpointFrames {
+		return r.checkpoint(ctx)
+	}
+	return nil
+}
+
+// Close ships what is left and releases the replicator's connection. Call
+// it before the database is closed.
+func (r *Replicator) Close(ctx context.Context) error {
+	err := r.Sync(ctx)
+
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	if r.conn != nil {
+		err = errors.Join(err, r.conn.Close())
+		r.conn = nil
+	}
+	return err
+}
+
+// Status returns the current replication state.
+func (r *Replicator) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	if status.ReplicatedAt != nil {
+		status.LagSeconds = time.Since(*status.ReplicatedAt).Seconds()
+	}
+	return status
+}
+
+// Ping checks that the replica is reachable.
+func (r *Replicator) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx)
+}
+
+// Generations lists the generations in the replica, newest first.
+func (r *Replicator) Generations(ctx context.Context) ([]Generation, error) {
+	return Generations(ctx, r.client)
+}
+
+func (r *Replicator) recordSync(readAt time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	r.status.LastSyncAt = &now
+	r.status.LastError = ""
+	if err != nil {
+		r.status.LastError = err.Error()
+		return
+	}
+	if r.generation != "" {
+		started := r.started
+		r.status.Generation = r.generation
+		r.status.GenerationStartedAt = &started
+		r.status.ReplicatedAt = &readAt
+	}
+	r.status.Segments = r.index
+	r.status.PendingBytes = 0
+	if info, err := os.Stat(r.path + "-wal"); err == nil && r.hasPos && info.Size() > r.pos.Offset {
+		r.status.PendingBytes = info.Size() - r.pos.Offset
+	}
+}
+
+// startGeneration checkpoints the whole WAL into the database file and
+// uploads a copy of the file as the snapshot of a new generation.
+func (r *Replicator) startGeneration(ctx context.Context) error {
+	r.generation = ""
+
+	var busy, logFrames, checkpointed int
+	if err := r.conn.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return fmt.Errorf("checkpoint failed: %w", err)
+	}
+	if busy != 0 {
+		return errors.New("checkpoint was blocked by open transactions")
+	}
+
+	// Automatic checkpoints are off, so the file stays as it is until the
+	// next checkpoint, which only this replicator runs.
+	started := time.Now().UTC()
+	generation := started.Format(timeLayout)
+	if err := r.upload(ctx, snapshotKey(generation), func(w io.Writer) error {
+		f, err := os.Open(r.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+
+	r.generation, r.started, r.index = generation, started, 0
+	r.pos, r.hasPos, r.restartOK = walPosition{}, false, false
+
+	if err := r.prune(ctx, started); err != nil {
+		return fmt.Errorf("failed to delete old generations: %w", err)
+	}
+	return nil
+}
+
+// ship uploads the frames committed since the last position as the next
+// segment of the generation.
+func (r *Replicator) ship(ctx context.Context) error {
+	f, err := os.Open(r.path + "-wal")
+	if errors.Is(err, os.ErrNotExist) {
+		if r.hasPos {
+			return errGenerationBroken
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if !r.hasPos {
+		// The WAL was truncated when the generation started; the first
+		// header written since then begins the stream.
+		pos, ok, err := readWALHeader(f)
+		if err != nil || !ok {
+			return err
+		}
+		r.pos, r.hasPos = pos, true
+	}
+
+	frames, next, err := readWALFrames(f, r.pos)
+	if errors.Is(err, errWALRestarted) {
+		if !r.restartOK {
+			return errGenerationBroken
+		}
+		pos, ok, headerErr := readWALHeader(f)
+		if headerErr != nil || !ok {
+			return headerErr
+		}
+		r.pos, r.restartOK = pos, false
+		frames, next, err = readWALFrames(f, r.pos)
+	}
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	key := segmentKey(r.generation, r.index, time.Now().UTC())
+	if err := r.upload(ctx, key, func(w io.Writer) error {
+		_, err := w.Write(frames)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to upload WAL segment: %w", err)
+	}
+	r.index++
+	r.pos = next
+	return nil
+}
+
+// checkpoint copies the WAL into the database file. It holds the write lock
+// while it ships the remaining frames and checkpoints, so that no frame can
+// be checkpointed without having been shipped first.
+func (r *Replicator) checkpoint(ctx context.Context) (err error) {
+	if _, err := r.conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	defer func() {
+		_, rollbackErr := r.conn.ExecContext(context.Background(), "ROLLBACK")
+		err = errors.Join(err, rollbackErr)
+	}()
+
+	if err := r.ship(ctx); err != nil {
+		return err
+	}
+
+	// A passive checkpoint does not need the write lock held above, and
+	// cannot be passed by new commits while it is held.
+	var busy, logFrames, checkpointed int64
+	if err := r.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return fmt.Errorf("checkpoint failed: %w", err)
+	}
+	r.restartOK = busy == 0 && checkpointed == logFrames && logFrames == r.pos.frames()
+	return nil
+}
+
+// upload streams what write produces to key, gzip compressed.
+func (r *Replicator) upload(ctx context.Context, key string, write func(w io.Writer) error) error {
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		err := write(gz)
+		if err == nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	err := r.client.Put(ctx, key, pr, -1)
+	pr.CloseWithError(err)
+	return err
+}
+
+// prune deletes the generations that are no longer needed to restore to any
+// point within the retention window: those followed by a generation that
+// itself started before the window.
+func (r *Replicator) prune(ctx context.Context, now time.Time) error {
+	generations, err := listGenerations(ctx, r.client)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-r.opts.Retention)
+	var keys []string
+	for i := 0; i+1 < len(generations); i++ {
+		if generations[i+1].StartedAt.After(cutoff) {
+			break
+		}
+		keys = append(keys, generations[i].keys...)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Delete(ctx, keys)
+}
@@ -0,0 +1,222 @@
+package replication
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys in the replica:
+//
+//	generations/<started>/snapshot.db.gz
+//	generations/<started>/wal/<index>-<shipped>.wal.gz
+const (
+	generationsPrefix = "generations/"
+	timeLayout        = "20060102T150405.000Z"
+)
+
+func snapshotKey(generation string) string {
+	return generationsPrefix + generation + "/snapshot.db.gz"
+}
+
+func segmentKey(generation string, index int, shippedAt time.Time) string {
+	return fmt.Sprintf("%s%s/wal/%010d-%s.wal.gz", generationsPrefix, generation, index, shippedAt.Format(timeLayout))
+}
+
+// Generation is a snapshot and the WAL segments shipped after it.
+type Generation struct {
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+	Segments  int       `json:"segments"`
+	// LatestAt is the shipping time of the last segment, the latest point
+	// this generation can be restored to.
+	LatestAt time.Time `json:"latest_at"`
+
+	hasSnapshot bool
+	segments    []segment
+	keys        []string
+}
+
+type segment struct {
+	index     int
+	shippedAt time.Time
+	key       string
+}
+
+// Generations lists the restorable generations in the replica, oldest first.
+func Generations(ctx context.Context, client Client) ([]Generation, error) {
+	generations, err := listGenerations(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var restorable []Generation
+	for _, g := range generations {
+		if g.hasSnapshot {
+			restorable = append(restorable, g)
+		}
+	}
+	return restorable, nil
+}
+
+func listGenerations(ctx context.Context, client Client) ([]Generation, error) {
+	keys, err := client.List(ctx, generationsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*Generation{}
+	var generations []*Generation
+	for _, key := range keys {
+		name, rest, ok := strings.Cut(strings.TrimPrefix(key, generationsPrefix), "/")
+		if !ok {
+			continue
+		}
+		g := byName[name]
+		if g == nil {
+			startedAt, err := time.Parse(timeLayout, name)
+			if err != nil {
+				continue
+			}
+			g = &Generation{Name: name, StartedAt: startedAt, LatestAt: startedAt}
+			byName[name] = g
+			generations = append(generations, g)
+		}
+		g.keys = append(g.keys, key)
+
+		if rest == "snapshot.db.gz" {
+			g.hasSnapshot = true
+		} else if s, ok := parseSegmentKey(rest); ok {
+			s.key = key
+			g.segments = append(g.segments, s)
+		}
+	}
+
+	result := make([]Generation, 0, len(generations))
+	for _, g := range generations {
+		sort.Slice(g.segments, func(i, j int) bool { return g.segments[i].index < g.segments[j].index })
+		g.Segments = len(g.segments)
+		if len(g.segments) > 0 {
+			g.LatestAt = g.segments[len(g.segments)-1].shippedAt
+		}
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartedAt.Before(result[j].StartedAt) })
+	return result, nil
+}
+
+func parseSegmentKey(rest string) (segment, bool) {
+	name, ok := strings.CutPrefix(rest, "wal/")
+	if !ok {
+		return segment{}, false
+	}
+	name, ok = strings.CutSuffix(name, ".wal.gz")
+	if !ok {
+		return segment{}, false
+	}
+	index, shippedAt, ok := strings.Cut(name, "-")
+	if !ok {
+		return segment{}, false
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return segment{}, false
+	}
+	t, err := time.Parse(timeLayout, shippedAt)
+	if err != nil {
+		return segment{}, false
+	}
+	return segment{index: i, shippedAt: t}, true
+}
+
+// Restore rebuilds the database as of at into the file dst, which must not
+// exist yet. A zero at restores the latest state. It picks the last
+// generation started by then and replays its segments shipped by then, and
+// returns the time of the last segment applied.
+func Restore(ctx context.Context, client Client, dst string, at time.Time) (time.Time, error) {
+	generations, err := Generations(ctx, client)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var g *Generation
+	for i := range generations {
+		if at.IsZero() || !generations[i].StartedAt.After(at) {
+			g = &generations[i]
+		}
+	}
+	if g == nil {
+		return time.Time{}, errors.New("the replica has no generation that old")
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if err != nil {
+		return time.Time{}, err
+	}
+	restoredTo, err := restoreGeneration(ctx, client, f, g, at)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return time.Time{}, err
+	}
+	return restoredTo, nil
+}
+
+func restoreGeneration(ctx context.Context, client Client, f *os.File, g *Generation, at time.Time) (time.Time, error) {
+	if err := download(ctx, client, snapshotKey(g.Name), f); err != nil {
+		return time.Time{}, fmt.Errorf("failed to download snapshot: %w", err)
+	}
+
+	// The page size is stored big-endian at offset 16, with 1 meaning 65536.
+	header := make([]byte, 18)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return time.Time{}, fmt.Errorf("snapshot is not a database: %w", err)
+	}
+	pageSize := int64(binary.BigEndian.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+
+	restoredTo := g.StartedAt
+	for i, s := range g.segments {
+		if !at.IsZero() && s.shippedAt.After(at) {
+			break
+		}
+		if s.index != i {
+			return time.Time{}, fmt.Errorf("WAL segment %d is missing from generation %s", i, g.Name)
+		}
+		var frames bytes.Buffer
+		if err := download(ctx, client, s.key, &frames); err != nil {
+			return time.Time{}, fmt.Errorf("failed to download WAL segment %d: %w", i, err)
+		}
+		if err := applyWALFrames(f, frames.Bytes(), pageSize); err != nil {
+			return time.Time{}, fmt.Errorf("failed to apply WAL segment %d: %w", i, err)
+		}
+		restoredTo = s.shippedAt
+	}
+	return restoredTo, f.Sync()
+}
+
+func download(ctx context.Context, client Client, key string, w io.Writer) error {
+	r, err := client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	_, err = io.Copy(w, gz)
+	return err
+}
@@ -0,0 +1,122 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3Client keeps the replica in an S3 compatible bucket such as AWS S3 or
+// MinIO.
+type s3Client struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3Client(bucket string, prefix string, opts ClientOptions) (*s3Client, error) {
+	endpoint, secure := "s3.amazonaws.com", true
+	if opts.Endpoint != "" {
+		u, err := url.Parse(opts.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+		}
+		endpoint, secure = u.Host, u.Scheme == "https"
+	}
+
+	// Without explicit keys, fall back to the usual AWS environment
+	// variables and instance credentials.
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+	})
+	if opts.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(opts.AccessKeyID, opts.SecretAccessKey, "")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: secure,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3Client{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (c *s3Client) key(key string) string {
+	return path.Join(c.prefix, key)
+}
+
+func (c *s3Client) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := c.client.PutObject(ctx, c.bucket, c.key(key), r, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (c *s3Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := c.client.GetObject(ctx, c.bucket, c.key(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat surfaces a missing object right away.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (c *s3Client) List(ctx context.Context, prefix string) ([]string, error) {
+	root := ""
+	if c.prefix != "" {
+		root = c.prefix + "/"
+	}
+	var keys []string
+	for obj := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Prefix: root + prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		keys = append(keys, obj.Key[len(root):])
+	}
+	return keys, nil
+}
+
+func (c *s3Client) Delete(ctx context.Context, keys []string) error {
+	objects := make(chan minio.ObjectInfo, len(keys))
+	for _, key := range keys {
+		objects <- minio.ObjectInfo{Key: c.key(key)}
+	}
+	close(objects)
+
+	var errs []error
+	for result := range c.client.RemoveObjects(ctx, c.bucket, objects, minio.RemoveObjectsOptions{}) {
+		errs = append(errs, result.Err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *s3Client) Ping(ctx context.Context) error {
+	exists, err := c.client.BucketExists(ctx, c.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", c.bucket)
+	}
+	return nil
+}
+
+func (c *s3Client) String() string {
+	return "s3://" + path.Join(c.bucket, c.prefix)
+}
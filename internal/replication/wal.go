@@ -0,0 +1,152 @@
+package replication
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// See https://www.sqlite.org/fileformat2.html#walformat for the layout of
+// the write-ahead log.
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+)
+
+// errWALRestarted means the WAL header no longer matches the position, so
+// SQLite has started writing the log from the beginning.
+var errWALRestarted = errors.New("WAL was restarted")
+
+// walPosition identifies a commit boundary in one incarnation of the WAL.
+// The salts change every time SQLite restarts the log and the running
+// checksum chains every frame to the ones before it.
+type walPosition struct {
+	Salt1, Salt2   uint32
+	Cksum1, Cksum2 uint32
+	Offset         int64
+	PageSize       int64
+	BigEndian      bool
+}
+
+// frames returns the number of frames before the position.
+func (p walPosition) frames() int64 {
+	if p.Offset < walHeaderSize {
+		return 0
+	}
+	return (p.Offset - walHeaderSize) / (walFrameHeaderSize + p.PageSize)
+}
+
+// readWALHeader returns the position of the first frame of the WAL in f, or
+// ok false if the WAL is empty or its header is not valid yet.
+func readWALHeader(f *os.File) (pos walPosition, ok bool, err error) {
+	header := make([]byte, walHeaderSize)
+	if _, err := f.ReadAt(header, 0); errors.Is(err, io.EOF) {
+		return pos, false, nil
+	} else if err != nil {
+		return pos, false, err
+	}
+
+	magic := binary.BigEndian.Uint32(header[0:])
+	if magic != 0x377f0682 && magic != 0x377f0683 {
+		return pos, false, nil
+	}
+	pos = walPosition{
+		PageSize:  int64(binary.BigEndian.Uint32(header[8:])),
+		Salt1:     binary.BigEndian.Uint32(header[16:]),
+		Salt2:     binary.BigEndian.Uint32(header[20:]),
+		Offset:    walHeaderSize,
+		BigEndian: magic&1 == 1,
+	}
+	pos.Cksum1, pos.Cksum2 = walChecksum(pos.BigEndian, 0, 0, header[:24])
+	if pos.Cksum1 != binary.BigEndian.Uint32(header[24:]) || pos.Cksum2 != binary.BigEndian.Uint32(header[28:]) {
+		return walPosition{}, false, nil
+	}
+	return pos, true, nil
+}
+
+// readWALFrames reads the committed frames after pos. It stops at the first
+// frame that belongs to another incarnation of the WAL or fails its checksum,
+// and returns the frames up to the last commit together with the position
+// after them.
+func readWALFrames(f *os.File, pos walPosition) ([]byte, walPosition, error) {
+	header, ok, err := readWALHeader(f)
+	if err != nil {
+		return nil, pos, err
+	}
+	if !ok || header.Salt1 != pos.Salt1 || header.Salt2 != pos.Salt2 {
+		return nil, pos, errWALRestarted
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, pos, err
+	}
+	if info.Size() <= pos.Offset {
+		return nil, pos, nil
+	}
+	buf := make([]byte, info.Size()-pos.Offset)
+	n, err := f.ReadAt(buf, pos.Offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, pos, err
+	}
+	buf = buf[:n]
+
+	frameSize := walFrameHeaderSize + pos.PageSize
+	committed, end := 0, pos
+	cksum1, cksum2 := pos.Cksum1, pos.Cksum2
+	for off := int64(0); off+frameSize <= int64(len(buf)); off += frameSize {
+		frame := buf[off : off+frameSize]
+		if binary.BigEndian.Uint32(frame[8:]) != pos.Salt1 || binary.BigEndian.Uint32(frame[12:]) != pos.Salt2 {
+			break
+		}
+		cksum1, cksum2 = walChecksum(pos.BigEndian, cksum1, cksum2, frame[:8])
+		cksum1, cksum2 = walChecksum(pos.BigEndian, cksum1, cksum2, frame[walFrameHeaderSize:])
+		if cksum1 != binary.BigEndian.Uint32(frame[16:]) || cksum2 != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+		// A non-zero database size marks the last frame of a transaction.
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			committed = int(off + frameSize)
+			end.Offset = pos.Offset + off + frameSize
+			end.Cksum1, end.Cksum2 = cksum1, cksum2
+		}
+	}
+	return buf[:committed], end, nil
+}
+
+// walChecksum continues the WAL checksum s1, s2 over b, whose length must be
+// a multiple of 8.
+func walChecksum(bigEndian bool, s1, s2 uint32, b []byte) (uint32, uint32) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(b); i += 8 {
+		s1 += order.Uint32(b[i:]) + s2
+		s2 += order.Uint32(b[i+4:]) + s1
+	}
+	return s1, s2
+}
+
+// applyWALFrames writes the page images of frames into the database file
+// and truncates it to the size recorded at each commit.
+func applyWALFrames(db *os.File, frames []byte, pageSize int64) error {
+	frameSize := walFrameHeaderSize + pageSize
+	if int64(len(frames))%frameSize != 0 {
+		return errors.New("WAL segment is not a whole number of frames")
+	}
+	for off := int64(0); off < int64(len(frames)); off += frameSize {
+		frame := frames[off : off+frameSize]
+		pgno := int64(binary.BigEndian.Uint32(frame[0:]))
+		if _, err := db.WriteAt(frame[walFrameHeaderSize:], (pgno-1)*pageSize); err != nil {
+			return err
+		}
+		if size := int64(binary.BigEndian.Uint32(frame[4:])); size != 0 {
+			if err := db.Truncate(size * pageSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
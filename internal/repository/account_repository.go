@@ -9,5 +9,13 @@ import (
 type AccountRepository interface {
 	CreateAccount(ctx context.Context, account accounts.Account) (int64, error)
 	DeleteAccountById(ctx context.Context, accountId int64) error
+	GetAccountById(ctx context.Context, accountId int64) (accounts.Account, error)
+	GetAccountByUsername(ctx context.Context, username string) (accounts.Account, error)
+	GetAccountByIdentity(ctx context.Context, issuer string, subject string) (accounts.Account, error)
+	ListAccounts(ctx context.Context) ([]accounts.Account, error)
+	SetAccountDisabledAt(ctx context.Context, accountId int64, disabledAt string) error
+	SetAccountTimeZone(ctx context.Context, accountId int64, timeZone string) error
+	UpdatePasswordHash(ctx context.Context, accountId int64, passwordHash string) error
+	CreateIdentity(ctx context.Context, identity accounts.Identity) error
 	RetrieveCountOfAccountsWithUsername(ctx context.Context, username string) (int, error)
 }
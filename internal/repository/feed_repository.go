@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"journal-lite/internal/feeds"
+)
+
+type FeedRepository interface {
+	CreateFeed(ctx context.Context, feed feeds.Feed) (feeds.Feed, error)
+	GetFeeds(ctx context.Context, accountId int64) ([]feeds.Feed, error)
+	GetFeedByHash(ctx context.Context, tokenHash string) (feeds.Feed, error)
+	DeleteFeed(ctx context.Context, accountId int64, feedId int64) error
+	UpdateFeedLastFetched(ctx context.Context, feedId int64, lastFetchedAt string) error
+}
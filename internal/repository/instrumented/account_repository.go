@@ -0,0 +1,121 @@
+// internal/repository/instrumented/account_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// accountRepository wraps every call to the underlying repository in a span
+// and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type accountRepository struct {
+	next repository.AccountRepository
+}
+
+func NewAccountRepository(next repository.AccountRepository) repository.AccountRepository {
+	return &accountRepository{next: next}
+}
+
+func (r *accountRepository) CreateAccount(ctx context.Context, account accounts.Account) (int64, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.CreateAccount")
+	start := time.Now()
+	result, err := r.next.CreateAccount(ctx, account)
+	metrics.ObserveQuery("account", "CreateAccount", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *accountRepository) DeleteAccountById(ctx context.Context, accountId int64) error {
+	ctx, span := tracing.Start(ctx, "AccountRepository.DeleteAccountById")
+	start := time.Now()
+	err := r.next.DeleteAccountById(ctx, accountId)
+	metrics.ObserveQuery("account", "DeleteAccountById", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *accountRepository) GetAccountById(ctx context.Context, accountId int64) (accounts.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.GetAccountById")
+	start := time.Now()
+	result, err := r.next.GetAccountById(ctx, accountId)
+	metrics.ObserveQuery("account", "GetAccountById", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *accountRepository) GetAccountByUsername(ctx context.Context, username string) (accounts.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.GetAccountByUsername")
+	start := time.Now()
+	result, err := r.next.GetAccountByUsername(ctx, username)
+	metrics.ObserveQuery("account", "GetAccountByUsername", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *accountRepository) GetAccountByIdentity(ctx context.Context, issuer string, subject string) (accounts.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.GetAccountByIdentity")
+	start := time.Now()
+	result, err := r.next.GetAccountByIdentity(ctx, issuer, subject)
+	metrics.ObserveQuery("account", "GetAccountByIdentity", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *accountRepository) ListAccounts(ctx context.Context) ([]accounts.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.ListAccounts")
+	start := time.Now()
+	result, err := r.next.ListAccounts(ctx)
+	metrics.ObserveQuery("account", "ListAccounts", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *accountRepository) SetAccountDisabledAt(ctx context.Context, accountId int64, disabledAt string) error {
+	ctx, span := tracing.Start(ctx, "AccountRepository.SetAccountDisabledAt")
+	start := time.Now()
+	err := r.next.SetAccountDisabledAt(ctx, accountId, disabledAt)
+	metrics.ObserveQuery("account", "SetAccountDisabledAt", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *accountRepository) SetAccountTimeZone(ctx context.Context, accountId int64, timeZone string) error {
+	ctx, span := tracing.Start(ctx, "AccountRepository.SetAccountTimeZone")
+	start := time.Now()
+	err := r.next.SetAccountTimeZone(ctx, accountId, timeZone)
+	metrics.ObserveQuery("account", "SetAccountTimeZone", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *accountRepository) UpdatePasswordHash(ctx context.Context, accountId int64, passwordHash string) error {
+	ctx, span := tracing.Start(ctx, "AccountRepository.UpdatePasswordHash")
+	start := time.Now()
+	err := r.next.UpdatePasswordHash(ctx, accountId, passwordHash)
+	metrics.ObserveQuery("account", "UpdatePasswordHash", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *accountRepository) CreateIdentity(ctx context.Context, identity accounts.Identity) error {
+	ctx, span := tracing.Start(ctx, "AccountRepository.CreateIdentity")
+	start := time.Now()
+	err := r.next.CreateIdentity(ctx, identity)
+	metrics.ObserveQuery("account", "CreateIdentity", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *accountRepository) RetrieveCountOfAccountsWithUsername(ctx context.Context, username string) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountRepository.RetrieveCountOfAccountsWithUsername")
+	start := time.Now()
+	result, err := r.next.RetrieveCountOfAccountsWithUsername(ctx, username)
+	metrics.ObserveQuery("account", "RetrieveCountOfAccountsWithUsername", start, err)
+	tracing.End(span, err)
+	return result, err
+}
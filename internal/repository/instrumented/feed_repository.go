@@ -0,0 +1,68 @@
+// internal/repository/instrumented/feed_repository.go
+// internal/repository/instrumented/feed_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// feedRepository wraps every call to the underlying repository in a span
+// and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type feedRepository struct {
+	next repository.FeedRepository
+}
+
+func NewFeedRepository(next repository.FeedRepository) repository.FeedRepository {
+	return &feedRepository{next: next}
+}
+
+func (r *feedRepository) CreateFeed(ctx context.Context, feed feeds.Feed) (feeds.Feed, error) {
+	ctx, span := tracing.Start(ctx, "FeedRepository.CreateFeed")
+	start := time.Now()
+	result, err := r.next.CreateFeed(ctx, feed)
+	metrics.ObserveQuery("feed", "CreateFeed", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *feedRepository) GetFeeds(ctx context.Context, accountId int64) ([]feeds.Feed, error) {
+	ctx, span := tracing.Start(ctx, "FeedRepository.GetFeeds")
+	start := time.Now()
+	result, err := r.next.GetFeeds(ctx, accountId)
+	metrics.ObserveQuery("feed", "GetFeeds", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *feedRepository) GetFeedByHash(ctx context.Context, tokenHash string) (feeds.Feed, error) {
+	ctx, span := tracing.Start(ctx, "FeedRepository.GetFeedByHash")
+	start := time.Now()
+	result, err := r.next.GetFeedByHash(ctx, tokenHash)
+	metrics.ObserveQuery("feed", "GetFeedByHash", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *feedRepository) DeleteFeed(ctx context.Context, accountId int64, feedId int64) error {
+	ctx, span := tracing.Start(ctx, "FeedRepository.DeleteFeed")
+	start := time.Now()
+	err := r.next.DeleteFeed(ctx, accountId, feedId)
+	metrics.ObserveQuery("feed", "DeleteFeed", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *feedRepository) UpdateFeedLastFetched(ctx context.Context, feedId int64, lastFetchedAt string) error {
+	ctx, span := tracing.Start(ctx, "FeedRepository.UpdateFeedLastFetched")
+	start := time.Now()
+	err := r.next.UpdateFeedLastFetched(ctx, feedId, lastFetchedAt)
+	metrics.ObserveQuery("feed", "UpdateFeedLastFetched", start, err)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,148 @@
+// internal/repository/instrumented/post_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// postRepository wraps every call to the underlying repository in a span
+// and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type postRepository struct {
+	next repository.PostRepository
+}
+
+func NewPostRepository(next repository.PostRepository) repository.PostRepository {
+	return &postRepository{next: next}
+}
+
+func (r *postRepository) CreatePost(ctx context.Context, post posts.Post) (posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.CreatePost")
+	start := time.Now()
+	result, err := r.next.CreatePost(ctx, post)
+	metrics.ObserveQuery("post", "CreatePost", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) CreatePosts(ctx context.Context, postsList []posts.Post) ([]posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.CreatePosts")
+	start := time.Now()
+	result, err := r.next.CreatePosts(ctx, postsList)
+	metrics.ObserveQuery("post", "CreatePosts", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) DeletePost(ctx context.Context, postId int64) error {
+	ctx, span := tracing.Start(ctx, "PostRepository.DeletePost")
+	start := time.Now()
+	err := r.next.DeletePost(ctx, postId)
+	metrics.ObserveQuery("post", "DeletePost", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *postRepository) GetPosts(ctx context.Context, params posts.QueryParams) ([]posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.GetPosts")
+	start := time.Now()
+	result, err := r.next.GetPosts(ctx, params)
+	metrics.ObserveQuery("post", "GetPosts", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) CountPosts(ctx context.Context, params posts.QueryParams) (int64, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.CountPosts")
+	start := time.Now()
+	result, err := r.next.CountPosts(ctx, params)
+	metrics.ObserveQuery("post", "CountPosts", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) ForEachPost(ctx context.Context, userId int64, fn func(posts.Post) error) error {
+	ctx, span := tracing.Start(ctx, "PostRepository.ForEachPost")
+	start := time.Now()
+	err := r.next.ForEachPost(ctx, userId, fn)
+	metrics.ObserveQuery("post", "ForEachPost", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *postRepository) GetPost(ctx context.Context, userId int64, postId int64) (posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.GetPost")
+	start := time.Now()
+	result, err := r.next.GetPost(ctx, userId, postId)
+	metrics.ObserveQuery("post", "GetPost", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) UpdatePost(ctx context.Context, newContent string, postId int64) error {
+	ctx, span := tracing.Start(ctx, "PostRepository.UpdatePost")
+	start := time.Now()
+	err := r.next.UpdatePost(ctx, newContent, postId)
+	metrics.ObserveQuery("post", "UpdatePost", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *postRepository) EditPost(ctx context.Context, userId int64, postId int64, etags []string, content *string, tags *[]string) (posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.EditPost")
+	start := time.Now()
+	result, err := r.next.EditPost(ctx, userId, postId, etags, content, tags)
+	metrics.ObserveQuery("post", "EditPost", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) RemovePost(ctx context.Context, userId int64, postId int64, etags []string) (posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.RemovePost")
+	start := time.Now()
+	result, err := r.next.RemovePost(ctx, userId, postId, etags)
+	metrics.ObserveQuery("post", "RemovePost", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) SetPostTags(ctx context.Context, postId int64, tags []string) error {
+	ctx, span := tracing.Start(ctx, "PostRepository.SetPostTags")
+	start := time.Now()
+	err := r.next.SetPostTags(ctx, postId, tags)
+	metrics.ObserveQuery("post", "SetPostTags", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *postRepository) GetTags(ctx context.Context, userId int64) ([]posts.Tag, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.GetTags")
+	start := time.Now()
+	result, err := r.next.GetTags(ctx, userId)
+	metrics.ObserveQuery("post", "GetTags", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) CountPostsByDay(ctx context.Context, userId int64, from time.Time, to time.Time) ([]posts.DayCount, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.CountPostsByDay")
+	start := time.Now()
+	result, err := r.next.CountPostsByDay(ctx, userId, from, to)
+	metrics.ObserveQuery("post", "CountPostsByDay", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *postRepository) GetPostsOnThisDay(ctx context.Context, userId int64, day time.Time) ([]posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostRepository.GetPostsOnThisDay")
+	start := time.Now()
+	result, err := r.next.GetPostsOnThisDay(ctx, userId, day)
+	metrics.ObserveQuery("post", "GetPostsOnThisDay", start, err)
+	tracing.End(span, err)
+	return result, err
+}
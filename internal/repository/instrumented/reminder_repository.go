@@ -0,0 +1,49 @@
+// internal/repository/instrumented/reminder_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/reminders"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// reminderRepository wraps every call to the underlying repository in a
+// span and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type reminderRepository struct {
+	next repository.ReminderRepository
+}
+
+func NewReminderRepository(next repository.ReminderRepository) repository.ReminderRepository {
+	return &reminderRepository{next: next}
+}
+
+func (r *reminderRepository) CreateReminder(ctx context.Context, reminder reminders.Reminder) (reminders.Reminder, error) {
+	ctx, span := tracing.Start(ctx, "ReminderRepository.CreateReminder")
+	start := time.Now()
+	result, err := r.next.CreateReminder(ctx, reminder)
+	metrics.ObserveQuery("reminder", "CreateReminder", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *reminderRepository) GetReminders(ctx context.Context, accountId int64) ([]reminders.Reminder, error) {
+	ctx, span := tracing.Start(ctx, "ReminderRepository.GetReminders")
+	start := time.Now()
+	result, err := r.next.GetReminders(ctx, accountId)
+	metrics.ObserveQuery("reminder", "GetReminders", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *reminderRepository) DeleteReminder(ctx context.Context, accountId int64, reminderId int64) error {
+	ctx, span := tracing.Start(ctx, "ReminderRepository.DeleteReminder")
+	start := time.Now()
+	err := r.next.DeleteReminder(ctx, accountId, reminderId)
+	metrics.ObserveQuery("reminder", "DeleteReminder", start, err)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,77 @@
+// internal/repository/instrumented/stats_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/stats"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// statsRepository wraps every call to the underlying repository in a span
+// and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type statsRepository struct {
+	next repository.StatsRepository
+}
+
+func NewStatsRepository(next repository.StatsRepository) repository.StatsRepository {
+	return &statsRepository{next: next}
+}
+
+func (r *statsRepository) FillWordCounts(ctx context.Context, accountId int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.FillWordCounts")
+	start := time.Now()
+	result, err := r.next.FillWordCounts(ctx, accountId)
+	metrics.ObserveQuery("stats", "FillWordCounts", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *statsRepository) GetDays(ctx context.Context, accountId int64) ([]stats.Day, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.GetDays")
+	start := time.Now()
+	result, err := r.next.GetDays(ctx, accountId)
+	metrics.ObserveQuery("stats", "GetDays", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *statsRepository) GetMonths(ctx context.Context, accountId int64) ([]stats.Month, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.GetMonths")
+	start := time.Now()
+	result, err := r.next.GetMonths(ctx, accountId)
+	metrics.ObserveQuery("stats", "GetMonths", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *statsRepository) CountPostsByWeekday(ctx context.Context, accountId int64) ([7]int64, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.CountPostsByWeekday")
+	start := time.Now()
+	result, err := r.next.CountPostsByWeekday(ctx, accountId)
+	metrics.ObserveQuery("stats", "CountPostsByWeekday", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *statsRepository) CountPostsByHour(ctx context.Context, accountId int64) ([24]int64, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.CountPostsByHour")
+	start := time.Now()
+	result, err := r.next.CountPostsByHour(ctx, accountId)
+	metrics.ObserveQuery("stats", "CountPostsByHour", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *statsRepository) GetTopTags(ctx context.Context, accountId int64, limit int) ([]posts.Tag, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.GetTopTags")
+	start := time.Now()
+	result, err := r.next.GetTopTags(ctx, accountId, limit)
+	metrics.ObserveQuery("stats", "GetTopTags", start, err)
+	tracing.End(span, err)
+	return result, err
+}
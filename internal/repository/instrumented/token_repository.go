@@ -0,0 +1,67 @@
+// internal/repository/instrumented/token_repository.go
+package instrumented
+
+import (
+	"context"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tokens"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+// tokenRepository wraps every call to the underlying repository in a span
+// and records its duration in the journal_db_query_duration_seconds
+// histogram.
+type tokenRepository struct {
+	next repository.TokenRepository
+}
+
+func NewTokenRepository(next repository.TokenRepository) repository.TokenRepository {
+	return &tokenRepository{next: next}
+}
+
+func (r *tokenRepository) CreateToken(ctx context.Context, token tokens.PersonalAccessToken) (tokens.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.CreateToken")
+	start := time.Now()
+	result, err := r.next.CreateToken(ctx, token)
+	metrics.ObserveQuery("token", "CreateToken", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *tokenRepository) GetTokens(ctx context.Context, accountId int64) ([]tokens.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.GetTokens")
+	start := time.Now()
+	result, err := r.next.GetTokens(ctx, accountId)
+	metrics.ObserveQuery("token", "GetTokens", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *tokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (tokens.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.GetTokenByHash")
+	start := time.Now()
+	result, err := r.next.GetTokenByHash(ctx, tokenHash)
+	metrics.ObserveQuery("token", "GetTokenByHash", start, err)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *tokenRepository) RevokeToken(ctx context.Context, accountId int64, tokenId int64) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.RevokeToken")
+	start := time.Now()
+	err := r.next.RevokeToken(ctx, accountId, tokenId)
+	metrics.ObserveQuery("token", "RevokeToken", start, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *tokenRepository) UpdateTokenLastUsed(ctx context.Context, tokenId int64, lastUsedAt string) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.UpdateTokenLastUsed")
+	start := time.Now()
+	err := r.next.UpdateTokenLastUsed(ctx, tokenId, lastUsedAt)
+	metrics.ObserveQuery("token", "UpdateTokenLastUsed", start, err)
+	tracing.End(span, err)
+	return err
+}
@@ -2,13 +2,46 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"journal-lite/internal/posts"
+	"time"
 )
 
+// ErrPostModified means the post no longer has the version an edit was
+// made against.
+var ErrPostModified = errors.New("post was modified")
+
 type PostRepository interface {
 	CreatePost(ctx context.Context, post posts.Post) (posts.Post, error)
+	// CreatePosts creates all posts in one transaction, keeping their
+	// timestamps. Either every post is created or none is.
+	CreatePosts(ctx context.Context, postsList []posts.Post) ([]posts.Post, error)
 	DeletePost(ctx context.Context, postId int64) error
 	GetPosts(ctx context.Context, params posts.QueryParams) ([]posts.Post, error)
+	// CountPosts counts the posts GetPosts would return without paging.
+	CountPosts(ctx context.Context, params posts.QueryParams) (int64, error)
+	// ForEachPost calls fn for every post of the account, oldest first,
+	// without loading them all into memory. It stops at the first error.
+	ForEachPost(ctx context.Context, userId int64, fn func(posts.Post) error) error
 	GetPost(ctx context.Context, userId int64, postId int64) (posts.Post, error)
 	UpdatePost(ctx context.Context, newContent string, postId int64) error
+	// EditPost replaces the content, the tags or both of the account's post
+	// in one transaction, leaving nil ones as they are. Unless etags is
+	// empty, the post must still have one of them as its ETag, or nothing is
+	// changed and ErrPostModified is returned with the current post.
+	EditPost(ctx context.Context, userId int64, postId int64, etags []string, content *string, tags *[]string) (posts.Post, error)
+	// RemovePost deletes the account's post in one transaction. Unless etags
+	// is empty, the post must still have one of them as its ETag, or nothing
+	// is deleted and ErrPostModified is returned with the current post.
+	RemovePost(ctx context.Context, userId int64, postId int64, etags []string) (posts.Post, error)
+	SetPostTags(ctx context.Context, postId int64, tags []string) error
+	GetTags(ctx context.Context, userId int64) ([]posts.Tag, error)
+	// CountPostsByDay counts the posts written on each day from from up to
+	// but not including to, both midnights in the location that decides
+	// where days start and end. Days without posts are left out.
+	CountPostsByDay(ctx context.Context, userId int64, from time.Time, to time.Time) ([]posts.DayCount, error)
+	// GetPostsOnThisDay returns the posts written on the month and day of day
+	// in earlier years, newest first. Days start and end at midnight in
+	// day's location, whatever time zone each post was written in.
+	GetPostsOnThisDay(ctx context.Context, userId int64, day time.Time) ([]posts.Post, error)
 }
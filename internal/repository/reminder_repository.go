@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"journal-lite/internal/reminders"
+)
+
+type ReminderRepository interface {
+	CreateReminder(ctx context.Context, reminder reminders.Reminder) (reminders.Reminder, error)
+	GetReminders(ctx context.Context, accountId int64) ([]reminders.Reminder, error)
+	DeleteReminder(ctx context.Context, accountId int64, reminderId int64) error
+}
@@ -33,17 +33,17 @@ func (r *AccountRepository) CreateAccount(ctx context.Context, account accounts.
 
 	account.PasswordHash = hashedPassword
 
-	_, err = r.db.ExecContext(ctx,
+	result, err := r.db.ExecContext(ctx,
 		"INSERT INTO accounts (username, password_hash, created_at) VALUES (?, ?, ?)",
 		account.Username,
 		account.PasswordHash,
-		time.Now(),
+		time.Now().Format(time.RFC3339),
 	)
 	if err != nil {
 		return 0, err
 	}
 
-	return 1, nil
+	return result.LastInsertId()
 }
 
 func (r *AccountRepository) DeleteAccountById(ctx context.Context, accountId int64) error {
@@ -51,6 +51,107 @@ func (r *AccountRepository) DeleteAccountById(ctx context.Context, accountId int
 	return err
 }
 
+func (r *AccountRepository) GetAccountById(ctx context.Context, accountId int64) (accounts.Account, error) {
+	var account accounts.Account
+	err := r.db.QueryRowContext(ctx, "SELECT id, username, password_hash, created_at, disabled_at, time_zone FROM accounts WHERE id = ?", accountId).
+		Scan(&account.Id, &account.Username, &account.PasswordHash, &account.CreatedAt, &account.DisabledAt, &account.TimeZone)
+	return account, err
+}
+
+func (r *AccountRepository) GetAccountByUsername(ctx context.Context, username string) (accounts.Account, error) {
+	var account accounts.Account
+	err := r.db.QueryRowContext(ctx, "SELECT id, username, password_hash, created_at, disabled_at, time_zone FROM accounts WHERE username = ?", username).
+		Scan(&account.Id, &account.Username, &account.PasswordHash, &account.CreatedAt, &account.DisabledAt, &account.TimeZone)
+	return account, err
+}
+
+func (r *AccountRepository) GetAccountByIdentity(ctx context.Context, issuer string, subject string) (accounts.Account, error) {
+	var account accounts.Account
+	err := r.db.QueryRowContext(ctx, `
+		SELECT a.id, a.username, a.password_hash, a.created_at, a.disabled_at, a.time_zone FROM accounts a
+		JOIN account_identities i ON i.account_id = a.id
+		WHERE i.issuer = ? AND i.subject = ?`, issuer, subject).
+		Scan(&account.Id, &account.Username, &account.PasswordHash, &account.CreatedAt, &account.DisabledAt, &account.TimeZone)
+	return account, err
+}
+
+func (r *AccountRepository) ListAccounts(ctx context.Context) ([]accounts.Account, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, username, password_hash, created_at, disabled_at, time_zone FROM accounts ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []accounts.Account
+	for rows.Next() {
+		var account accounts.Account
+		if err := rows.Scan(&account.Id, &account.Username, &account.PasswordHash, &account.CreatedAt, &account.DisabledAt, &account.TimeZone); err != nil {
+			return nil, err
+		}
+		list = append(list, account)
+	}
+	return list, rows.Err()
+}
+
+func (r *AccountRepository) SetAccountDisabledAt(ctx context.Context, accountId int64, disabledAt string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE accounts SET disabled_at = ? WHERE id = ?", disabledAt, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AccountRepository) SetAccountTimeZone(ctx context.Context, accountId int64, timeZone string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE accounts SET time_zone = ? WHERE id = ?", timeZone, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AccountRepository) UpdatePasswordHash(ctx context.Context, accountId int64, passwordHash string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE accounts SET password_hash = ? WHERE id = ?", passwordHash, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AccountRepository) CreateIdentity(ctx context.Context, identity accounts.Identity) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO account_identities (account_id, issuer, subject, created_at) VALUES (?, ?, ?, ?)",
+		identity.AccountId,
+		identity.Issuer,
+		identity.Subject,
+		time.Now().Format(time.RFC3339),
+	)
+	return err
+}
+
 func (r *AccountRepository) RetrieveCountOfAccountsWithUsername(ctx context.Context, username string) (int, error) {
 	var count int
 	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM accounts WHERE username = ?", username).Scan(&count)
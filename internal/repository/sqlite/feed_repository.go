@@ -0,0 +1,79 @@
+// internal/repository/sqlite/feed_repository.go
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/repository"
+)
+
+const feedColumns = `id, account_id, name, token_hash, created_at, last_fetched_at`
+
+type FeedRepository struct {
+	db *sql.DB
+}
+
+func NewFeedRepository(db *sql.DB) repository.FeedRepository {
+	return &FeedRepository{db: db}
+}
+
+func (r *FeedRepository) CreateFeed(ctx context.Context, feed feeds.Feed) (feeds.Feed, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO feeds (account_id, name, token_hash, created_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		feed.AccountId,
+		feed.Name,
+		feed.TokenHash,
+		feed.CreatedAt,
+	).Scan(&feed.Id)
+	return feed, err
+}
+
+func (r *FeedRepository) GetFeeds(ctx context.Context, accountId int64) ([]feeds.Feed, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+feedColumns+` FROM feeds WHERE account_id = ? ORDER BY created_at DESC, id DESC`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feedList []feeds.Feed
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feedList = append(feedList, feed)
+	}
+	return feedList, rows.Err()
+}
+
+func (r *FeedRepository) GetFeedByHash(ctx context.Context, tokenHash string) (feeds.Feed, error) {
+	return scanFeed(r.db.QueryRowContext(ctx, `SELECT `+feedColumns+` FROM feeds WHERE token_hash = ?`, tokenHash))
+}
+
+func (r *FeedRepository) DeleteFeed(ctx context.Context, accountId int64, feedId int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM feeds WHERE id = ? AND account_id = ?", feedId, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *FeedRepository) UpdateFeedLastFetched(ctx context.Context, feedId int64, lastFetchedAt string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE feeds SET last_fetched_at = ? WHERE id = ?", lastFetchedAt, feedId)
+	return err
+}
+
+func scanFeed(row rowScanner) (feeds.Feed, error) {
+	var feed feeds.Feed
+	err := row.Scan(&feed.Id, &feed.AccountId, &feed.Name, &feed.TokenHash, &feed.CreatedAt, &feed.LastFetchedAt)
+	return feed, err
+}
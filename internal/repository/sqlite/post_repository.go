@@ -6,9 +6,15 @@ import (
 	"database/sql"
 	"journal-lite/internal/posts"
 	"journal-lite/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const postColumns = `p.id, p.content, p.created_at, p.updated_at, p.account_id,
+	COALESCE((SELECT GROUP_CONCAT(t.tag, ',') FROM post_tags t WHERE t.post_id = p.id), '')`
+
 type PostRepository struct {
 	db *sql.DB
 }
@@ -18,9 +24,37 @@ func NewPostRepository(db *sql.DB) repository.PostRepository {
 }
 
 func (r *PostRepository) CreatePost(ctx context.Context, post posts.Post) (posts.Post, error) {
-	query := `INSERT INTO posts (content, created_at, updated_at, account_id) VALUES (?, ?, ?, ?) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, post.Content, post.CreatedAt, post.UpdatedAt, post.AccountId).Scan(&post.Id)
-	return post, err
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return post, err
+	}
+	defer tx.Rollback()
+
+	post, err = insertPost(ctx, tx, post)
+	if err != nil {
+		return post, err
+	}
+
+	return post, tx.Commit()
+}
+
+func (r *PostRepository) CreatePosts(ctx context.Context, postsList []posts.Post) ([]posts.Post, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]posts.Post, 0, len(postsList))
+	for _, post := range postsList {
+		post, err := insertPost(ctx, tx, post)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, post)
+	}
+
+	return created, tx.Commit()
 }
 
 func (r *PostRepository) DeletePost(ctx context.Context, postId int64) error {
@@ -29,41 +63,296 @@ func (r *PostRepository) DeletePost(ctx context.Context, postId int64) error {
 }
 
 func (r *PostRepository) GetPosts(ctx context.Context, params posts.QueryParams) ([]posts.Post, error) {
+	where, args, err := postFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE ` + where
+
+	query += " ORDER BY p.created_at DESC, p.id DESC"
+
+	if params.PageNumber > 0 && params.PageSize > 0 {
+		offset := (params.PageNumber - 1) * params.PageSize
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, params.PageSize, offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postsList []posts.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		postsList = append(postsList, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return postsList, nil
+}
+
+func (r *PostRepository) CountPosts(ctx context.Context, params posts.QueryParams) (int64, error) {
+	where, args, err := postFilter(params)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM posts p WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+// postFilter returns the WHERE clause selecting the posts params asks for,
+// ignoring paging.
+func postFilter(params posts.QueryParams) (string, []interface{}, error) {
 	args := []interface{}{params.AccountId}
-	query := `SELECT id, content, created_at, updated_at, account_id FROM posts WHERE account_id = ?`
+	where := "p.account_id = ?"
 
 	if params.SearchText != "" {
-		query += " AND content LIKE ?"
+		where += " AND p.content LIKE ?"
 		args = append(args, "%"+params.SearchText+"%")
 	}
 
+	// Days start and end at midnight in params.Location, like in
+	// GetPostsOnThisDay, whatever time zone each post was written in.
+	// DateTo includes its whole day.
+	loc := params.Location
+	if loc == nil {
+		loc = time.UTC
+	}
 	if params.DateFrom != "" {
-		dateFrom, err := time.Parse("2006-01-02", params.DateFrom)
+		dateFrom, err := time.ParseInLocation(time.DateOnly, params.DateFrom, loc)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
-		query += " AND created_at >= ?"
-		args = append(args, dateFrom)
+		where += " AND datetime(p.created_at) >= ?"
+		args = append(args, dateFrom.UTC().Format(time.DateTime))
 	}
 
 	if params.DateTo != "" {
-		dateTo, err := time.Parse("2006-01-02", params.DateTo)
+		dateTo, err := time.ParseInLocation(time.DateOnly, params.DateTo, loc)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND datetime(p.created_at) < ?"
+		args = append(args, dateTo.AddDate(0, 0, 1).UTC().Format(time.DateTime))
+	}
+
+	// Tags are matched as they are stored, so that ?tag=#Work finds work.
+	if tags := posts.NormalizeTags([]string{params.Tag}); len(tags) > 0 {
+		where += " AND EXISTS (SELECT 1 FROM post_tags t WHERE t.post_id = p.id AND t.tag = ?)"
+		args = append(args, tags[0])
+	}
+
+	return where, args, nil
+}
+
+func (r *PostRepository) ForEachPost(ctx context.Context, userId int64, fn func(posts.Post) error) error {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.account_id = ? ORDER BY p.created_at, p.id`, userId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PostRepository) GetPost(ctx context.Context, userId int64, postId int64) (posts.Post, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.id = ? AND p.account_id = ?`, postId, userId)
+	return scanPost(row)
+}
+
+func (r *PostRepository) UpdatePost(ctx context.Context, newContent string, postId int64) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE posts SET content = ?, word_count = ?, updated_at = ? WHERE id = ?",
+		newContent, posts.CountWords(newContent), time.Now().Format(time.RFC3339), postId)
+	return err
+}
+
+func (r *PostRepository) EditPost(ctx context.Context, userId int64, postId int64, etags []string, content *string, tags *[]string) (posts.Post, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return posts.Post{}, err
+	}
+	defer tx.Rollback()
+
+	post, err := scanPost(tx.QueryRowContext(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.id = ? AND p.account_id = ?`, postId, userId))
+	if err != nil {
+		return post, err
+	}
+	if len(etags) > 0 && !slices.Contains(etags, post.ETag()) {
+		return post, repository.ErrPostModified
+	}
+
+	updatedAt := time.Now().Format(time.RFC3339)
+	if content != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET content = ?, word_count = ?, updated_at = ? WHERE id = ?",
+			*content, posts.CountWords(*content), updatedAt, postId); err != nil {
+			return post, err
+		}
+	}
+	if tags != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = ?", postId); err != nil {
+			return post, err
+		}
+		if err := insertTags(ctx, tx, postId, posts.NormalizeTags(*tags)); err != nil {
+			return post, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET updated_at = ? WHERE id = ?", updatedAt, postId); err != nil {
+			return post, err
+		}
+	}
+
+	post, err = scanPost(tx.QueryRowContext(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.id = ?`, postId))
+	if err != nil {
+		return post, err
+	}
+	return post, tx.Commit()
+}
+
+func (r *PostRepository) RemovePost(ctx context.Context, userId int64, postId int64, etags []string) (posts.Post, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return posts.Post{}, err
+	}
+	defer tx.Rollback()
+
+	post, err := scanPost(tx.QueryRowContext(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.id = ? AND p.account_id = ?`, postId, userId))
+	if err != nil {
+		return post, err
+	}
+	if len(etags) > 0 && !slices.Contains(etags, post.ETag()) {
+		return post, repository.ErrPostModified
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", postId); err != nil {
+		return post, err
+	}
+	return post, tx.Commit()
+}
+
+func (r *PostRepository) SetPostTags(ctx context.Context, postId int64, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = ?", postId); err != nil {
+		return err
+	}
+	if err := insertTags(ctx, tx, postId, posts.NormalizeTags(tags)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE posts SET updated_at = ? WHERE id = ?", time.Now().Format(time.RFC3339), postId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostRepository) GetTags(ctx context.Context, userId int64) ([]posts.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.tag, COUNT(*) FROM post_tags t
+		JOIN posts p ON p.id = t.post_id
+		WHERE p.account_id = ?
+		GROUP BY t.tag
+		ORDER BY COUNT(*) DESC, t.tag`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []posts.Tag
+	for rows.Next() {
+		var tag posts.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *PostRepository) CountPostsByDay(ctx context.Context, userId int64, from time.Time, to time.Time) ([]posts.DayCount, error) {
+	// SQLite knows no time zones but UTC, so the posts are sorted into the
+	// days of from's location here.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.created_at FROM posts p
+		WHERE p.account_id = ? AND datetime(p.created_at) >= ? AND datetime(p.created_at) < ?
+		ORDER BY datetime(p.created_at)`,
+		userId, from.UTC().Format(time.DateTime), to.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []posts.DayCount
+	for rows.Next() {
+		var createdAt string
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, err
+		}
+		created, err := time.Parse(time.RFC3339, createdAt)
 		if err != nil {
 			return nil, err
 		}
-		query += " AND created_at <= ?"
-		args = append(args, dateTo)
+		day := created.In(from.Location()).Format(time.DateOnly)
+		if n := len(counts); n > 0 && counts[n-1].Date == day {
+			counts[n-1].PostCount++
+			continue
+		}
+		counts = append(counts, posts.DayCount{Date: day, PostCount: 1})
 	}
+	return counts, rows.Err()
+}
 
-	query += " ORDER BY created_at DESC"
+// onThisDayYears is how far back GetPostsOnThisDay looks at most.
+const onThisDayYears = 150
 
-	if params.PageNumber > 0 && params.PageSize > 0 {
-		offset := (params.PageNumber - 1) * params.PageSize
-		query += " LIMIT ? OFFSET ?"
-		args = append(args, params.PageSize, offset)
+func (r *PostRepository) GetPostsOnThisDay(ctx context.Context, userId int64, day time.Time) ([]posts.Post, error) {
+	// Only the years since the first post need a range. datetime() turns
+	// created_at into UTC, whatever offset it was written with.
+	var first sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT MIN(datetime(created_at)) FROM posts WHERE account_id = ?`, userId).Scan(&first)
+	if err != nil || !first.Valid {
+		return nil, err
+	}
+	firstYear, err := strconv.Atoi(first.String[:4])
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	ranges := onThisDayRanges(day, max(firstYear-1, day.Year()-onThisDayYears))
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	args := []interface{}{userId}
+	conditions := make([]string, len(ranges))
+	for i, span := range ranges {
+		conditions[i] = "(datetime(p.created_at) >= ? AND datetime(p.created_at) < ?)"
+		args = append(args, span[0].UTC().Format(time.DateTime), span[1].UTC().Format(time.DateTime))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts p
+		WHERE p.account_id = ? AND (`+strings.Join(conditions, " OR ")+`)
+		ORDER BY datetime(p.created_at) DESC, p.id DESC`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -71,28 +360,76 @@ func (r *PostRepository) GetPosts(ctx context.Context, params posts.QueryParams)
 
 	var postsList []posts.Post
 	for rows.Next() {
-		var post posts.Post
-		if err := rows.Scan(&post.Id, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.AccountId); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
 		postsList = append(postsList, post)
 	}
+	return postsList, rows.Err()
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+// onThisDayRanges returns, for every year from since to the year before
+// day's, the start and end of the same day in day's location. February 29
+// only matches leap years. On February 28 of other years, the 29th of leap
+// years is included too, so that those entries still come up once a year.
+func onThisDayRanges(day time.Time, since int) [][2]time.Time {
+	loc := day.Location()
+	month, dayOfMonth := day.Month(), day.Day()
+	leapCatchUp := month == time.February && dayOfMonth == 28 &&
+		time.Date(day.Year(), time.February, 29, 0, 0, 0, 0, loc).Month() != time.February
+
+	var ranges [][2]time.Time
+	for year := day.Year() - 1; year >= since; year-- {
+		start := time.Date(year, month, dayOfMonth, 0, 0, 0, 0, loc)
+		if start.Month() != month {
+			// February 29 in a year without one.
+			continue
+		}
+		end := time.Date(year, month, dayOfMonth+1, 0, 0, 0, 0, loc)
+		if leapCatchUp {
+			end = time.Date(year, time.March, 1, 0, 0, 0, 0, loc)
+		}
+		ranges = append(ranges, [2]time.Time{start, end})
 	}
+	return ranges
+}
 
-	return postsList, nil
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (r *PostRepository) GetPost(ctx context.Context, userId int64, postId int64) (posts.Post, error) {
+func scanPost(row rowScanner) (posts.Post, error) {
 	var post posts.Post
-	err := r.db.QueryRowContext(ctx, "SELECT id, content, created_at, updated_at, account_id FROM posts WHERE id = ? AND account_id = ?", postId, userId).
-		Scan(&post.Id, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.AccountId)
+	var tags string
+	err := row.Scan(&post.Id, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.AccountId, &tags)
+	post.Tags = splitTags(tags)
 	return post, err
 }
 
-func (r *PostRepository) UpdatePost(ctx context.Context, newContent string, postId int64) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE posts SET content = ?, updated_at = ? WHERE id = ?", newContent, time.Now(), postId)
-	return err
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return posts.NormalizeTags(strings.Split(tags, ","))
+}
+
+func insertPost(ctx context.Context, tx *sql.Tx, post posts.Post) (posts.Post, error) {
+	query := `INSERT INTO posts (content, word_count, created_at, updated_at, account_id) VALUES (?, ?, ?, ?, ?) RETURNING id`
+	err := tx.QueryRowContext(ctx, query, post.Content, posts.CountWords(post.Content), post.CreatedAt, post.UpdatedAt, post.AccountId).Scan(&post.Id)
+	if err != nil {
+		return post, err
+	}
+
+	post.Tags = posts.NormalizeTags(post.Tags)
+	return post, insertTags(ctx, tx, post.Id, post.Tags)
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, postId int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO post_tags (post_id, tag) VALUES (?, ?)", postId, tag); err != nil {
+			return err
+		}
+	}
+	return nil
 }
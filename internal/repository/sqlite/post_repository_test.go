@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/database"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "journal.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestDaysAreTheAccountsDays checks that the calendar counts, the date
+// filter and On This Day put posts written in other time zones on the same
+// day of the account's time zone.
+func TestDaysAreTheAccountsDays(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	accountId, err := NewAccountRepository(db).CreateAccount(ctx, accounts.Account{Username: "days", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewPostRepository(db)
+	for _, createdAt := range []string{
+		"2023-03-10T23:30:00Z",      // March 11, 12:30 at +13
+		"2023-03-11T09:00:00-05:00", // March 12, 03:00 at +13
+		"2023-03-11T10:00:00+13:00", // March 11, 10:00 at +13
+	} {
+		if _, err := repo.CreatePost(ctx, posts.Post{Content: createdAt, CreatedAt: createdAt, UpdatedAt: createdAt, AccountId: accountId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loc := time.FixedZone("+13", 13*60*60)
+
+	counts, err := repo.CountPostsByDay(ctx, accountId, time.Date(2023, 3, 1, 0, 0, 0, 0, loc), time.Date(2023, 4, 1, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []posts.DayCount{{Date: "2023-03-11", PostCount: 2}, {Date: "2023-03-12", PostCount: 1}}; !slices.Equal(counts, want) {
+		t.Errorf("CountPostsByDay = %v, want %v", counts, want)
+	}
+
+	contents := func(postsList []posts.Post) []string {
+		var contents []string
+		for _, post := range postsList {
+			contents = append(contents, post.Content)
+		}
+		slices.Sort(contents)
+		return contents
+	}
+	for _, count := range counts {
+		onDay, err := repo.GetPosts(ctx, posts.QueryParams{AccountId: accountId, DateFrom: count.Date, DateTo: count.Date, Location: loc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(onDay)) != count.PostCount {
+			t.Errorf("GetPosts on %s found %d posts, CountPostsByDay %d", count.Date, len(onDay), count.PostCount)
+		}
+
+		day, _ := time.ParseInLocation(time.DateOnly, count.Date, loc)
+		memories, err := repo.GetPostsOnThisDay(ctx, accountId, day.AddDate(1, 0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(contents(memories), contents(onDay)) {
+			t.Errorf("a year after %s, On This Day found %q, GetPosts %q", count.Date, contents(memories), contents(onDay))
+		}
+	}
+}
+
+func TestEditPostChecksTheVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	accountId, err := NewAccountRepository(db).CreateAccount(ctx, accounts.Account{Username: "edits", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewPostRepository(db)
+	now := time.Now().Format(time.RFC3339)
+	post, err := repo.CreatePost(ctx, posts.Post{Content: "Draft", CreatedAt: now, UpdatedAt: now, AccountId: accountId, Tags: []string{"work"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err = repo.GetPost(ctx, accountId, post.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, tags := "Final", []string{"#Home", "Work"}
+	current, err := repo.EditPost(ctx, accountId, post.Id, []string{`"stale"`}, &content, &tags)
+	if !errors.Is(err, repository.ErrPostModified) {
+		t.Fatalf("EditPost with a stale ETag: got %v, want ErrPostModified", err)
+	}
+	if current.ETag() != post.ETag() {
+		t.Errorf("EditPost with a stale ETag returned %+v, want the unchanged %+v", current, post)
+	}
+
+	edited, err := repo.EditPost(ctx, accountId, post.Id, []string{`"stale"`, post.ETag()}, &content, &tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Content != "Final" || !slices.Equal(edited.Tags, []string{"home", "work"}) {
+		t.Errorf("EditPost = %+v, want the new content and normalized tags", edited)
+	}
+	stored, err := repo.GetPost(ctx, accountId, post.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ETag() != edited.ETag() {
+		t.Errorf("stored post %+v differs from the edited %+v", stored, edited)
+	}
+
+	if _, err := repo.EditPost(ctx, accountId+1, post.Id, nil, &content, nil); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("EditPost of another account's post: got %v, want sql.ErrNoRows", err)
+	}
+
+	found, err := repo.GetPosts(ctx, posts.QueryParams{AccountId: accountId, Tag: " #Home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Errorf("GetPosts with tag #Home found %d posts, want 1", len(found))
+	}
+}
+
+func TestRemovePostChecksTheVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	accountId, err := NewAccountRepository(db).CreateAccount(ctx, accounts.Account{Username: "removals", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewPostRepository(db)
+	now := time.Now().Format(time.RFC3339)
+	post, err := repo.CreatePost(ctx, posts.Post{Content: "Draft", CreatedAt: now, UpdatedAt: now, AccountId: accountId, Tags: []string{"work"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err = repo.GetPost(ctx, accountId, post.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := repo.RemovePost(ctx, accountId, post.Id, []string{`"stale"`})
+	if !errors.Is(err, repository.ErrPostModified) {
+		t.Fatalf("RemovePost with a stale ETag: got %v, want ErrPostModified", err)
+	}
+	if current.ETag() != post.ETag() {
+		t.Errorf("RemovePost with a stale ETag returned %+v, want the current %+v", current, post)
+	}
+	if _, err := repo.RemovePost(ctx, accountId+1, post.Id, nil); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RemovePost of another account's post: got %v, want sql.ErrNoRows", err)
+	}
+	if _, err := repo.GetPost(ctx, accountId, post.Id); err != nil {
+		t.Fatalf("post is gone after refused removals: %v", err)
+	}
+
+	if _, err := repo.RemovePost(ctx, accountId, post.Id, []string{`"stale"`, post.ETag()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetPost(ctx, accountId, post.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetPost after RemovePost: got %v, want sql.ErrNoRows", err)
+	}
+	if tags, err := repo.GetTags(ctx, accountId); err != nil || len(tags) != 0 {
+		t.Errorf("GetTags after RemovePost = %v, %v, want no tags", tags, err)
+	}
+}
@@ -0,0 +1,68 @@
+// internal/repository/sqlite/reminder_repository.go
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"journal-lite/internal/reminders"
+	"journal-lite/internal/repository"
+	"strings"
+)
+
+type ReminderRepository struct {
+	db *sql.DB
+}
+
+func NewReminderRepository(db *sql.DB) repository.ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+func (r *ReminderRepository) CreateReminder(ctx context.Context, reminder reminders.Reminder) (reminders.Reminder, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO reminders (account_id, title, time_of_day, weekdays, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		reminder.AccountId,
+		reminder.Title,
+		reminder.Time,
+		strings.Join(reminder.Weekdays, ","),
+		reminder.CreatedAt,
+	).Scan(&reminder.Id)
+	return reminder, err
+}
+
+func (r *ReminderRepository) GetReminders(ctx context.Context, accountId int64) ([]reminders.Reminder, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, account_id, title, time_of_day, weekdays, created_at FROM reminders WHERE account_id = ? ORDER BY time_of_day, id`,
+		accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminderList []reminders.Reminder
+	for rows.Next() {
+		var reminder reminders.Reminder
+		var weekdays string
+		if err := rows.Scan(&reminder.Id, &reminder.AccountId, &reminder.Title, &reminder.Time, &weekdays, &reminder.CreatedAt); err != nil {
+			return nil, err
+		}
+		reminder.Weekdays = strings.Split(weekdays, ",")
+		reminderList = append(reminderList, reminder)
+	}
+	return reminderList, rows.Err()
+}
+
+func (r *ReminderRepository) DeleteReminder(ctx context.Context, accountId int64, reminderId int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM reminders WHERE id = ? AND account_id = ?", reminderId, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
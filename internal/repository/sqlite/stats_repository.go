@@ -0,0 +1,181 @@
+// internal/repository/sqlite/stats_repository.go
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/stats"
+)
+
+// created_at is RFC 3339 in the time zone a post was written in, so its
+// first characters are the date and time there. Passing them to strftime
+// without the offset keeps them from being turned into UTC.
+const (
+	postDay   = `substr(p.created_at, 1, 10)`
+	postMonth = `substr(p.created_at, 1, 7)`
+	postHour  = `CAST(substr(p.created_at, 12, 2) AS INTEGER)`
+	// Sunday is 0.
+	postWeekday = `CAST(strftime('%w', substr(p.created_at, 1, 10)) AS INTEGER)`
+)
+
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository(db *sql.DB) repository.StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// FillWordCounts counts the words of posts written before word counts were
+// stored. Counting needs Go's idea of white space, so it is not done in SQL.
+func (r *StatsRepository) FillWordCounts(ctx context.Context, accountId int64) (int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, COALESCE(content, '') FROM posts WHERE account_id = ? AND word_count IS NULL`, accountId)
+	if err != nil {
+		return 0, err
+	}
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		counts[id] = posts.CountWords(content)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for id, count := range counts {
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET word_count = ? WHERE id = ?", count, id); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(counts)), tx.Commit()
+}
+
+func (r *StatsRepository) GetDays(ctx context.Context, accountId int64) ([]stats.Day, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+postDay+` AS day, COUNT(*), COALESCE(SUM(p.word_count), 0) FROM posts p
+		WHERE p.account_id = ?
+		GROUP BY day
+		ORDER BY day`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []stats.Day
+	for rows.Next() {
+		var day stats.Day
+		if err := rows.Scan(&day.Date, &day.Entries, &day.Words); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+func (r *StatsRepository) GetMonths(ctx context.Context, accountId int64) ([]stats.Month, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+postMonth+` AS month, COUNT(*), COALESCE(SUM(p.word_count), 0) FROM posts p
+		WHERE p.account_id = ?
+		GROUP BY month
+		ORDER BY month`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []stats.Month
+	for rows.Next() {
+		var month stats.Month
+		if err := rows.Scan(&month.Month, &month.Entries, &month.Words); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
+
+func (r *StatsRepository) CountPostsByWeekday(ctx context.Context, accountId int64) ([7]int64, error) {
+	var counts [7]int64
+	err := r.countBy(ctx, postWeekday, accountId, func(weekday int, count int64) {
+		if weekday >= 0 && weekday < 7 {
+			counts[(weekday+6)%7] = count
+		}
+	})
+	return counts, err
+}
+
+func (r *StatsRepository) CountPostsByHour(ctx context.Context, accountId int64) ([24]int64, error) {
+	var counts [24]int64
+	err := r.countBy(ctx, postHour, accountId, func(hour int, count int64) {
+		if hour >= 0 && hour < 24 {
+			counts[hour] = count
+		}
+	})
+	return counts, err
+}
+
+// countBy counts an account's posts grouped by the integer expression.
+func (r *StatsRepository) countBy(ctx context.Context, expression string, accountId int64, fn func(key int, count int64)) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+expression+` AS key, COUNT(*) FROM posts p
+		WHERE p.account_id = ?
+		GROUP BY key`, accountId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key sql.NullInt64
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		if key.Valid {
+			fn(int(key.Int64), count)
+		}
+	}
+	return rows.Err()
+}
+
+func (r *StatsRepository) GetTopTags(ctx context.Context, accountId int64, limit int) ([]posts.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.tag, COUNT(*) FROM post_tags t
+		JOIN posts p ON p.id = t.post_id
+		WHERE p.account_id = ?
+		GROUP BY t.tag
+		ORDER BY COUNT(*) DESC, t.tag
+		LIMIT ?`, accountId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []posts.Tag
+	for rows.Next() {
+		var tag posts.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
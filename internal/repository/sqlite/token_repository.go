@@ -0,0 +1,90 @@
+// internal/repository/sqlite/token_repository.go
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tokens"
+	"strings"
+	"time"
+)
+
+const tokenColumns = `id, account_id, name, scopes, token_hash, created_at, expires_at, last_used_at, revoked_at`
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) repository.TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) CreateToken(ctx context.Context, token tokens.PersonalAccessToken) (tokens.PersonalAccessToken, error) {
+	query := `INSERT INTO personal_access_tokens (account_id, name, scopes, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query,
+		token.AccountId,
+		token.Name,
+		strings.Join(token.Scopes, " "),
+		token.TokenHash,
+		token.CreatedAt,
+		token.ExpiresAt,
+	).Scan(&token.Id)
+	return token, err
+}
+
+func (r *TokenRepository) GetTokens(ctx context.Context, accountId int64) ([]tokens.PersonalAccessToken, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+tokenColumns+` FROM personal_access_tokens WHERE account_id = ? ORDER BY created_at DESC, id DESC`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokensList []tokens.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokensList = append(tokensList, token)
+	}
+
+	return tokensList, rows.Err()
+}
+
+func (r *TokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (tokens.PersonalAccessToken, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM personal_access_tokens WHERE token_hash = ?`, tokenHash)
+	return scanToken(row)
+}
+
+func (r *TokenRepository) RevokeToken(ctx context.Context, accountId int64, tokenId int64) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE personal_access_tokens SET revoked_at = ? WHERE id = ? AND account_id = ? AND revoked_at = ''",
+		time.Now().Format(time.RFC3339), tokenId, accountId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *TokenRepository) UpdateTokenLastUsed(ctx context.Context, tokenId int64, lastUsedAt string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?", lastUsedAt, tokenId)
+	return err
+}
+
+func scanToken(row rowScanner) (tokens.PersonalAccessToken, error) {
+	var token tokens.PersonalAccessToken
+	var scopes string
+	err := row.Scan(&token.Id, &token.AccountId, &token.Name, &scopes, &token.TokenHash,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt)
+	token.Scopes = strings.Fields(scopes)
+	return token, err
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/stats"
+)
+
+// StatsRepository aggregates an account's posts. Days and hours are those of
+// the time zone each post was written in.
+type StatsRepository interface {
+	// FillWordCounts counts the words of posts that have no count yet and
+	// returns how many it counted.
+	FillWordCounts(ctx context.Context, accountId int64) (int64, error)
+	GetDays(ctx context.Context, accountId int64) ([]stats.Day, error)
+	GetMonths(ctx context.Context, accountId int64) ([]stats.Month, error)
+	// CountPostsByWeekday counts posts by the day of the week, from Monday.
+	CountPostsByWeekday(ctx context.Context, accountId int64) ([7]int64, error)
+	CountPostsByHour(ctx context.Context, accountId int64) ([24]int64, error)
+	GetTopTags(ctx context.Context, accountId int64, limit int) ([]posts.Tag, error)
+}
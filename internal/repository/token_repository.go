@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"journal-lite/internal/tokens"
+)
+
+type TokenRepository interface {
+	CreateToken(ctx context.Context, token tokens.PersonalAccessToken) (tokens.PersonalAccessToken, error)
+	GetTokens(ctx context.Context, accountId int64) ([]tokens.PersonalAccessToken, error)
+	GetTokenByHash(ctx context.Context, tokenHash string) (tokens.PersonalAccessToken, error)
+	RevokeToken(ctx context.Context, accountId int64, tokenId int64) error
+	UpdateTokenLastUsed(ctx context.Context, tokenId int64, lastUsedAt string) error
+}
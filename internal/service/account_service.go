@@ -3,8 +3,22 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"journal-lite/internal/accounts"
 	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIdentityLinked  = errors.New("identity is already linked to another account")
+	ErrAccountDisabled = errors.New("account is disabled")
+	ErrUnknownTimeZone = errors.New("unknown time zone; use an IANA name such as Europe/Berlin")
 )
 
 type AccountService struct {
@@ -15,10 +29,165 @@ func NewAccountService(repo repository.AccountRepository) *AccountService {
 	return &AccountService{repo: repo}
 }
 
-func (s *AccountService) CreateAccount(ctx context.Context, account accounts.Account) (int64, error) {
+func (s *AccountService) CreateAccount(ctx context.Context, account accounts.Account) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.CreateAccount")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.CreateAccount(ctx, account)
 }
 
-func (s *AccountService) DeleteAccountById(ctx context.Context, accountId int64) error {
+func (s *AccountService) DeleteAccountById(ctx context.Context, accountId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.DeleteAccountById")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.DeleteAccountById(ctx, accountId)
 }
+
+func (s *AccountService) GetAccountById(ctx context.Context, accountId int64) (_ accounts.Account, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.GetAccountById")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetAccountById(ctx, accountId)
+}
+
+func (s *AccountService) GetAccountByUsername(ctx context.Context, username string) (_ accounts.Account, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.GetAccountByUsername")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetAccountByUsername(ctx, username)
+}
+
+func (s *AccountService) ListAccounts(ctx context.Context) (_ []accounts.Account, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ListAccounts")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListAccounts(ctx)
+}
+
+// CheckActive returns ErrAccountDisabled for a disabled account and
+// sql.ErrNoRows for a deleted one, so that sessions and tokens issued to
+// them stop working.
+func (s *AccountService) CheckActive(ctx context.Context, accountId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.CheckActive")
+	defer func() { tracing.End(span, err) }()
+
+	account, err := s.repo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return err
+	}
+	if account.DisabledAt != "" {
+		return ErrAccountDisabled
+	}
+	return nil
+}
+
+// SetDisabled disables or re-enables an account. A disabled account keeps
+// its entries and tokens but cannot sign in or use them.
+func (s *AccountService) SetDisabled(ctx context.Context, accountId int64, disabled bool) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.SetDisabled")
+	defer func() { tracing.End(span, err) }()
+
+	disabledAt := ""
+	if disabled {
+		disabledAt = time.Now().Format(time.RFC3339)
+	}
+	return s.repo.SetAccountDisabledAt(ctx, accountId, disabledAt)
+}
+
+// SetTimeZone sets the IANA time zone the account's days follow. An empty
+// name goes back to the server's.
+func (s *AccountService) SetTimeZone(ctx context.Context, accountId int64, timeZone string) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.SetTimeZone")
+	defer func() { tracing.End(span, err) }()
+
+	timeZone = strings.TrimSpace(timeZone)
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "Local" {
+			return ErrUnknownTimeZone
+		}
+	}
+	return s.repo.SetAccountTimeZone(ctx, accountId, timeZone)
+}
+
+func (s *AccountService) ResetPassword(ctx context.Context, accountId int64, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	hash, err := accounts.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePasswordHash(ctx, accountId, hash)
+}
+
+// ProvisionOIDCAccount returns the account linked to issuer and subject,
+// creating and linking a new account on first login. The new account gets
+// an unusable random password so it can only sign in through the provider.
+func (s *AccountService) ProvisionOIDCAccount(ctx context.Context, issuer string, subject string, preferredUsername string) (_ accounts.Account, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ProvisionOIDCAccount")
+	defer func() { tracing.End(span, err) }()
+
+	account, err := s.repo.GetAccountByIdentity(ctx, issuer, subject)
+	if err == nil {
+		return account, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return account, err
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return account, err
+	}
+
+	base := strings.TrimSpace(preferredUsername)
+	if base == "" {
+		base = "user"
+	}
+
+	var id int64
+	for attempt := 1; id == 0; attempt++ {
+		username := base
+		if attempt > 1 {
+			username = base + "-" + strconv.Itoa(attempt)
+		}
+		id, err = s.repo.CreateAccount(ctx, accounts.Account{
+			Username:     username,
+			PasswordHash: hex.EncodeToString(password),
+		})
+		if err != nil {
+			return account, err
+		}
+		if id == 0 && attempt >= 100 {
+			return account, errors.New("could not find a free username")
+		}
+	}
+
+	if err := s.LinkIdentity(ctx, id, issuer, subject); err != nil {
+		return account, err
+	}
+	return s.repo.GetAccountById(ctx, id)
+}
+
+// LinkIdentity links an existing account to issuer and subject.
+func (s *AccountService) LinkIdentity(ctx context.Context, accountId int64, issuer string, subject string) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.LinkIdentity")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.repo.GetAccountByIdentity(ctx, issuer, subject)
+	if err == nil {
+		if existing.Id == accountId {
+			return nil
+		}
+		return ErrIdentityLinked
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return s.repo.CreateIdentity(ctx, accounts.Identity{
+		AccountId: accountId,
+		Issuer:    issuer,
+		Subject:   subject,
+	})
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"io"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/export"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+type ExportService struct {
+	repo repository.PostRepository
+}
+
+func NewExportService(repo repository.PostRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// WriteArchive streams a ZIP export of every post of the account to w.
+func (s *ExportService) WriteArchive(ctx context.Context, w io.Writer, account accounts.Account) (err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.WriteArchive")
+	defer func() { tracing.End(span, err) }()
+
+	archive, err := export.NewArchive(w, export.Meta{Username: account.Username, ExportedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ForEachPost(ctx, account.Id, archive.Add); err != nil {
+		archive.Discard()
+		return err
+	}
+	return archive.Close()
+}
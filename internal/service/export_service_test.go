@@ -0,0 +1,195 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/database"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository/sqlite"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "journal.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTestAccount(t *testing.T, db *sql.DB, username string) accounts.Account {
+	t.Helper()
+	repo := sqlite.NewAccountRepository(db)
+	id, err := repo.CreateAccount(context.Background(), accounts.Account{Username: username, PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := repo.GetAccountById(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewPostRepository(db)
+	writer := createTestAccount(t, db, "writer")
+	other := createTestAccount(t, db, "other")
+
+	var created []posts.Post
+	for _, post := range []posts.Post{
+		{Content: "Last entry of the year.\nAnd a <b>second</b> line & more.", CreatedAt: "2023-12-31T22:00:00+01:00", Tags: []string{"key: value", `say "hi"`, "plain"}},
+		{Content: "New year.", CreatedAt: "2024-01-01T09:00:00+01:00"},
+	} {
+		post.AccountId, post.UpdatedAt = writer.Id, post.CreatedAt
+		post, err := repo.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post, err = repo.GetPost(ctx, writer.Id, post.Id); err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, post)
+	}
+	if _, err := repo.CreatePost(ctx, posts.Post{Content: "Someone else's secret.", CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z", AccountId: other.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := NewExportService(repo).WriteArchive(ctx, &out, writer); err != nil {
+		t.Fatal(err)
+	}
+	files := readZip(t, out.Bytes())
+
+	first, second := strconv.FormatInt(created[0].Id, 10), strconv.FormatInt(created[1].Id, 10)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{
+		"journal.json",
+		"markdown/2023/2023-12-31-" + first + ".md",
+		"markdown/2024/2024-01-01-" + second + ".md",
+		"site/entries/" + first + ".html",
+		"site/entries/" + second + ".html",
+		"site/index.html",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("archive holds %q, want %q", names, want)
+	}
+	for name, content := range files {
+		if strings.Contains(content, "Someone else's secret") {
+			t.Errorf("%s holds another account's post", name)
+		}
+	}
+
+	t.Run("front matter", func(t *testing.T) {
+		markdown := files["markdown/2023/2023-12-31-"+first+".md"]
+		frontMatter, body, ok := strings.Cut(strings.TrimPrefix(markdown, "---\n"), "\n---\n\n")
+		if !ok {
+			t.Fatalf("no front matter in:\n%s", markdown)
+		}
+		var meta struct {
+			Id      int64     `yaml:"id"`
+			Created time.Time `yaml:"created"`
+			Updated time.Time `yaml:"updated"`
+			Tags    []string  `yaml:"tags"`
+		}
+		if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+			t.Fatalf("front matter is not valid YAML: %v\n%s", err, frontMatter)
+		}
+		wantCreated, _ := time.Parse(time.RFC3339, created[0].CreatedAt)
+		if meta.Id != created[0].Id || !meta.Created.Equal(wantCreated) || !meta.Updated.Equal(wantCreated) {
+			t.Errorf("front matter = %+v, want the post's id and timestamps", meta)
+		}
+		if !slices.Equal(meta.Tags, created[0].Tags) {
+			t.Errorf("front matter tags = %q, want %q", meta.Tags, created[0].Tags)
+		}
+		if body != created[0].Content+"\n" {
+			t.Errorf("body = %q, want the post's content", body)
+		}
+	})
+
+	t.Run("JSON dump", func(t *testing.T) {
+		var dump struct {
+			Format     string       `json:"format"`
+			Version    int          `json:"version"`
+			Username   string       `json:"username"`
+			ExportedAt string       `json:"exported_at"`
+			Count      int          `json:"count"`
+			Posts      []posts.Post `json:"posts"`
+		}
+		if err := json.Unmarshal([]byte(files["journal.json"]), &dump); err != nil {
+			t.Fatalf("journal.json is not valid JSON: %v\n%s", err, files["journal.json"])
+		}
+		if dump.Format != "journal-lite-export" || dump.Version != 1 || dump.Username != "writer" || dump.Count != 2 {
+			t.Errorf("header = %+v", dump)
+		}
+		if _, err := time.Parse(time.RFC3339, dump.ExportedAt); err != nil {
+			t.Errorf("exported_at %q: %v", dump.ExportedAt, err)
+		}
+		if len(dump.Posts) != len(created) {
+			t.Fatalf("dump holds %d posts, want %d", len(dump.Posts), len(created))
+		}
+		for i, post := range dump.Posts {
+			if post.Id != created[i].Id || post.Content != created[i].Content || post.AccountId != writer.Id || !slices.Equal(post.Tags, created[i].Tags) {
+				t.Errorf("post %d = %+v, want %+v", i, post, created[i])
+			}
+		}
+	})
+
+	t.Run("site", func(t *testing.T) {
+		page := files["site/entries/"+first+".html"]
+		if !strings.Contains(page, "&lt;b&gt;second&lt;/b&gt; line &amp; more") {
+			t.Errorf("entry page does not hold the escaped content:\n%s", page)
+		}
+		index := files["site/index.html"]
+		for _, id := range []string{first, second} {
+			if !strings.Contains(index, `href="entries/`+id+`.html"`) {
+				t.Errorf("index does not link entry %s", id)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tokens"
+	"journal-lite/internal/tracing"
+	"strings"
+	"time"
+)
+
+type FeedService struct {
+	feeds    repository.FeedRepository
+	posts    repository.PostRepository
+	accounts repository.AccountRepository
+}
+
+func NewFeedService(feedRepo repository.FeedRepository, postRepo repository.PostRepository, accountRepo repository.AccountRepository) *FeedService {
+	return &FeedService{feeds: feedRepo, posts: postRepo, accounts: accountRepo}
+}
+
+// CreateFeed creates a feed of the account's journal. The plaintext token
+// is returned once and never stored.
+func (s *FeedService) CreateFeed(ctx context.Context, accountId int64, name string) (_ string, _ feeds.Feed, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.CreateFeed")
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", feeds.Feed{}, errors.New("feed name is required")
+	}
+
+	plaintext, err := feeds.GenerateToken()
+	if err != nil {
+		return "", feeds.Feed{}, err
+	}
+
+	feed, err := s.feeds.CreateFeed(ctx, feeds.Feed{
+		AccountId: accountId,
+		Name:      name,
+		TokenHash: tokens.Hash(plaintext),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", feeds.Feed{}, err
+	}
+	return plaintext, feed, nil
+}
+
+func (s *FeedService) GetFeeds(ctx context.Context, accountId int64) (_ []feeds.Feed, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.GetFeeds")
+	defer func() { tracing.End(span, err) }()
+
+	return s.feeds.GetFeeds(ctx, accountId)
+}
+
+// DeleteFeed revokes a feed. Readers using it get 404 from then on.
+func (s *FeedService) DeleteFeed(ctx context.Context, accountId int64, feedId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.DeleteFeed")
+	defer func() { tracing.End(span, err) }()
+
+	return s.feeds.DeleteFeed(ctx, accountId, feedId)
+}
+
+// Authenticate resolves a plaintext feed token to its feed and account, and
+// records when the feed was last fetched. Tokens of deleted feeds and of
+// disabled accounts are rejected with ErrInvalidToken.
+func (s *FeedService) Authenticate(ctx context.Context, plaintext string) (_ feeds.Feed, _ accounts.Account, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(plaintext, feeds.TokenPrefix) {
+		return feeds.Feed{}, accounts.Account{}, ErrInvalidToken
+	}
+	feed, err := s.feeds.GetFeedByHash(ctx, tokens.Hash(plaintext))
+	if errors.Is(err, sql.ErrNoRows) {
+		return feed, accounts.Account{}, ErrInvalidToken
+	}
+	if err != nil {
+		return feed, accounts.Account{}, err
+	}
+	account, err := s.accounts.GetAccountById(ctx, feed.AccountId)
+	if err != nil {
+		return feed, account, err
+	}
+	if account.DisabledAt != "" {
+		return feed, account, ErrInvalidToken
+	}
+
+	feed.LastFetchedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := s.feeds.UpdateFeedLastFetched(ctx, feed.Id, feed.LastFetchedAt); err != nil {
+		return feed, account, err
+	}
+	return feed, account, nil
+}
+
+// Page returns one page of the account's posts, newest first, and how many
+// pages there are. An empty journal has one empty page.
+func (s *FeedService) Page(ctx context.Context, accountId int64, page int64) (_ []posts.Post, pages int64, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.Page")
+	defer func() { tracing.End(span, err) }()
+
+	params := posts.QueryParams{AccountId: accountId}
+	count, err := s.posts.CountPosts(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	pages = max(1, (count+feeds.PageSize-1)/feeds.PageSize)
+	if page > pages {
+		return nil, pages, nil
+	}
+
+	params.PageNumber, params.PageSize = page, feeds.PageSize
+	postList, err := s.posts.GetPosts(ctx, params)
+	return postList, pages, err
+}
+
+// AllPosts returns every post of the account, oldest first. Calendars
+// cannot page, so the calendar feed holds the whole journal.
+func (s *FeedService) AllPosts(ctx context.Context, accountId int64) (_ []posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.AllPosts")
+	defer func() { tracing.End(span, err) }()
+
+	var postList []posts.Post
+	err = s.posts.ForEachPost(ctx, accountId, func(post posts.Post) error {
+		postList = append(postList, post)
+		return nil
+	})
+	return postList, err
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"journal-lite/internal/importer"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"strings"
+	"time"
+)
+
+type ImportService struct {
+	repo repository.PostRepository
+}
+
+func NewImportService(repo repository.PostRepository) *ImportService {
+	return &ImportService{repo: repo}
+}
+
+// Import reads the entries of src with the parser registered for format and
+// adds them to the account, keeping their original timestamps. Entries whose
+// content matches an existing post, or an earlier entry of the same import,
+// are reported as duplicates and skipped. All new entries are created in one
+// transaction. With dryRun nothing is written and the report shows what an
+// import would do.
+func (s *ImportService) Import(ctx context.Context, accountId int64, format string, src importer.Source, dryRun bool) (_ importer.Report, err error) {
+	ctx, span := tracing.Start(ctx, "ImportService.Import")
+	defer func() { tracing.End(span, err) }()
+
+	report := importer.Report{Format: format, DryRun: dryRun, Entries: []importer.EntryResult{}}
+	parser, ok := importer.Lookup(format)
+	if !ok {
+		return report, fmt.Errorf("unknown import format %q, expected one of %s", format, strings.Join(importer.Formats(), ", "))
+	}
+
+	existing := map[string]int64{}
+	if err := s.repo.ForEachPost(ctx, accountId, func(post posts.Post) error {
+		existing[posts.ContentHash(post.Content)] = post.Id
+		return nil
+	}); err != nil {
+		return report, err
+	}
+
+	var pending []posts.Post
+	var pendingResults []int
+	seen := map[string]string{}
+	err = importer.Read(src, parser, func(entry importer.Entry) {
+		result := importer.EntryResult{Source: entry.Source, Excerpt: posts.Excerpt(entry.Content, 80)}
+		if !entry.CreatedAt.IsZero() {
+			result.CreatedAt = entry.CreatedAt.Format(time.RFC3339)
+		}
+		hash := posts.ContentHash(entry.Content)
+		switch {
+		case strings.TrimSpace(entry.Content) == "":
+			result.Status, result.Reason = importer.StatusInvalid, "The entry is empty."
+		case entry.CreatedAt.IsZero():
+			result.Status, result.Reason = importer.StatusInvalid, "The entry has no creation time."
+		case existing[hash] != 0:
+			result.Status, result.PostId = importer.StatusDuplicate, existing[hash]
+			result.Reason = "The journal already has a post with this content."
+		case seen[hash] != "":
+			result.Status, result.Reason = importer.StatusDuplicate, "Same content as "+seen[hash]+"."
+		default:
+			seen[hash] = entry.Source
+			result.Status = importer.StatusNew
+			updated := entry.UpdatedAt
+			if updated.Before(entry.CreatedAt) {
+				updated = entry.CreatedAt
+			}
+			pending = append(pending, posts.Post{
+				Content:   entry.Content,
+				CreatedAt: result.CreatedAt,
+				UpdatedAt: updated.Format(time.RFC3339),
+				AccountId: accountId,
+				Tags:      entry.Tags,
+			})
+			pendingResults = append(pendingResults, len(report.Entries))
+		}
+		report.Add(result)
+	}, func(name string, err error) {
+		report.Add(importer.EntryResult{Source: name, Status: importer.StatusInvalid, Reason: err.Error()})
+	})
+	if err != nil {
+		return report, err
+	}
+	if dryRun || len(pending) == 0 {
+		return report, nil
+	}
+
+	created, err := s.repo.CreatePosts(ctx, pending)
+	if err != nil {
+		return report, err
+	}
+	for i, post := range created {
+		result := &report.Entries[pendingResults[i]]
+		result.Status, result.PostId = importer.StatusImported, post.Id
+	}
+	return report, nil
+}
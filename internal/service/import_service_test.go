@@ -0,0 +1,135 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"journal-lite/internal/importer"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository/sqlite"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// zipSource is an uploaded ZIP file holding files.
+func zipSource(t *testing.T, files map[string]string) importer.Source {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return importer.ReaderSource("export.zip", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewPostRepository(db)
+	account := createTestAccount(t, db, "importer")
+	other := createTestAccount(t, db, "other")
+	existing, err := repo.CreatePost(ctx, posts.Post{Content: "Already in the journal.", CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z", AccountId: account.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreatePost(ctx, posts.Post{Content: "Written by someone else.", CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z", AccountId: other.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := zipSource(t, map[string]string{
+		"2024-01-01-old.md":   "Already in the journal.\n",
+		"2024-01-02-new.md":   "---\ncreated: 2024-01-02T08:00:00+01:00\nupdated: 2024-01-03T09:00:00+01:00\ntags: [Home, work]\n---\nA new entry.\n",
+		"2024-01-03-again.md": "A new entry.",
+		"2024-01-04-other.md": "Written by someone else.",
+		"2024-01-05-empty.md": "---\ntags: [empty]\n---\n",
+		"broken.md":           "---\ntags: [unclosed\n---\nNot YAML.",
+	})
+	service := NewImportService(repo)
+	statuses := func(report importer.Report) map[string]string {
+		got := map[string]string{}
+		for _, e := range report.Entries {
+			got[e.Source] = e.Status
+		}
+		return got
+	}
+	countPosts := func() int64 {
+		n, err := repo.CountPosts(ctx, posts.QueryParams{AccountId: account.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	report, err := service.Import(ctx, account.Id, "markdown", src, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"2024-01-01-old.md":   importer.StatusDuplicate,
+		"2024-01-02-new.md":   importer.StatusNew,
+		"2024-01-03-again.md": importer.StatusDuplicate,
+		"2024-01-04-other.md": importer.StatusNew,
+		"2024-01-05-empty.md": importer.StatusInvalid,
+		"broken.md":           importer.StatusInvalid,
+	}
+	if got := statuses(report); !maps.Equal(got, want) || !report.DryRun || report.Total != 6 || report.Imported != 2 || report.Duplicates != 2 || report.Invalid != 2 {
+		t.Errorf("dry run report = %+v\nstatuses %v, want %v", report, got, want)
+	}
+	for _, e := range report.Entries {
+		if e.Source == "2024-01-01-old.md" && e.PostId != existing.Id {
+			t.Errorf("duplicate of an existing post names post %d, want %d", e.PostId, existing.Id)
+		}
+	}
+	if n := countPosts(); n != 1 {
+		t.Fatalf("dry run left %d posts, want 1", n)
+	}
+
+	report, err = service.Import(ctx, account.Id, "markdown", src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want["2024-01-02-new.md"], want["2024-01-04-other.md"] = importer.StatusImported, importer.StatusImported
+	if got := statuses(report); !maps.Equal(got, want) || report.DryRun || report.Imported != 2 || report.Duplicates != 2 || report.Invalid != 2 {
+		t.Errorf("import report = %+v\nstatuses %v, want %v", report, got, want)
+	}
+	if n := countPosts(); n != 3 {
+		t.Errorf("import left %d posts, want 3", n)
+	}
+	for _, e := range report.Entries {
+		if e.Source != "2024-01-02-new.md" {
+			continue
+		}
+		post, err := repo.GetPost(ctx, account.Id, e.PostId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Content != "A new entry." || post.CreatedAt != "2024-01-02T08:00:00+01:00" || post.UpdatedAt != "2024-01-03T09:00:00+01:00" || !slices.Equal(post.Tags, []string{"home", "work"}) {
+			t.Errorf("imported post = %+v, want the entry's content, timestamps and tags", post)
+		}
+	}
+
+	report, err = service.Import(ctx, account.Id, "markdown", src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 0 || report.Duplicates != 4 || report.Invalid != 2 {
+		t.Errorf("re-import report = %+v, want every readable entry reported as a duplicate", report)
+	}
+	if n := countPosts(); n != 3 {
+		t.Errorf("re-import left %d posts, want 3", n)
+	}
+
+	if _, err := service.Import(ctx, account.Id, "evernote", src, true); err == nil {
+		t.Error("Import of an unknown format succeeded")
+	}
+}
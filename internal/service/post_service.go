@@ -4,6 +4,8 @@ import (
 	"context"
 	"journal-lite/internal/posts"
 	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
 )
 
 type PostService struct {
@@ -14,22 +16,100 @@ func NewPostService(repo repository.PostRepository) *PostService {
 	return &PostService{repo: repo}
 }
 
-func (s *PostService) CreatePost(ctx context.Context, post posts.Post) (posts.Post, error) {
+func (s *PostService) CreatePost(ctx context.Context, post posts.Post) (_ posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CreatePost")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.CreatePost(ctx, post)
 }
 
-func (s *PostService) DeletePost(ctx context.Context, postId int64) error {
+func (s *PostService) DeletePost(ctx context.Context, postId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.DeletePost")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.DeletePost(ctx, postId)
 }
 
-func (s *PostService) GetPosts(ctx context.Context, params posts.QueryParams) ([]posts.Post, error) {
+func (s *PostService) GetPosts(ctx context.Context, params posts.QueryParams) (_ []posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPosts")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.GetPosts(ctx, params)
 }
 
-func (s *PostService) GetPost(ctx context.Context, userId int64, postId int64) (posts.Post, error) {
+func (s *PostService) GetPost(ctx context.Context, userId int64, postId int64) (_ posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPost")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.GetPost(ctx, userId, postId)
 }
 
-func (s *PostService) UpdatePost(ctx context.Context, newContent string, postId int64) error {
+func (s *PostService) UpdatePost(ctx context.Context, newContent string, postId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.UpdatePost")
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.UpdatePost(ctx, newContent, postId)
 }
+
+// EditPost changes the content, the tags or both of the post in one
+// transaction, if it still has one of etags. See PostRepository.EditPost.
+func (s *PostService) EditPost(ctx context.Context, userId int64, postId int64, etags []string, content *string, tags *[]string) (_ posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.EditPost")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.EditPost(ctx, userId, postId, etags, content, tags)
+}
+
+// RemovePost deletes the post in one transaction, if it still has one of
+// etags. See PostRepository.RemovePost.
+func (s *PostService) RemovePost(ctx context.Context, userId int64, postId int64, etags []string) (_ posts.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.RemovePost")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.RemovePost(ctx, userId, postId, etags)
+}
+
+func (s *PostService) GetTags(ctx context.Context, userId int64) (_ []posts.Tag, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetTags")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetTags(ctx, userId)
+}
+
+func (s *PostService) CountPostsByDay(ctx context.Context, userId int64, from time.Time, to time.Time) (_ []posts.DayCount, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CountPostsByDay")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.CountPostsByDay(ctx, userId, from, to)
+}
+
+// OnThisDay returns what was written on the month and day of day in earlier
+// years, grouped by year, most recent first. day's location decides where
+// days start and end.
+func (s *PostService) OnThisDay(ctx context.Context, userId int64, day time.Time) (_ []posts.Memory, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.OnThisDay")
+	defer func() { tracing.End(span, err) }()
+
+	postList, err := s.repo.GetPostsOnThisDay(ctx, userId, day)
+	if err != nil {
+		return nil, err
+	}
+	var memories []posts.Memory
+	for _, post := range postList {
+		created, err := time.Parse(time.RFC3339, post.CreatedAt)
+		if err != nil {
+			continue
+		}
+		created = created.In(day.Location())
+		if n := len(memories); n > 0 && memories[n-1].YearsAgo == day.Year()-created.Year() {
+			memories[n-1].Posts = append(memories[n-1].Posts, post)
+			continue
+		}
+		memories = append(memories, posts.Memory{
+			YearsAgo: day.Year() - created.Year(),
+			Date:     created.Format(time.DateOnly),
+			Posts:    []posts.Post{post},
+		})
+	}
+	return memories, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"journal-lite/internal/reminders"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tracing"
+	"time"
+)
+
+type ReminderService struct {
+	reminders repository.ReminderRepository
+}
+
+func NewReminderService(reminderRepo repository.ReminderRepository) *ReminderService {
+	return &ReminderService{reminders: reminderRepo}
+}
+
+// CreateReminder validates and stores a reminder. Validation errors are
+// meant to be shown to the user.
+func (s *ReminderService) CreateReminder(ctx context.Context, accountId int64, reminder reminders.Reminder) (_ reminders.Reminder, err error) {
+	ctx, span := tracing.Start(ctx, "ReminderService.CreateReminder")
+	defer func() { tracing.End(span, err) }()
+
+	if err := reminder.Normalize(); err != nil {
+		return reminders.Reminder{}, err
+	}
+	reminder.AccountId = accountId
+	reminder.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	return s.reminders.CreateReminder(ctx, reminder)
+}
+
+func (s *ReminderService) GetReminders(ctx context.Context, accountId int64) (_ []reminders.Reminder, err error) {
+	ctx, span := tracing.Start(ctx, "ReminderService.GetReminders")
+	defer func() { tracing.End(span, err) }()
+
+	return s.reminders.GetReminders(ctx, accountId)
+}
+
+func (s *ReminderService) DeleteReminder(ctx context.Context, accountId int64, reminderId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "ReminderService.DeleteReminder")
+	defer func() { tracing.End(span, err) }()
+
+	return s.reminders.DeleteReminder(ctx, accountId, reminderId)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/stats"
+	"journal-lite/internal/tracing"
+	"sync"
+	"time"
+)
+
+// StatsService summarises accounts' journals. Summaries are cached until a
+// post is written, which PostRepository reports when wrapped with
+// WatchPosts.
+type StatsService struct {
+	repo repository.StatsRepository
+
+	mu    sync.Mutex
+	cache map[int64]stats.Stats
+	// generation counts writes, so that a summary computed while a post was
+	// being written is not cached.
+	generation uint64
+}
+
+func NewStatsService(repo repository.StatsRepository) *StatsService {
+	return &StatsService{repo: repo, cache: make(map[int64]stats.Stats)}
+}
+
+// GetStats returns the summary of the account's journal on today, whose
+// location is the account's time zone.
+func (s *StatsService) GetStats(ctx context.Context, accountId int64, today time.Time) (_ stats.Stats, err error) {
+	ctx, span := tracing.Start(ctx, "StatsService.GetStats")
+	defer func() { tracing.End(span, err) }()
+
+	date := today.Format(time.DateOnly)
+	s.mu.Lock()
+	cached, ok := s.cache[accountId]
+	generation := s.generation
+	s.mu.Unlock()
+	// The streak and the recent days move on at midnight even without writes.
+	if ok && cached.Today == date {
+		return cached, nil
+	}
+
+	summary, err := s.compute(ctx, accountId, today)
+	if err != nil {
+		return summary, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.cache[accountId] = summary
+	}
+	s.mu.Unlock()
+	return summary, nil
+}
+
+func (s *StatsService) compute(ctx context.Context, accountId int64, today time.Time) (stats.Stats, error) {
+	summary := stats.Stats{Today: today.Format(time.DateOnly)}
+	if _, err := s.repo.FillWordCounts(ctx, accountId); err != nil {
+		return summary, err
+	}
+
+	days, err := s.repo.GetDays(ctx, accountId)
+	if err != nil {
+		return summary, err
+	}
+	dates := make([]string, len(days))
+	for i, day := range days {
+		dates[i] = day.Date
+		summary.Entries += day.Entries
+		summary.Words += day.Words
+	}
+	summary.ActiveDays = len(days)
+	summary.CurrentStreak, summary.LongestStreak = stats.Streaks(dates, today)
+	summary.Days = stats.LastDays(days, today, stats.RecentDays)
+
+	months, err := s.repo.GetMonths(ctx, accountId)
+	if err != nil {
+		return summary, err
+	}
+	summary.Months = stats.LastMonths(months, today, stats.RecentMonths)
+
+	if summary.Weekdays, err = s.repo.CountPostsByWeekday(ctx, accountId); err != nil {
+		return summary, err
+	}
+	if summary.Hours, err = s.repo.CountPostsByHour(ctx, accountId); err != nil {
+		return summary, err
+	}
+	if summary.TopTags, err = s.repo.GetTopTags(ctx, accountId, stats.TopTags); err != nil {
+		return summary, err
+	}
+	if summary.TopTags == nil {
+		summary.TopTags = []posts.Tag{}
+	}
+	return summary, nil
+}
+
+// Invalidate drops every cached summary. Writes are rare next to the reads
+// they would have to be matched with, and some only know the post, so all
+// accounts are dropped rather than the one written to.
+func (s *StatsService) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	clear(s.cache)
+}
+
+// WatchPosts returns repo, invalidating the cached summaries after every
+// write through it.
+func (s *StatsService) WatchPosts(repo repository.PostRepository) repository.PostRepository {
+	return &watchedPostRepository{PostRepository: repo, stats: s}
+}
+
+type watchedPostRepository struct {
+	repository.PostRepository
+	stats *StatsService
+}
+
+func (r *watchedPostRepository) CreatePost(ctx context.Context, post posts.Post) (posts.Post, error) {
+	defer r.stats.Invalidate()
+	return r.PostRepository.CreatePost(ctx, post)
+}
+
+func (r *watchedPostRepository) CreatePosts(ctx context.Context, postsList []posts.Post) ([]posts.Post, error) {
+	defer r.stats.Invalidate()
+	return r.PostRepository.CreatePosts(ctx, postsList)
+}
+
+func (r *watchedPostRepository) UpdatePost(ctx context.Context, newContent string, postId int64) error {
+	defer r.stats.Invalidate()
+	return r.PostRepository.UpdatePost(ctx, newContent, postId)
+}
+
+func (r *watchedPostRepository) EditPost(ctx context.Context, userId int64, postId int64, etags []string, content *string, tags *[]string) (posts.Post, error) {
+	defer r.stats.Invalidate()
+	return r.PostRepository.EditPost(ctx, userId, postId, etags, content, tags)
+}
+
+func (r *watchedPostRepository) RemovePost(ctx context.Context, userId int64, postId int64, etags []string) (posts.Post, error) {
+	defer r.stats.Invalidate()
+	return r.PostRepository.RemovePost(ctx, userId, postId, etags)
+}
+
+func (r *watchedPostRepository) SetPostTags(ctx context.Context, postId int64, tags []string) error {
+	defer r.stats.Invalidate()
+	return r.PostRepository.SetPostTags(ctx, postId, tags)
+}
+
+func (r *watchedPostRepository) DeletePost(ctx context.Context, postId int64) error {
+	defer r.stats.Invalidate()
+	return r.PostRepository.DeletePost(ctx, postId)
+}
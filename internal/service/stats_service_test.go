@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/repository/sqlite"
+	"testing"
+	"time"
+)
+
+// countingStatsRepository counts the summaries computed from it and can run
+// a function in the middle of one.
+type countingStatsRepository struct {
+	repository.StatsRepository
+	computed int
+	during   func()
+}
+
+func (r *countingStatsRepository) FillWordCounts(ctx context.Context, accountId int64) (int64, error) {
+	r.computed++
+	if r.during != nil {
+		r.during()
+	}
+	return r.StatsRepository.FillWordCounts(ctx, accountId)
+}
+
+func TestStatsCache(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	account := createTestAccount(t, db, "stats")
+	statsRepo := &countingStatsRepository{StatsRepository: sqlite.NewStatsRepository(db)}
+	service := NewStatsService(statsRepo)
+	unwatched := sqlite.NewPostRepository(db)
+	watched := service.WatchPosts(unwatched)
+	today := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	getStats := func(today time.Time) (int64, int64, []posts.Tag) {
+		t.Helper()
+		summary, err := service.GetStats(ctx, account.Id, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary.Entries, summary.Words, summary.TopTags
+	}
+	newPost := func(content string) posts.Post {
+		return posts.Post{Content: content, CreatedAt: "2024-03-01T08:00:00Z", UpdatedAt: "2024-03-01T08:00:00Z", AccountId: account.Id}
+	}
+
+	if entries, _, _ := getStats(today); entries != 0 {
+		t.Fatalf("new account has %d entries", entries)
+	}
+
+	// Writes that bypass the watched repository leave the summary cached.
+	unseen, err := unwatched.CreatePost(ctx, newPost("unseen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _, _ := getStats(today); entries != 0 || statsRepo.computed != 1 {
+		t.Fatalf("summary was computed %d times, want it cached", statsRepo.computed)
+	}
+	// The next day it is computed again.
+	if entries, _, _ := getStats(today.AddDate(0, 0, 1)); entries != 1 {
+		t.Errorf("next day's summary has %d entries, want 1", entries)
+	}
+
+	// Every write through the watched repository drops the cached summary.
+	getStats(today)
+	var post posts.Post
+	for _, write := range []struct {
+		name    string
+		write   func() error
+		entries int64
+		words   int64
+		tags    int
+	}{
+		{"CreatePost", func() error {
+			var err error
+			post, err = watched.CreatePost(ctx, newPost("one two three"))
+			return err
+		}, 2, 4, 0},
+		{"CreatePosts", func() error {
+			_, err := watched.CreatePosts(ctx, []posts.Post{newPost("four"), newPost("five six")})
+			return err
+		}, 4, 7, 0},
+		{"UpdatePost", func() error { return watched.UpdatePost(ctx, "one two", post.Id) }, 4, 6, 0},
+		{"EditPost", func() error {
+			content := "one"
+			_, err := watched.EditPost(ctx, account.Id, post.Id, nil, &content, nil)
+			return err
+		}, 4, 5, 0},
+		{"SetPostTags", func() error { return watched.SetPostTags(ctx, post.Id, []string{"a", "b"}) }, 4, 5, 2},
+		{"RemovePost", func() error {
+			_, err := watched.RemovePost(ctx, account.Id, post.Id, nil)
+			return err
+		}, 3, 4, 0},
+		{"DeletePost", func() error { return watched.DeletePost(ctx, unseen.Id) }, 2, 3, 0},
+	} {
+		if err := write.write(); err != nil {
+			t.Fatalf("%s: %v", write.name, err)
+		}
+		entries, words, tags := getStats(today)
+		if entries != write.entries || words != write.words || len(tags) != write.tags {
+			t.Errorf("after %s: %d entries, %d words, tags %v; want %d, %d, %d tags", write.name, entries, words, tags, write.entries, write.words, write.tags)
+		}
+	}
+
+	// A summary computed while a post is written may miss it, so it is not
+	// kept.
+	statsRepo.during = func() {
+		statsRepo.during = nil
+		if _, err := watched.CreatePost(ctx, newPost("racing")); err != nil {
+			t.Error(err)
+		}
+	}
+	computed := statsRepo.computed
+	getStats(today.AddDate(0, 0, 2))
+	getStats(today.AddDate(0, 0, 2))
+	if statsRepo.computed != computed+2 {
+		t.Errorf("summary computed during a write was cached")
+	}
+}
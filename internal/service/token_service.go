@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/tokens"
+	"journal-lite/internal/tracing"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenRevoked = errors.New("token has been revoked")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+type TokenService struct {
+	repo repository.TokenRepository
+}
+
+func NewTokenService(repo repository.TokenRepository) *TokenService {
+	return &TokenService{repo: repo}
+}
+
+// CreateToken issues a new personal access token. The plaintext token is
+// returned once and never stored.
+func (s *TokenService) CreateToken(ctx context.Context, accountId int64, name string, scopes []string, expiresAt string) (_ string, _ tokens.PersonalAccessToken, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.CreateToken")
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", tokens.PersonalAccessToken{}, errors.New("token name is required")
+	}
+	if err := tokens.ValidateScopes(scopes); err != nil {
+		return "", tokens.PersonalAccessToken{}, err
+	}
+	if expiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return "", tokens.PersonalAccessToken{}, errors.New("expires_at must be an RFC 3339 timestamp")
+		}
+		if !expiry.After(time.Now()) {
+			return "", tokens.PersonalAccessToken{}, errors.New("expires_at must be in the future")
+		}
+		expiresAt = expiry.UTC().Format(time.RFC3339)
+	}
+
+	plaintext, err := tokens.Generate()
+	if err != nil {
+		return "", tokens.PersonalAccessToken{}, err
+	}
+
+	token, err := s.repo.CreateToken(ctx, tokens.PersonalAccessToken{
+		AccountId: accountId,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: tokens.Hash(plaintext),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", tokens.PersonalAccessToken{}, err
+	}
+
+	return plaintext, token, nil
+}
+
+func (s *TokenService) GetTokens(ctx context.Context, accountId int64) (_ []tokens.PersonalAccessToken, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.GetTokens")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetTokens(ctx, accountId)
+}
+
+func (s *TokenService) RevokeToken(ctx context.Context, accountId int64, tokenId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeToken")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.RevokeToken(ctx, accountId, tokenId)
+}
+
+// Authenticate resolves a plaintext token, rejecting revoked and expired
+// ones, and records when it was last used.
+func (s *TokenService) Authenticate(ctx context.Context, plaintext string) (_ tokens.PersonalAccessToken, err error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(plaintext, tokens.Prefix) {
+		return tokens.PersonalAccessToken{}, ErrInvalidToken
+	}
+
+	token, err := s.repo.GetTokenByHash(ctx, tokens.Hash(plaintext))
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, ErrInvalidToken
+	}
+	if err != nil {
+		return token, err
+	}
+
+	if token.RevokedAt != "" {
+		return token, ErrTokenRevoked
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339, token.ExpiresAt)
+		if err != nil || !expiry.After(now) {
+			return token, ErrTokenExpired
+		}
+	}
+
+	token.LastUsedAt = now.Format(time.RFC3339)
+	if err := s.repo.UpdateTokenLastUsed(ctx, token.Id, token.LastUsedAt); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,122 @@
+// Package stats summarises how an account writes: streaks of consecutive
+// days, words over time, and when and about what entries are written.
+package stats
+
+import (
+	"journal-lite/internal/posts"
+	"time"
+)
+
+// RecentDays and RecentMonths are how far back the words per day and per
+// month go.
+const (
+	RecentDays   = 30
+	RecentMonths = 12
+	// TopTags is how many of the most used tags are listed.
+	TopTags = 10
+)
+
+// Day is the writing done on one day, as YYYY-MM-DD in the time zone each
+// entry was written in.
+type Day struct {
+	Date    string `json:"date"`
+	Entries int64  `json:"entries"`
+	Words   int64  `json:"words"`
+}
+
+// Month is the writing done in one month, as YYYY-MM.
+type Month struct {
+	Month   string `json:"month"`
+	Entries int64  `json:"entries"`
+	Words   int64  `json:"words"`
+}
+
+// Streak is a run of consecutive days with at least one entry.
+type Streak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Stats is the summary of an account's journal on the day Today.
+type Stats struct {
+	Today      string `json:"today"`
+	Entries    int64  `json:"entries"`
+	Words      int64  `json:"words"`
+	ActiveDays int    `json:"active_days"`
+	// CurrentStreak ends today, or yesterday while today has no entry yet.
+	CurrentStreak Streak `json:"current_streak"`
+	LongestStreak Streak `json:"longest_streak"`
+	// Days and Months are the last RecentDays days and RecentMonths
+	// months, oldest first, including those without entries.
+	Days   []Day   `json:"days"`
+	Months []Month `json:"months"`
+	// Weekdays counts entries by the day of the week, from Monday, and
+	// Hours by the hour of the day they were written at.
+	Weekdays [7]int64    `json:"weekdays"`
+	Hours    [24]int64   `json:"hours"`
+	TopTags  []posts.Tag `json:"top_tags"`
+}
+
+// Streaks finds the current and the longest streak in days, the dates with
+// entries in ascending order. today is the date to count the current streak
+// back from.
+func Streaks(days []string, today time.Time) (current Streak, longest Streak) {
+	var run Streak
+	var prev time.Time
+	for _, date := range days {
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			continue
+		}
+		if run.Days > 0 && day.Equal(prev.AddDate(0, 0, 1)) {
+			run.Days++
+			run.End = date
+		} else {
+			run = Streak{Days: 1, Start: date, End: date}
+		}
+		if run.Days > longest.Days {
+			longest = run
+		}
+		prev = day
+	}
+
+	todayDate := today.Format(time.DateOnly)
+	yesterday := time.Date(today.Year(), today.Month(), today.Day()-1, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+	if run.End == todayDate || run.End == yesterday {
+		current = run
+	}
+	return current, longest
+}
+
+// LastDays returns the last n days up to today from days, oldest first,
+// with those that have no entries filled in.
+func LastDays(days []Day, today time.Time, n int) []Day {
+	byDate := make(map[string]Day, len(days))
+	for _, day := range days {
+		byDate[day.Date] = day
+	}
+	last := make([]Day, n)
+	for i := range last {
+		date := time.Date(today.Year(), today.Month(), today.Day()-(n-1-i), 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+		last[i] = byDate[date]
+		last[i].Date = date
+	}
+	return last
+}
+
+// LastMonths returns the last n months up to today's from months, oldest
+// first, with those that have no entries filled in.
+func LastMonths(months []Month, today time.Time, n int) []Month {
+	byMonth := make(map[string]Month, len(months))
+	for _, month := range months {
+		byMonth[month.Month] = month
+	}
+	last := make([]Month, n)
+	for i := range last {
+		month := time.Date(today.Year(), today.Month()-time.Month(n-1-i), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		last[i] = byMonth[month]
+		last[i].Month = month
+	}
+	return last
+}
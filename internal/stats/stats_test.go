@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreaks(t *testing.T) {
+	today := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		days    []string
+		current Streak
+		longest Streak
+	}{
+		{"no entries", nil, Streak{}, Streak{}},
+		{"today", []string{"2024-03-01"}, Streak{1, "2024-03-01", "2024-03-01"}, Streak{1, "2024-03-01", "2024-03-01"}},
+		// Today may still get an entry, so a streak up to yesterday holds.
+		{"up to yesterday", []string{"2024-02-28", "2024-02-29"}, Streak{2, "2024-02-28", "2024-02-29"}, Streak{2, "2024-02-28", "2024-02-29"}},
+		{"broken", []string{"2024-02-27", "2024-02-28"}, Streak{}, Streak{2, "2024-02-27", "2024-02-28"}},
+		{"across the year", []string{"2023-12-30", "2023-12-31", "2024-01-01", "2024-01-02", "2024-02-29", "2024-03-01"},
+			Streak{2, "2024-02-29", "2024-03-01"}, Streak{4, "2023-12-30", "2024-01-02"}},
+		// The first of two streaks as long is the longest.
+		{"ties", []string{"2024-02-01", "2024-02-02", "2024-02-10", "2024-02-11"}, Streak{}, Streak{2, "2024-02-01", "2024-02-02"}},
+		{"gap of a day", []string{"2024-02-27", "2024-02-29", "2024-03-01"}, Streak{2, "2024-02-29", "2024-03-01"}, Streak{2, "2024-02-29", "2024-03-01"}},
+		{"invalid dates are skipped", []string{"2024-02-29", "someday", "2024-03-01"}, Streak{2, "2024-02-29", "2024-03-01"}, Streak{2, "2024-02-29", "2024-03-01"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			current, longest := Streaks(tc.days, today)
+			if current != tc.current || longest != tc.longest {
+				t.Errorf("Streaks = %+v, %+v; want %+v, %+v", current, longest, tc.current, tc.longest)
+			}
+		})
+	}
+}
+
+// TestStreaksAcrossMidnight checks that the current streak follows the
+// calendar day of today's location rather than UTC.
+func TestStreaksAcrossMidnight(t *testing.T) {
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Skip(err)
+	}
+	days := []string{"2024-02-28", "2024-02-29"}
+	for _, tc := range []struct {
+		today time.Time
+		want  int
+	}{
+		{time.Date(2024, 2, 29, 23, 59, 0, 0, auckland), 2},
+		// Past midnight the streak still holds until the day is over.
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, auckland), 2},
+		{time.Date(2024, 3, 1, 23, 59, 59, 0, auckland), 2},
+		{time.Date(2024, 3, 2, 0, 0, 0, 0, auckland), 0},
+		// The same instant is still March 1 in UTC.
+		{time.Date(2024, 3, 2, 0, 0, 0, 0, auckland).UTC(), 2},
+	} {
+		if current, _ := Streaks(days, tc.today); current.Days != tc.want {
+			t.Errorf("on %s the current streak is %d days, want %d", tc.today, current.Days, tc.want)
+		}
+	}
+
+	// An entry on a day that has begun in Auckland but not yet in UTC.
+	early := time.Date(2024, 3, 1, 0, 30, 0, 0, auckland)
+	if current, _ := Streaks([]string{"2024-02-29", "2024-03-01"}, early); current.Days != 2 {
+		t.Errorf("in Auckland the current streak is %d days, want 2", current.Days)
+	}
+	if current, _ := Streaks([]string{"2024-03-01"}, early.UTC()); current.Days != 0 {
+		t.Errorf("in UTC a streak starting tomorrow is current: %d days", current.Days)
+	}
+}
+
+func TestLastDays(t *testing.T) {
+	loc := time.FixedZone("+12", 12*60*60)
+	today := time.Date(2024, 4, 8, 0, 30, 0, 0, loc)
+	days := []Day{
+		{Date: "2024-03-31", Entries: 9, Words: 900},
+		{Date: "2024-04-06", Entries: 1, Words: 10},
+		{Date: "2024-04-08", Entries: 2, Words: 20},
+	}
+	got := LastDays(days, today, 3)
+	want := []Day{{"2024-04-06", 1, 10}, {"2024-04-07", 0, 0}, {"2024-04-08", 2, 20}}
+	if len(got) != len(want) {
+		t.Fatalf("LastDays = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LastDays = %+v, want %+v", got, want)
+			break
+		}
+	}
+
+	across := LastDays(nil, time.Date(2024, 1, 1, 0, 0, 0, 0, loc), 2)
+	if across[0].Date != "2023-12-31" || across[1].Date != "2024-01-01" {
+		t.Errorf("LastDays across the year = %+v", across)
+	}
+}
+
+func TestLastMonths(t *testing.T) {
+	months := []Month{{Month: "2023-11", Entries: 3, Words: 30}, {Month: "2024-01", Entries: 1, Words: 5}}
+	// On the 31st, counting back months must not skip any that are shorter.
+	got := LastMonths(months, time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC), 5)
+	want := []Month{{"2023-11", 3, 30}, {"2023-12", 0, 0}, {"2024-01", 1, 5}, {"2024-02", 0, 0}, {"2024-03", 0, 0}}
+	if len(got) != len(want) {
+		t.Fatalf("LastMonths = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LastMonths = %+v, want %+v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks a bearer token as a personal access token rather than a
+// session JWT.
+const Prefix = "jlpat_"
+
+const (
+	ScopePostsRead    = "posts:read"
+	ScopePostsWrite   = "posts:write"
+	ScopeAccountRead  = "account:read"
+	ScopeAccountWrite = "account:write"
+)
+
+// Scopes lists every scope a personal access token can be granted.
+var Scopes = []string{ScopePostsRead, ScopePostsWrite, ScopeAccountRead, ScopeAccountWrite}
+
+type PersonalAccessToken struct {
+	Id         int64    `db:"id" json:"id"`
+	AccountId  int64    `db:"account_id" json:"-"`
+	Name       string   `db:"name" json:"name"`
+	Scopes     []string `db:"scopes" json:"scopes"`
+	TokenHash  string   `db:"token_hash" json:"-"`
+	CreatedAt  string   `db:"created_at" json:"created_at"`
+	ExpiresAt  string   `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt string   `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  string   `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope.
+func (t PersonalAccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns a new random plaintext token. Only its hash is stored.
+func Generate() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Hash returns the value stored in place of a plaintext token.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateScopes returns an error if any scope is unknown or none are given.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required (%s)", strings.Join(Scopes, ", "))
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, candidate := range Scopes {
+			if scope == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
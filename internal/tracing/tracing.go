@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"journal-lite/internal/config"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "journal-lite"
+
+// Setup installs the W3C trace context propagator and, if an OTLP endpoint
+// is configured, a tracer provider that exports to it. Without an endpoint
+// spans are not recorded, but incoming trace context is still propagated.
+// The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(cfg.Endpoint),
+		otlptracehttp.WithHeaders(parseHeaders(cfg.Headers)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewTracerProvider(sdktrace.WithBatcher(exporter), cfg.ServiceName, cfg.SampleRatio)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider builds the tracer provider used by Setup around any span
+// processor. Tests pass sdktrace.WithSyncer(tracetest.NewInMemoryExporter())
+// and install the result with otel.SetTracerProvider.
+func NewTracerProvider(processor sdktrace.TracerProviderOption, serviceName string, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+}
+
+// Start begins a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End marks span as failed if err is set and ends it. sql.ErrNoRows is an
+// expected outcome for lookups and is recorded as an event instead.
+func End(span trace.Span, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		span.AddEvent("not found")
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlWhitespace     = regexp.MustCompile(`\s+`)
+)
+
+// RedactSQL returns query with every literal replaced by a placeholder, so
+// that statements can be attached to spans without leaking journal content.
+// Bound parameters are never recorded in the first place.
+func RedactSQL(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlNumericLiteral.ReplaceAllString(query, "?")
+	return strings.TrimSpace(sqlWhitespace.ReplaceAllString(query, " "))
+}
+
+// parseHeaders reads headers in the OTEL_EXPORTER_OTLP_HEADERS format,
+// "key1=value1,key2=value2".
+func parseHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
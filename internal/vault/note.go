@@ -0,0 +1,168 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"journal-lite/internal/posts"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A daily note holds the entries of one day, oldest first. Each entry starts
+// with a marker comment, which Obsidian and Logseq hide when rendering, and
+// may end with a line of hashtags holding its tags:
+//
+//	<!-- journal-lite id=12 created=2024-01-29T08:30:00+01:00 -->
+//	Went for a run along the river.
+//
+//	#health #running
+//
+// Text before the first marker, and text after a marker without an id,
+// becomes a new entry. An entry without tags whose text ends in a line of
+// hashtags has that line escaped with a backslash, so that it stays text.
+
+var (
+	markerLine  = regexp.MustCompile(`^<!--\s*journal-lite\b(.*?)-->\s*$`)
+	markerField = regexp.MustCompile(`(\w+)=(\S+)`)
+	hashtagLine = regexp.MustCompile(`^#[^\s#]+(?:\s+#[^\s#]+)*$`)
+	// escapedLine matches a line of hashtags escaped one or more times.
+	escapedLine = regexp.MustCompile(`^\\+#[^\s#]+(?:\s+#[^\s#]+)*$`)
+)
+
+// noteEntry is one entry as written in a daily note.
+type noteEntry struct {
+	Id        int64
+	CreatedAt string
+	Content   string
+	Tags      []string
+}
+
+// hash identifies the entry as it appears in a note. Tags are compared the
+// way they would be stored.
+func (e noteEntry) hash() string {
+	return entryHash(e.Content, e.Tags)
+}
+
+func entryHash(content string, tags []string) string {
+	sum := sha256.Sum256([]byte(posts.ContentHash(content) + "\x00" + strings.Join(posts.NormalizeTags(tags), ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// postEntry is the post as it is written to a note.
+func postEntry(post posts.Post) noteEntry {
+	tags := make([]string, len(post.Tags))
+	for i, tag := range post.Tags {
+		tags[i] = hashtag(tag)
+	}
+	return noteEntry{Id: post.Id, CreatedAt: post.CreatedAt, Content: strings.TrimSpace(post.Content), Tags: tags}
+}
+
+// hashtag writes a tag so that editors recognize it. Hashtags cannot hold
+// spaces, so they become dashes.
+func hashtag(tag string) string {
+	return "#" + strings.Join(strings.Fields(tag), "-")
+}
+
+func parseNote(text string) []noteEntry {
+	text = strings.ReplaceAll(strings.TrimPrefix(text, "\ufeff"), "\r\n", "\n")
+	var entries []noteEntry
+	current := noteEntry{}
+	var lines []string
+	flush := func() {
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		current.Content, current.Tags = splitHashtags(current.Content)
+		if current.Id != 0 || current.Content != "" {
+			entries = append(entries, current)
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		m := markerLine.FindStringSubmatch(line)
+		if m == nil {
+			lines = append(lines, line)
+			continue
+		}
+		flush()
+		current, lines = noteEntry{}, nil
+		for _, field := range markerField.FindAllStringSubmatch(m[1], -1) {
+			switch field[1] {
+			case "id":
+				current.Id, _ = strconv.ParseInt(field[2], 10, 64)
+			case "created":
+				current.CreatedAt = field[2]
+			}
+		}
+	}
+	flush()
+	return entries
+}
+
+// splitHashtags removes a last line made of hashtags from content and
+// returns them. The leading # is dropped when tags are normalized. Without
+// tags, an escaped last line loses one backslash.
+func splitHashtags(content string) (string, []string) {
+	i := strings.LastIndex(content, "\n")
+	last := strings.TrimSpace(content[i+1:])
+	if escapedLine.MatchString(last) {
+		return content[:i+1] + strings.Replace(content[i+1:], "\\", "", 1), nil
+	}
+	if !hashtagLine.MatchString(last) {
+		return content, nil
+	}
+	tags := strings.Fields(last)
+	if i < 0 {
+		return "", tags
+	}
+	return strings.TrimSpace(content[:i]), tags
+}
+
+func renderNote(entries []noteEntry) string {
+	var b strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "<!-- journal-lite id=%d created=%s -->\n", e.Id, e.CreatedAt)
+		if e.Content != "" {
+			content := e.Content
+			if len(e.Tags) == 0 {
+				content = escapeHashtags(content)
+			}
+			b.WriteString(content)
+			b.WriteString("\n")
+		}
+		if len(e.Tags) > 0 {
+			if e.Content != "" {
+				b.WriteString("\n")
+			}
+			b.WriteString(strings.Join(e.Tags, " "))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// escapeHashtags adds a backslash to a last line of content that would
+// otherwise be read back as tags, or that is already escaped.
+func escapeHashtags(content string) string {
+	i := strings.LastIndex(content, "\n")
+	last := content[i+1:]
+	trimmed := strings.TrimSpace(last)
+	if !hashtagLine.MatchString(trimmed) && !escapedLine.MatchString(trimmed) {
+		return content
+	}
+	indent := last[:len(last)-len(strings.TrimLeft(last, " \t"))]
+	return content[:i+1] + indent + "\\" + last[len(indent):]
+}
+
+// entryDay returns the day an entry belongs to, in the time zone it was
+// written in.
+func entryDay(createdAt string) (string, bool) {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return "", false
+	}
+	return t.Format(time.DateOnly), true
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNoteRoundTrip(t *testing.T) {
+	created := "2024-01-29T08:30:00+01:00"
+	for name, entries := range map[string][]noteEntry{
+		"plain":                     {{Id: 1, CreatedAt: created, Content: "Went for a run."}},
+		"tags":                      {{Id: 2, CreatedAt: created, Content: "Went for a run.", Tags: []string{"#health", "#running"}}},
+		"only tags":                 {{Id: 3, CreatedAt: created, Tags: []string{"#rest"}}},
+		"empty":                     {{Id: 4, CreatedAt: created}},
+		"last line is hashtags":     {{Id: 5, CreatedAt: created, Content: "Ideas for the weekend\n\n#hike #museum"}},
+		"content is hashtags":       {{Id: 6, CreatedAt: created, Content: "#hike #museum"}},
+		"indented hashtags":         {{Id: 7, CreatedAt: created, Content: "Ideas:\n  #hike"}},
+		"escaped hashtags":          {{Id: 8, CreatedAt: created, Content: "Not a tag:\n\\#hike"}},
+		"hashtags and tags":         {{Id: 9, CreatedAt: created, Content: "Ideas\n#hike", Tags: []string{"#plans"}}},
+		"escaped hashtags and tags": {{Id: 10, CreatedAt: created, Content: "\\#hike", Tags: []string{"#plans"}}},
+		"several": {
+			{Id: 11, CreatedAt: created, Content: "Morning.", Tags: []string{"#coffee"}},
+			{Id: 12, CreatedAt: "2024-01-29T21:00:00+01:00", Content: "Evening.\n\n#tired"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			text := renderNote(entries)
+			if got := parseNote(text); !reflect.DeepEqual(got, entries) {
+				t.Errorf("parseNote(renderNote(%+v)) = %+v\nnote:\n%s", entries, got, text)
+			}
+		})
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	text := "\ufeffA thought before any marker.\r\n" +
+		"#idea\r\n" +
+		"<!-- journal-lite id=12 created=2024-01-29T08:30:00+01:00 -->\r\n" +
+		"Went for a run along the river.\r\n" +
+		"\r\n" +
+		"#health #running\r\n" +
+		"<!-- journal-lite -->\n" +
+		"Written in the vault.\n" +
+		"<!-- journal-lite id=13 -->\n"
+	want := []noteEntry{
+		{Content: "A thought before any marker.", Tags: []string{"#idea"}},
+		{Id: 12, CreatedAt: "2024-01-29T08:30:00+01:00", Content: "Went for a run along the river.", Tags: []string{"#health", "#running"}},
+		{Content: "Written in the vault."},
+		{Id: 13},
+	}
+	if got := parseNote(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNote = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,517 @@
+// Package vault keeps a journal and a folder of daily-note Markdown files,
+// such as an Obsidian or Logseq vault, in step in both directions.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConflictDir is the folder of the vault that conflicting versions are saved
+// to. It is not synced.
+const ConflictDir = "conflicts"
+
+// Syncer mirrors the journal of one account to a folder of daily notes.
+//
+// Every entry's last synced version is remembered as two content hashes, one
+// of the entry as written in the note and one of the post, together with the
+// post's updated_at. A sync compares both sides with that base: a side that
+// changed is copied to the other, and an entry that changed on both sides is
+// a conflict. Conflicts are resolved in favour of the newer side, comparing
+// the note's modification time with updated_at, and the other version is
+// saved to the conflicts folder so that no edit is lost.
+type Syncer struct {
+	repo      repository.PostRepository
+	accountId int64
+	dir       string
+	layout    string
+
+	mu     sync.Mutex
+	notes  map[string]*note
+	status Status
+}
+
+// Status describes the most recent sync.
+type Status struct {
+	Dir        string     `json:"dir"`
+	LastSyncAt *time.Time `json:"last_sync_at"`
+	LastError  string     `json:"last_error,omitempty"`
+	// Conflicts counts the conflicts since the process started.
+	Conflicts int `json:"conflicts"`
+}
+
+// Result counts what one sync changed.
+type Result struct {
+	// Created, Updated and Deleted count posts changed from the vault.
+	Created int
+	Updated int
+	Deleted int
+	// Written and Removed count daily notes changed from the journal.
+	Written   int
+	Removed   int
+	Conflicts int
+}
+
+// Changed reports whether the sync changed anything.
+func (r Result) Changed() bool {
+	return r != Result{}
+}
+
+// note is a daily note as last read from disk.
+type note struct {
+	name    string
+	day     string
+	modTime time.Time
+	size    int64
+	text    string
+	entries []noteEntry
+}
+
+// vaultEntry is an entry found in a note.
+type vaultEntry struct {
+	noteEntry
+	day     string
+	modTime time.Time
+}
+
+type syncState struct {
+	AccountId int64                `json:"account_id"`
+	Entries   map[int64]entryState `json:"entries"`
+}
+
+type entryState struct {
+	NoteHash  string `json:"note_hash"`
+	PostHash  string `json:"post_hash"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// NewSyncer syncs the journal of accountId with the daily notes in dir.
+// layout is the Go time layout of the note names, without the .md suffix.
+func NewSyncer(repo repository.PostRepository, accountId int64, dir string, layout string) *Syncer {
+	return &Syncer{
+		repo:      repo,
+		accountId: accountId,
+		dir:       dir,
+		layout:    layout,
+		notes:     map[string]*note{},
+		status:    Status{Dir: dir},
+	}
+}
+
+// Status returns the outcome of the most recent sync.
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync runs one sync in both directions.
+func (s *Syncer) Sync(ctx context.Context) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.sync(ctx)
+	now := time.Now()
+	s.status.LastSyncAt = &now
+	s.status.LastError = ""
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	s.status.Conflicts += result.Conflicts
+	return result, err
+}
+
+func (s *Syncer) sync(ctx context.Context) (Result, error) {
+	var result Result
+	// A missing folder is more likely an unmounted drive than a vault
+	// whose every note was deleted, so it is an error.
+	if info, err := os.Stat(s.dir); err != nil {
+		return result, err
+	} else if !info.IsDir() {
+		return result, fmt.Errorf("%s is not a directory", s.dir)
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return result, err
+	}
+	postsById := map[int64]posts.Post{}
+	if err := s.repo.ForEachPost(ctx, s.accountId, func(post posts.Post) error {
+		postsById[post.Id] = post
+		return nil
+	}); err != nil {
+		return result, err
+	}
+	if err := s.readNotes(); err != nil {
+		return result, err
+	}
+
+	inVault := map[int64]vaultEntry{}
+	var fresh []vaultEntry
+	for _, n := range s.notes {
+		for _, e := range n.entries {
+			v := vaultEntry{noteEntry: e, day: n.day, modTime: n.modTime}
+			if _, dup := inVault[e.Id]; e.Id == 0 || dup {
+				// A copied marker does not make the copy the same entry.
+				v.Id = 0
+				fresh = append(fresh, v)
+				continue
+			}
+			inVault[e.Id] = v
+		}
+	}
+	// With no notes at all the vault is probably new or was emptied by
+	// mistake; rewrite it instead of deleting every post.
+	vaultIntact := len(s.notes) > 0 || len(state.Entries) == 0
+
+	ids := map[int64]bool{}
+	for id := range postsById {
+		ids[id] = true
+	}
+	for id := range inVault {
+		ids[id] = true
+	}
+
+	synced := map[int64]posts.Post{}
+	for _, id := range sortedIds(ids) {
+		post, inJournal := postsById[id]
+		v, inNote := inVault[id]
+		base, known := state.Entries[id]
+		noteChanged := inNote && (!known || v.hash() != base.NoteHash)
+		postChanged := inJournal && (!known || entryHash(post.Content, post.Tags) != base.PostHash || post.UpdatedAt != base.UpdatedAt)
+
+		switch {
+		case inJournal && inNote:
+			switch {
+			case noteChanged && postChanged && v.hash() != postEntry(post).hash():
+				result.Conflicts++
+				if noteIsNewer(v.modTime, post.UpdatedAt) {
+					if err := s.saveConflict(v.day, postEntry(post), "app"); err != nil {
+						return result, err
+					}
+					if post, err = s.apply(ctx, post, v.noteEntry); err != nil {
+						return result, err
+					}
+					result.Updated++
+				} else if err := s.saveConflict(v.day, v.noteEntry, "vault"); err != nil {
+					return result, err
+				}
+			case noteChanged && !postChanged:
+				if post, err = s.apply(ctx, post, v.noteEntry); err != nil {
+					return result, err
+				}
+				result.Updated++
+			}
+			synced[id] = post
+
+		case inJournal:
+			if known && !postChanged && vaultIntact {
+				// Deleted from the vault.
+				if err := s.repo.DeletePost(ctx, id); err != nil {
+					return result, err
+				}
+				result.Deleted++
+				continue
+			}
+			synced[id] = post
+
+		case inNote:
+			if known && !noteChanged {
+				// Deleted in the app; the note is rewritten without it.
+				continue
+			}
+			// New in the vault, or edited there after the app deleted it.
+			v.Id = 0
+			fresh = append(fresh, v)
+		}
+	}
+
+	for _, v := range fresh {
+		post, err := s.create(ctx, v)
+		if err != nil {
+			return result, err
+		}
+		synced[post.Id] = post
+		result.Created++
+	}
+
+	written, removed, err := s.writeNotes(synced)
+	result.Written, result.Removed = written, removed
+	if err != nil {
+		return result, err
+	}
+
+	state.AccountId = s.accountId
+	state.Entries = map[int64]entryState{}
+	for id, post := range synced {
+		if _, ok := entryDay(post.CreatedAt); !ok {
+			continue
+		}
+		state.Entries[id] = entryState{
+			NoteHash:  postEntry(post).hash(),
+			PostHash:  entryHash(post.Content, post.Tags),
+			UpdatedAt: post.UpdatedAt,
+		}
+	}
+	return result, s.saveState(state)
+}
+
+// apply copies an entry edited in the vault to its post.
+func (s *Syncer) apply(ctx context.Context, post posts.Post, e noteEntry) (posts.Post, error) {
+	if posts.ContentHash(e.Content) != posts.ContentHash(post.Content) {
+		if err := s.repo.UpdatePost(ctx, e.Content, post.Id); err != nil {
+			return post, err
+		}
+	}
+	if !slices.Equal(posts.NormalizeTags(e.Tags), posts.NormalizeTags(postEntry(post).Tags)) {
+		if err := s.repo.SetPostTags(ctx, post.Id, e.Tags); err != nil {
+			return post, err
+		}
+	}
+	return s.repo.GetPost(ctx, s.accountId, post.Id)
+}
+
+// create adds an entry written in the vault as a new post. Without a
+// creation time in its marker, it is dated to its note: now for today's
+// note, midday for any other.
+func (s *Syncer) create(ctx context.Context, v vaultEntry) (posts.Post, error) {
+	now := time.Now()
+	created, err := time.Parse(time.RFC3339, v.CreatedAt)
+	if err != nil {
+		created = now
+		if day, err := time.ParseInLocation(time.DateOnly, v.day, time.Local); err == nil && v.day != now.Format(time.DateOnly) {
+			created = day.Add(12 * time.Hour)
+		}
+	}
+	updated := now
+	if created.After(now) {
+		updated = created
+	}
+	return s.repo.CreatePost(ctx, posts.Post{
+		Content:   v.Content,
+		CreatedAt: created.Format(time.RFC3339),
+		UpdatedAt: updated.Format(time.RFC3339),
+		AccountId: s.accountId,
+		Tags:      v.Tags,
+	})
+}
+
+func noteIsNewer(modTime time.Time, updatedAt string) bool {
+	updated, err := time.Parse(time.RFC3339, updatedAt)
+	return err != nil || modTime.After(updated)
+}
+
+// readNotes refreshes s.notes from the folder, reading only the notes that
+// changed since the last sync.
+func (s *Syncer) readNotes() error {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	notes := map[string]*note{}
+	for _, d := range dirEntries {
+		name := d.Name()
+		if d.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".md") {
+			continue
+		}
+		day, ok := s.noteDay(name)
+		if !ok {
+			continue
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n := s.notes[day]; n != nil && n.name == name && n.modTime.Equal(info.ModTime()) && n.size == info.Size() {
+			notes[day] = n
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return err
+		}
+		notes[day] = &note{
+			name:    name,
+			day:     day,
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			text:    string(data),
+			entries: parseNote(string(data)),
+		}
+	}
+	s.notes = notes
+	return nil
+}
+
+// noteDay returns the day of the note with the given file name. Names that
+// do not follow the layout exactly are not daily notes.
+func (s *Syncer) noteDay(name string) (string, bool) {
+	base := name[:len(name)-len(filepath.Ext(name))]
+	t, err := time.Parse(s.layout, base)
+	if err != nil || t.Format(s.layout) != base {
+		return "", false
+	}
+	return t.Format(time.DateOnly), true
+}
+
+func (s *Syncer) noteName(day string) string {
+	t, _ := time.Parse(time.DateOnly, day)
+	return t.Format(s.layout) + ".md"
+}
+
+// writeNotes rewrites the notes whose entries differ from the synced posts
+// and removes notes that are left without entries.
+func (s *Syncer) writeNotes(synced map[int64]posts.Post) (written int, removed int, err error) {
+	byDay := map[string][]posts.Post{}
+	for _, post := range synced {
+		if day, ok := entryDay(post.CreatedAt); ok {
+			byDay[day] = append(byDay[day], post)
+		}
+	}
+
+	for day, dayPosts := range byDay {
+		sort.Slice(dayPosts, func(i, j int) bool {
+			ti, _ := time.Parse(time.RFC3339, dayPosts[i].CreatedAt)
+			tj, _ := time.Parse(time.RFC3339, dayPosts[j].CreatedAt)
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return dayPosts[i].Id < dayPosts[j].Id
+		})
+		entries := make([]noteEntry, len(dayPosts))
+		for i, post := range dayPosts {
+			entries[i] = postEntry(post)
+		}
+		text := renderNote(entries)
+		if n := s.notes[day]; n != nil && n.text == text {
+			continue
+		}
+		name := s.noteName(day)
+		if n := s.notes[day]; n != nil {
+			name = n.name
+		}
+		if err := s.writeNote(day, name, text, entries); err != nil {
+			return written, removed, err
+		}
+		written++
+	}
+
+	for day, n := range s.notes {
+		if _, ok := byDay[day]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, n.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return written, removed, err
+		}
+		delete(s.notes, day)
+		removed++
+	}
+	return written, removed, nil
+}
+
+func (s *Syncer) writeNote(day string, name string, text string, entries []noteEntry) error {
+	path := filepath.Join(s.dir, name)
+	if err := writeFileAtomic(path, []byte(text)); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	s.notes[day] = &note{name: name, day: day, modTime: info.ModTime(), size: info.Size(), text: text, entries: entries}
+	return nil
+}
+
+// saveConflict keeps the losing version of a conflicting entry.
+func (s *Syncer) saveConflict(day string, e noteEntry, side string) error {
+	dir := filepath.Join(s.dir, ConflictDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d-%s-%s.md", day, e.Id, side, time.Now().UTC().Format("20060102T150405Z"))
+	text := e.Content + "\n"
+	if len(e.Tags) > 0 {
+		text += "\n" + strings.Join(e.Tags, " ") + "\n"
+	}
+	return writeFileAtomic(filepath.Join(dir, name), []byte(text))
+}
+
+func (s *Syncer) statePath() string {
+	return filepath.Join(s.dir, ".journal-lite", "state.json")
+}
+
+// loadState reads the base versions of the last sync. A state written for
+// another account is ignored, so that every entry is compared afresh.
+func (s *Syncer) loadState() (syncState, error) {
+	state := syncState{Entries: map[int64]entryState{}}
+	data, err := os.ReadFile(s.statePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("reading sync state: %w", err)
+	}
+	if state.AccountId != s.accountId || state.Entries == nil {
+		state.Entries = map[int64]entryState{}
+	}
+	return state, nil
+}
+
+func (s *Syncer) saveState(state syncState) error {
+	if err := os.MkdirAll(filepath.Dir(s.statePath()), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.statePath(), data)
+}
+
+// writeFileAtomic replaces a file through a hidden temporary file, so that
+// editors never see it half written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".journal-lite-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func sortedIds(ids map[int64]bool) []int64 {
+	sorted := make([]int64, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	slices.Sort(sorted)
+	return sorted
+}
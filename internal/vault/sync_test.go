@@ -0,0 +1,263 @@
+package vault
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/database"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/repository/sqlite"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncFixture is a journal with two entries on different days and a vault
+// that has been synced with it once.
+type syncFixture struct {
+	t         *testing.T
+	repo      repository.PostRepository
+	accountId int64
+	dir       string
+	syncer    *Syncer
+	run       posts.Post
+	rest      posts.Post
+}
+
+func newSyncFixture(t *testing.T) *syncFixture {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "journal.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	accountId, err := sqlite.NewAccountRepository(db).CreateAccount(ctx, accounts.Account{Username: "vault", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &syncFixture{t: t, repo: sqlite.NewPostRepository(db), accountId: accountId, dir: t.TempDir()}
+	f.syncer = NewSyncer(f.repo, accountId, f.dir, time.DateOnly)
+	past := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	f.run = f.create("2024-01-29T08:30:00+01:00", past, "Went for a run.", "health")
+	f.rest = f.create("2024-01-30T21:00:00+01:00", past, "Stayed in.")
+	if result := f.sync(); result.Written != 2 {
+		f.t.Fatalf("first sync = %+v, want both notes written", result)
+	}
+	return f
+}
+
+func (f *syncFixture) create(createdAt string, updatedAt string, content string, tags ...string) posts.Post {
+	f.t.Helper()
+	post, err := f.repo.CreatePost(context.Background(), posts.Post{
+		Content: content, CreatedAt: createdAt, UpdatedAt: updatedAt, AccountId: f.accountId, Tags: tags,
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return f.get(post.Id)
+}
+
+func (f *syncFixture) get(id int64) posts.Post {
+	f.t.Helper()
+	post, err := f.repo.GetPost(context.Background(), f.accountId, id)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return post
+}
+
+func (f *syncFixture) sync() Result {
+	f.t.Helper()
+	result, err := f.syncer.Sync(context.Background())
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return result
+}
+
+func (f *syncFixture) notePath(day string) string {
+	return filepath.Join(f.dir, day+".md")
+}
+
+func (f *syncFixture) readNote(day string) string {
+	f.t.Helper()
+	data, err := os.ReadFile(f.notePath(day))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return string(data)
+}
+
+// editNote replaces old with new in a note and sets its modification time.
+func (f *syncFixture) editNote(day string, old string, new string, modTime time.Time) {
+	f.t.Helper()
+	text := f.readNote(day)
+	if !strings.Contains(text, old) {
+		f.t.Fatalf("note %s does not contain %q:\n%s", day, old, text)
+	}
+	if err := os.WriteFile(f.notePath(day), []byte(strings.Replace(text, old, new, 1)), 0o644); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := os.Chtimes(f.notePath(day), modTime, modTime); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *syncFixture) conflicts() map[string]string {
+	f.t.Helper()
+	entries, err := os.ReadDir(filepath.Join(f.dir, ConflictDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	conflicts := map[string]string{}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(f.dir, ConflictDir, e.Name()))
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		conflicts[e.Name()] = string(data)
+	}
+	return conflicts
+}
+
+func TestSyncWritesDailyNotes(t *testing.T) {
+	f := newSyncFixture(t)
+	want := "<!-- journal-lite id=" + strconv.FormatInt(f.run.Id, 10) + " created=2024-01-29T08:30:00+01:00 -->\nWent for a run.\n\n#health\n"
+	if got := f.readNote("2024-01-29"); got != want {
+		t.Errorf("note = %q, want %q", got, want)
+	}
+	if result := f.sync(); result.Changed() {
+		t.Errorf("second sync = %+v, want no changes", result)
+	}
+}
+
+func TestSyncCopiesEditsInTheVault(t *testing.T) {
+	f := newSyncFixture(t)
+	f.editNote("2024-01-29", "Went for a run.\n\n#health", "Went for a long run.\n\n#health #running", time.Now())
+
+	if result := f.sync(); result.Updated != 1 || result.Conflicts != 0 {
+		t.Errorf("sync = %+v, want one post updated", result)
+	}
+	post := f.get(f.run.Id)
+	if post.Content != "Went for a long run." || !slices.Equal(post.Tags, []string{"health", "running"}) {
+		t.Errorf("post = %q %v, want the note's content and tags", post.Content, post.Tags)
+	}
+}
+
+func TestSyncCopiesEditsInTheApp(t *testing.T) {
+	f := newSyncFixture(t)
+	if err := f.repo.UpdatePost(context.Background(), "Went for a run in the rain.", f.run.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := f.sync(); result.Written != 1 || result.Updated != 0 {
+		t.Errorf("sync = %+v, want one note written", result)
+	}
+	if note := f.readNote("2024-01-29"); !strings.Contains(note, "Went for a run in the rain.\n") {
+		t.Errorf("note was not rewritten:\n%s", note)
+	}
+}
+
+func TestSyncResolvesConflictsForTheNewerSide(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		modTime time.Time
+		want    string
+		loser   string
+		lost    string
+	}{
+		{"vault is newer", time.Now().Add(time.Hour), "Edited in the vault.", "-app-", "Edited in the app."},
+		{"app is newer", time.Now().Add(-time.Hour), "Edited in the app.", "-vault-", "Edited in the vault."},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newSyncFixture(t)
+			if err := f.repo.UpdatePost(context.Background(), "Edited in the app.", f.run.Id); err != nil {
+				t.Fatal(err)
+			}
+			f.editNote("2024-01-29", "Went for a run.", "Edited in the vault.", tc.modTime)
+
+			if result := f.sync(); result.Conflicts != 1 {
+				t.Errorf("sync = %+v, want one conflict", result)
+			}
+			if got := f.get(f.run.Id).Content; got != tc.want {
+				t.Errorf("post content = %q, want %q", got, tc.want)
+			}
+			if note := f.readNote("2024-01-29"); !strings.Contains(note, tc.want+"\n") {
+				t.Errorf("note does not hold the winning version %q:\n%s", tc.want, note)
+			}
+			conflicts := f.conflicts()
+			if len(conflicts) != 1 {
+				t.Fatalf("conflicts = %v, want one", conflicts)
+			}
+			for name, text := range conflicts {
+				if !strings.HasPrefix(name, "2024-01-29-"+strconv.FormatInt(f.run.Id, 10)+tc.loser) || text != tc.lost+"\n\n#health\n" {
+					t.Errorf("conflict %s = %q, want the losing version %q", name, text, tc.lost)
+				}
+			}
+			if status := f.syncer.Status(); status.Conflicts != 1 {
+				t.Errorf("status = %+v, want one conflict", status)
+			}
+		})
+	}
+}
+
+func TestSyncDeletesPostsDeletedInTheVault(t *testing.T) {
+	f := newSyncFixture(t)
+	if err := os.Remove(f.notePath("2024-01-30")); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := f.sync(); result.Deleted != 1 {
+		t.Errorf("sync = %+v, want one post deleted", result)
+	}
+	if _, err := f.repo.GetPost(context.Background(), f.accountId, f.rest.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetPost of the deleted note's post: got %v, want sql.ErrNoRows", err)
+	}
+	f.get(f.run.Id)
+}
+
+func TestSyncDoesNotDeleteAnythingFromAnEmptiedVault(t *testing.T) {
+	f := newSyncFixture(t)
+	for _, day := range []string{"2024-01-29", "2024-01-30"} {
+		if err := os.Remove(f.notePath(day)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if result := f.sync(); result.Deleted != 0 || result.Written != 2 {
+		t.Errorf("sync = %+v, want both notes rewritten and nothing deleted", result)
+	}
+	f.get(f.run.Id)
+	f.get(f.rest.Id)
+}
+
+func TestSyncCreatesPostsWrittenInTheVault(t *testing.T) {
+	f := newSyncFixture(t)
+	if err := os.WriteFile(f.notePath("2024-02-01"), []byte("Written in the vault.\n\n#ideas\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := f.sync(); result.Created != 1 {
+		t.Errorf("sync = %+v, want one post created", result)
+	}
+	if note := f.readNote("2024-02-01"); !strings.HasPrefix(note, "<!-- journal-lite id=") {
+		t.Errorf("new entry was not given a marker:\n%s", note)
+	}
+	if result := f.sync(); result.Changed() {
+		t.Errorf("sync after creating = %+v, want no changes", result)
+	}
+}
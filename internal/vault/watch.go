@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher reports changes to the daily notes of a folder. Editors save in
+// bursts, so changes are reported once the folder has been quiet for a
+// moment.
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	changes chan struct{}
+	errors  chan error
+	done    chan struct{}
+}
+
+const settle = 300 * time.Millisecond
+
+func NewWatcher(dir string) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(dir); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	w := &Watcher{
+		watcher: fw,
+		changes: make(chan struct{}, 1),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Changes delivers a value after notes were created, changed or removed.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Errors delivers errors from the underlying watcher.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.watcher.Close()
+}
+
+func (w *Watcher) run() {
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".md") || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(settle)
+		case <-timer.C:
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			default:
+			}
+		}
+	}
+}
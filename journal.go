@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"journal-lite/internal/client"
+	"journal-lite/internal/config"
+	"journal-lite/internal/posts"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runJournalCommand reads and writes entries on a running server through the
+// JSON API, authenticated with a personal access token. It never opens the
+// database, so it runs before it is opened.
+func runJournalCommand(cfg config.Config, args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] journal new [--tag TAG]... [--json]      (reads stdin, or opens $EDITOR)")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] journal list [--tag TAG] [--from DATE] [--to DATE] [--page N] [--limit N] [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] journal search TEXT [list flags]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] journal show ID [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] journal edit ID [--json]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	action, args := args[0], args[1:]
+	flags := flag.NewFlagSet("journal "+action, flag.ContinueOnError)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+
+	var run func(ctx context.Context, c *client.Client) int
+	switch action {
+	case "new":
+		var tags stringList
+		flags.Var(&tags, "tag", "tag the entry; repeat for more tags")
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() > 0 {
+			flags.Usage()
+			return 2
+		}
+		run = func(ctx context.Context, c *client.Client) int { return journalNew(ctx, c, tags, *asJSON) }
+
+	case "list", "search":
+		tag := flags.String("tag", "", "only entries with this tag")
+		from := flags.String("from", "", "only entries written on or after this date (YYYY-MM-DD)")
+		to := flags.String("to", "", "only entries written on or before this date (YYYY-MM-DD)")
+		page := flags.Int64("page", 1, "page of results")
+		limit := flags.Int64("limit", 20, "entries per page, at most 100")
+		params := posts.QueryParams{}
+		if action == "search" {
+			text, ok := parseWithName(flags, args)
+			if !ok {
+				return 2
+			}
+			params.SearchText = text
+		} else {
+			if err := flags.Parse(args); err != nil {
+				return 2
+			}
+			if flags.NArg() > 0 {
+				flags.Usage()
+				return 2
+			}
+		}
+		params.Tag, params.DateFrom, params.DateTo = *tag, *from, *to
+		params.PageNumber, params.PageSize = *page, *limit
+		run = func(ctx context.Context, c *client.Client) int { return journalList(ctx, c, params, *asJSON) }
+
+	case "show", "edit":
+		value, ok := parseWithName(flags, args)
+		if !ok {
+			return 2
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid entry ID %q\n", value)
+			return 2
+		}
+		run = func(ctx context.Context, c *client.Client) int { return journalEdit(ctx, c, id, *asJSON) }
+		if action == "show" {
+			run = func(ctx context.Context, c *client.Client) int { return journalShow(ctx, c, id, *asJSON) }
+		}
+	default:
+		usage()
+		return 2
+	}
+
+	if cfg.Client.Token == "" {
+		fmt.Fprintln(os.Stderr, "Set JOURNAL_TOKEN or client.token to a personal access token")
+		return 2
+	}
+	return run(context.Background(), client.New(clientURL(cfg), cfg.Client.Token))
+}
+
+func journalNew(ctx context.Context, c *client.Client, tags []string, asJSON bool) int {
+	var content string
+	if stdinIsTerminal() {
+		draft, err := editDraft(client.FormatDraft("", tags))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		content, tags, err = client.ParseDraft(draft)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		content = strings.TrimSpace(string(data))
+	}
+	if content == "" {
+		fmt.Fprintln(os.Stderr, "Entry is empty; nothing was saved")
+		return 1
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	post, err := c.CreatePost(ctx, content, tags)
+	if err != nil {
+		return clientError(c, err)
+	}
+	if asJSON {
+		return printJSON(post)
+	}
+	fmt.Printf("Created entry %d\n", post.Id)
+	return 0
+}
+
+func journalList(ctx context.Context, c *client.Client, params posts.QueryParams, asJSON bool) int {
+	list, err := c.ListPosts(ctx, params)
+	if err != nil {
+		return clientError(c, err)
+	}
+	if asJSON {
+		return printJSON(list)
+	}
+	if len(list.Posts) == 0 {
+		fmt.Println("No entries")
+		return 0
+	}
+	table := newTable()
+	fmt.Fprintln(table, "ID\tDATE\tTAGS\tENTRY")
+	for _, post := range list.Posts {
+		date := post.CreatedAt
+		if t, err := time.Parse(time.RFC3339, post.CreatedAt); err == nil {
+			date = t.Format("2006-01-02 15:04")
+		}
+		excerpt := strings.Join(strings.Fields(posts.Excerpt(post.Content, 60)), " ")
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", post.Id, date, strings.Join(post.Tags, ", "), excerpt)
+	}
+	table.Flush()
+	if int64(len(list.Posts)) == list.PageSize {
+		fmt.Printf("More entries with --page %d\n", list.PageNumber+1)
+	}
+	return 0
+}
+
+func journalShow(ctx context.Context, c *client.Client, id int64, asJSON bool) int {
+	post, _, err := c.GetPost(ctx, id)
+	if err != nil {
+		return clientError(c, err)
+	}
+	if asJSON {
+		return printJSON(post)
+	}
+	fmt.Printf("Entry %d, written %s", post.Id, post.CreatedAt)
+	if len(post.Tags) > 0 {
+		fmt.Printf(", tagged %s", strings.Join(post.Tags, ", "))
+	}
+	fmt.Printf("\n\n%s\n", strings.TrimRight(post.Content, "\n"))
+	return 0
+}
+
+// journalEdit opens an entry in $EDITOR and saves it if it changed. If the
+// entry was changed elsewhere in the meantime, nothing is overwritten and
+// the edited draft is kept in a file.
+func journalEdit(ctx context.Context, c *client.Client, id int64, asJSON bool) int {
+	post, etag, err := c.GetPost(ctx, id)
+	if err != nil {
+		return clientError(c, err)
+	}
+	original := client.FormatDraft(post.Content, post.Tags)
+	draft, err := editDraft(original)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if draft == original {
+		fmt.Fprintln(os.Stderr, "No changes")
+		return 0
+	}
+	content, tags, err := client.ParseDraft(draft)
+	if err == nil && content == "" {
+		err = errors.New("entry is empty")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not saved: %v\n", err)
+		keepDraft(draft)
+		return 1
+	}
+
+	updated, err := c.UpdatePost(ctx, id, etag, content, tags)
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusPreconditionFailed {
+		fmt.Fprintf(os.Stderr, "Entry %d was changed elsewhere while you edited it; nothing was overwritten\n", id)
+		keepDraft(draft)
+		return 1
+	}
+	if err != nil {
+		keepDraft(draft)
+		return clientError(c, err)
+	}
+	if asJSON {
+		return printJSON(updated)
+	}
+	fmt.Printf("Updated entry %d\n", updated.Id)
+	return 0
+}
+
+// editDraft opens text in $VISUAL or $EDITOR and returns what was saved.
+func editDraft(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "journal-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// Run through the shell so that editors with arguments, such as
+	// "code --wait", work.
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// keepDraft saves a draft that could not be saved on the server, so that
+// the edit is not lost.
+func keepDraft(draft string) {
+	f, err := os.CreateTemp("", "journal-draft-*.md")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(draft); err == nil {
+		fmt.Fprintf(os.Stderr, "Your draft is saved in %s\n", f.Name())
+	}
+}
+
+// clientError reports a failed request and returns the exit code.
+func clientError(c *client.Client, err error) int {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusUnauthorized:
+			fmt.Fprintln(os.Stderr, "The token is invalid, revoked or expired")
+		case http.StatusForbidden:
+			fmt.Fprintf(os.Stderr, "The token is not allowed to do this: %s\n", apiErr.Message)
+		case http.StatusNotFound:
+			fmt.Fprintln(os.Stderr, "No such entry")
+		default:
+			fmt.Fprintln(os.Stderr, apiErr.Error())
+		}
+		return 1
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	fmt.Fprintf(os.Stderr, "Could not reach the server at %s: %v\n", c.BaseURL(), err)
+	return 1
+}
+
+// clientURL is client.url, or server.addr on this machine. Plain HTTP is
+// only used for localhost, which config validation enforces for client.url.
+func clientURL(cfg config.Config) string {
+	if cfg.Client.URL != "" {
+		return cfg.Client.URL
+	}
+	host, port, err := net.SplitHostPort(cfg.Server.Addr)
+	if err != nil {
+		return "http://localhost:8080"
+	}
+	if host == "" || !config.IsLoopback(host) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stringList collects a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
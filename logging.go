@@ -0,0 +1,196 @@
+// logging.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// sensitiveWords are the words of attribute and query parameter names whose
+// values are never written to the log, whatever the caller passes in. Names
+// are split into words at punctuation and case changes, so access_token,
+// codeVerifier and X-CSRF-Token are redacted but postcode and encoding are not.
+var sensitiveWords = map[string]bool{
+	"password": true, "passwd": true, "token": true, "secret": true, "key": true,
+	"authorization": true, "cookie": true, "code": true, "verifier": true,
+}
+
+var nameWord = regexp.MustCompile(`[A-Z]*[a-z0-9]+|[A-Z]+`)
+
+func isSensitiveKey(key string) bool {
+	for _, word := range nameWord.FindAllString(key, -1) {
+		if sensitiveWords[strings.ToLower(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// newLogger returns a JSON logger that adds the request ID from the context
+// to every record and redacts sensitive attributes.
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if isSensitiveKey(a.Key) {
+				return slog.String(a.Key, "REDACTED")
+			}
+			return a
+		},
+	})
+	return slog.New(contextHandler{handler})
+}
+
+// contextHandler adds request scoped attributes to records logged with a
+// request context, as in slog.InfoContext(r.Context(), ...).
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value("requestID").(string)
+	return id
+}
+
+// requestIDMiddleware reuses a well-formed X-Request-ID from the client or a
+// proxy, generates one otherwise, and echoes it in the response.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), "requestID", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLogEntry collects what the access log reports about a request. The
+// authentication middlewares fill in the account through setLogAccount, since
+// their context is not visible to the outer access log middleware.
+type accessLogEntry struct {
+	http.ResponseWriter
+	status    int
+	bytes     int
+	accountId string
+}
+
+func (e *accessLogEntry) WriteHeader(statusCode int) {
+	if e.status == 0 {
+		e.status = statusCode
+	}
+	e.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (e *accessLogEntry) Write(b []byte) (int, error) {
+	if e.status == 0 {
+		e.status = http.StatusOK
+	}
+	n, err := e.ResponseWriter.Write(b)
+	e.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (e *accessLogEntry) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// accessLogMiddleware logs one line per request with its outcome.
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{ResponseWriter: w}
+		ctx := context.WithValue(r.Context(), "accessLogEntry", entry)
+
+		next.ServeHTTP(entry, r.WithContext(ctx))
+
+		if entry.status == 0 {
+			entry.status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if entry.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", redactQuery(r.URL.Query())),
+			slog.Int("status", entry.status),
+			slog.Int("bytes", entry.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("account_id", entry.accountId),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// setLogAccount records the authenticated account for the access log and on
+// the request span.
+func setLogAccount(r *http.Request, accountId string) {
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", accountId))
+	if entry, ok := r.Context().Value("accessLogEntry").(*accessLogEntry); ok {
+		entry.accountId = accountId
+	}
+}
+
+func redactQuery(query url.Values) string {
+	for key := range query {
+		if isSensitiveKey(key) {
+			query[key] = []string{"REDACTED"}
+		}
+	}
+	return query.Encode()
+}
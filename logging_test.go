@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"journal-lite/internal/tokens"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// captureLog sends the default logger to a buffer for the rest of the test
+// and returns a function that reads back the records logged so far.
+func captureLog(t *testing.T) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newLogger(&buf, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("log line is not JSON: %v\n%s", err, line)
+			}
+			records = append(records, record)
+		}
+		buf.Reset()
+		return records
+	}
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"password":      true,
+		"new_password":  true,
+		"Authorization": true,
+		"access_token":  true,
+		"X-CSRF-Token":  true,
+		"codeVerifier":  true,
+		"code_verifier": true,
+		"code":          true,
+		"client_secret": true,
+		"Set-Cookie":    true,
+		"api_key":       true,
+		"postcode":      false,
+		"encoding":      false,
+		"keyboard":      false,
+		"state":         false,
+		"account_id":    false,
+		"user_agent":    false,
+	} {
+		if got := isSensitiveKey(key); got != want {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestAccessLogRedactsSecrets(t *testing.T) {
+	readLog := captureLog(t)
+	accountId, token := newTestAccount(t, "access-log", tokens.ScopePostsRead)
+	root := newRootHandler(http.HandlerFunc(handler))
+	secrets := []string{token, "oidc-code-value", "oidc-verifier-value", "feed-token-value", "form-password-value"}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/api/v1/posts", nil),
+		httptest.NewRequest("GET", "/auth/oidc/callback?code=oidc-code-value&code_verifier=oidc-verifier-value&state=state-value", nil),
+		httptest.NewRequest("GET", "/feeds/journal.atom?token=feed-token-value&postcode=12345", nil),
+		httptest.NewRequest("POST", "/login?password=form-password-value", strings.NewReader("username=access-log&password=form-password-value")),
+	} {
+		req.Header.Set("Authorization", "Bearer "+token)
+		root.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	slog.Info("signing in", "password", "form-password-value", "Authorization", "Bearer "+token, "code_verifier", "oidc-verifier-value")
+
+	records := readLog()
+	for _, record := range records {
+		line, _ := json.Marshal(record)
+		for _, secret := range secrets {
+			if strings.Contains(string(line), secret) {
+				t.Errorf("log holds the secret %q:\n%s", secret, line)
+			}
+		}
+	}
+
+	var queries []string
+	for _, record := range records {
+		if record["msg"] != "request" {
+			continue
+		}
+		queries = append(queries, record["query"].(string))
+		if record["path"] == "/api/v1/posts" && record["account_id"] != strconv.FormatInt(accountId, 10) {
+			t.Errorf("access log of the API request has account_id %v, want %d", record["account_id"], accountId)
+		}
+	}
+	wantQueries := []string{
+		"",
+		"code=REDACTED&code_verifier=REDACTED&state=state-value",
+		"postcode=12345&token=REDACTED",
+		"password=REDACTED",
+	}
+	if strings.Join(queries, "\n") != strings.Join(wantQueries, "\n") {
+		t.Errorf("logged queries = %q, want %q", queries, wantQueries)
+	}
+
+	last := records[len(records)-1]
+	for _, key := range []string{"password", "Authorization", "code_verifier"} {
+		if last[key] != "REDACTED" {
+			t.Errorf("%s logged as %v, want REDACTED", key, last[key])
+		}
+	}
+}
+
+func TestRequestIDIsPropagated(t *testing.T) {
+	readLog := captureLog(t)
+	root := newRootHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handling")
+		handleError(w, r, "Not found", http.StatusNotFound)
+	}))
+
+	for _, tc := range []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"none", "", false},
+		{"valid", "req-42.a:b_c", true},
+		{"invalid", "bad id\nwith newline", false},
+		{"too long", strings.Repeat("a", 129), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/missing", nil)
+			if tc.incoming != "" {
+				req.Header.Set(requestIDHeader, tc.incoming)
+			}
+			recorder := httptest.NewRecorder()
+			root.ServeHTTP(recorder, req)
+
+			id := recorder.Header().Get(requestIDHeader)
+			if tc.reused && id != tc.incoming {
+				t.Errorf("X-Request-ID = %q, want the incoming %q", id, tc.incoming)
+			}
+			if !tc.reused && (id == tc.incoming || !validRequestID(id)) {
+				t.Errorf("X-Request-ID = %q, want a new ID", id)
+			}
+
+			records := readLog()
+			if len(records) != 3 {
+				t.Fatalf("logged %d records, want the handler's two and the access log", len(records))
+			}
+			for _, record := range records {
+				if record["request_id"] != id {
+					t.Errorf("%q logged with request_id %v, want %q", record["msg"], record["request_id"], id)
+				}
+			}
+		})
+	}
+
+	// Requests get IDs of their own.
+	first, second := httptest.NewRecorder(), httptest.NewRecorder()
+	root.ServeHTTP(first, httptest.NewRequest("GET", "/missing", nil))
+	root.ServeHTTP(second, httptest.NewRequest("GET", "/missing", nil))
+	if first.Header().Get(requestIDHeader) == second.Header().Get(requestIDHeader) {
+		t.Error("two requests got the same request ID")
+	}
+}
@@ -3,30 +3,62 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"io"
 	"journal-lite/internal/accounts"
 	"journal-lite/internal/auth"
+	"journal-lite/internal/config"
 	"journal-lite/internal/database"
+	"journal-lite/internal/metrics"
 	"journal-lite/internal/posts"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/repository/instrumented"
 	"journal-lite/internal/repository/sqlite"
 	"journal-lite/internal/service"
-	"log"
+	"journal-lite/internal/tokens"
+	"journal-lite/internal/tracing"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Template struct {
 	templates *template.Template
+	funcMap   template.FuncMap
+	// devMode re-parses the templates from views/ on disk for every render.
+	devMode bool
 }
 
 func (t *Template) Render(w io.Writer, name string, data interface{}, r *http.Request) error {
-	return t.templates.ExecuteTemplate(w, name+".html", data)
+	var templates *template.Template
+	var err error
+	if t.devMode {
+		templates, err = template.New("").Funcs(t.funcMap).ParseFS(os.DirFS("."), "views/*.html")
+	} else {
+		// Bind the request-scoped functions on a clone so the shared set is
+		// never executed directly and stays cloneable.
+		templates, err = t.templates.Clone()
+	}
+	if err != nil {
+		return err
+	}
+	templates.Funcs(template.FuncMap{
+		"csrfToken": func() string { return csrfTokenFromRequest(r) },
+		"cspNonce":  func() string { return cspNonceFromRequest(r) },
+	})
+	return templates.ExecuteTemplate(w, name+".html", data)
 }
 
-func newTemplate() *Template {
+func newTemplate(devMode bool) *Template {
 	funcMap := template.FuncMap{
 		"formatDate": func(date string) string {
 			t, err := time.Parse(time.RFC3339, date)
@@ -35,156 +67,328 @@ func newTemplate() *Template {
 			}
 			return t.Format("January 2, 2006")
 		},
+		"asset": assets.URL,
+		// Replaced per request in Render.
+		"csrfToken": func() string { return "" },
+		"cspNonce":  func() string { return "" },
 	}
 	return &Template{
-		templates: template.Must(template.New("").Funcs(funcMap).ParseGlob("views/*.html")),
+		templates: template.Must(template.New("").Funcs(funcMap).ParseFS(viewsFS, "views/*.html")),
+		funcMap:   funcMap,
+		devMode:   devMode,
 	}
 }
 
 var (
-	templates      = newTemplate()
-	accountService *service.AccountService
-	postService    *service.PostService
+	authenticator   *auth.Authenticator
+	assets          *Assets
+	templates       *Template
+	accountService  *service.AccountService
+	postService     *service.PostService
+	tokenService    *service.TokenService
+	exportService   *service.ExportService
+	importService   *service.ImportService
+	feedService     *service.FeedService
+	reminderService *service.ReminderService
+	statsService    *service.StatsService
 )
 
 func main() {
-	database.Initialize()
-	defer database.CloseDB()
+	cfg, opts, args, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if opts.PrintConfig {
+		out, printErr := cfg.Redacted().TOML()
+		if printErr != nil {
+			fmt.Fprintf(os.Stderr, "Error printing config: %v\n", printErr)
+			os.Exit(1)
+		}
+		fmt.Print(out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if opts.PrintConfig {
+		return
+	}
+
+	slog.SetDefault(newLogger(os.Stderr, cfg.Log.SlogLevel()))
+
+	command := ""
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	run, isCommand := commands[command]
+	switch {
+	case command == "" || command == "serve":
+		if len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] [serve]")
+			os.Exit(2)
+		}
+	case command == "restore":
+		// Restoring replaces the database file, so it runs before it is opened.
+		os.Exit(runRestoreCommand(cfg, args))
+	case command == "journal":
+		os.Exit(runJournalCommand(cfg, args))
+	case !isCommand:
+		fmt.Fprintf(os.Stderr, "Unknown command %q. Commands: %s\n", command, commandList)
+		os.Exit(2)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Error initializing tracing", err)
+	}
 
+	authenticator, err = auth.NewAuthenticator(cfg.Auth.JWTSecret, cfg.Auth.TokenLifetime.Duration)
+	if err != nil {
+		fatal("Error initializing authentication", err)
+	}
+	if cfg.Auth.JWTSecret == "" && !isCommand {
+		slog.Warn("auth.jwt_secret is not set; sessions will not survive a restart")
+	}
+
+	assets = newAssets(cfg.Server.DevMode)
+	templates = newTemplate(cfg.Server.DevMode)
+
+	dbOptions := database.Options{
+		ManualCheckpoints: cfg.Replication.URL != "",
+		SkipMigrations:    command == "migrate",
+	}
+	if err := database.Initialize(cfg.Database.Path, dbOptions); err != nil {
+		fatal("Error initializing database", err)
+	}
+
+	postRepo := initServices(database.Db)
+
+	configureSSO(cfg.OIDC)
+	registerHealthChecks(cfg.Health, cfg.Database.Path)
+
+	if err := configureBackups(cfg.Backup); err != nil {
+		fatal("Error configuring backups", err)
+	}
+	if isCommand {
+		code := run(cfg, args)
+		database.CloseDB()
+		os.Exit(code)
+	}
+	scheduleBackups(cfg.Backup.Interval.Duration)
+	if err := startReplication(cfg.Replication, cfg.Database.Path); err != nil {
+		fatal("Error configuring replication", err)
+	}
+	if err := startVaultSync(cfg.Vault, postRepo); err != nil {
+		fatal("Error configuring vault sync", err)
+	}
+
+	if cfg.Server.DevMode {
+		slog.Info("Dev mode: serving templates and static files from disk")
+	}
+
+	htmlMux, apiMux = newHTMLMux(cfg), newAPIMux(cfg)
+	mux := http.NewServeMux()
+	mux.Handle("/static/", securityHeadersMiddleware(assets))
+	mux.Handle("/", securityHeadersMiddleware(csrfMiddleware(cfg.Server.CookieSecure,
+		publicURLMiddleware(cfg.Server.PublicURL, http.HandlerFunc(handler)))))
+
+	if cfg.Metrics.Addr != "" {
+		startMetricsListener(cfg.Metrics.Addr, cfg.Metrics.Token)
+	} else if cfg.Metrics.Token != "" {
+		mux.Handle("/metrics", metricsHandler(cfg.Metrics.Token))
+	}
+
+	err = serve(newServer(cfg.Server, newRootHandler(mux)), cfg.Server.ShutdownTimeout.Duration)
+	if closeErr := database.CloseDB(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if traceErr := shutdownTracing(flushCtx); traceErr != nil {
+		err = errors.Join(err, traceErr)
+	}
+	if err != nil {
+		fatal("Server stopped with error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// newRootHandler wraps mux in the middleware every request goes through,
+// starting with the server span that the rest of the request's spans are
+// children of.
+func newRootHandler(mux http.Handler) http.Handler {
+	return otelhttp.NewHandler(requestIDMiddleware(accessLogMiddleware(metricsMiddleware(mux))), "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return routeLabel(r) }),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !isProbe(r.URL.Path) }),
+	)
+}
+
+// initServices builds the repositories and services over db. It returns the
+// post repository, which everything that writes posts must go through.
+func initServices(db *sql.DB) repository.PostRepository {
 	// Initialize repositories
-	accountRepo := sqlite.NewAccountRepository(database.Db)
-	postRepo := sqlite.NewPostRepository(database.Db)
+	accountRepo := instrumented.NewAccountRepository(sqlite.NewAccountRepository(db))
+	postRepo := instrumented.NewPostRepository(sqlite.NewPostRepository(db))
+	tokenRepo := instrumented.NewTokenRepository(sqlite.NewTokenRepository(db))
+	feedRepo := instrumented.NewFeedRepository(sqlite.NewFeedRepository(db))
+	reminderRepo := instrumented.NewReminderRepository(sqlite.NewReminderRepository(db))
+	statsRepo := instrumented.NewStatsRepository(sqlite.NewStatsRepository(db))
 
 	// Initialize services
+	statsService = service.NewStatsService(statsRepo)
+	// Every write to posts goes through postRepo, so that stats stay fresh.
+	postRepo = statsService.WatchPosts(postRepo)
 	accountService = service.NewAccountService(accountRepo)
 	postService = service.NewPostService(postRepo)
+	tokenService = service.NewTokenService(tokenRepo)
+	exportService = service.NewExportService(postRepo)
+	importService = service.NewImportService(postRepo)
+	feedService = service.NewFeedService(feedRepo, postRepo, accountRepo)
+	reminderService = service.NewReminderService(reminderRepo)
+	return postRepo
+}
 
-	http.HandleFunc("/", handler)
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+// fatal logs err and exits. Only use it before the server starts or after
+// the database has been closed.
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
 
 func handler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Request: %s %s", r.Method, r.URL.Path)
-
-	switch r.URL.Path {
-	case "/":
-		if r.Method == http.MethodGet {
-			renderTemplate(w, r, "index", nil)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-
-	case "/feed":
-		if r.Method == http.MethodGet {
-			authMiddleware(http.HandlerFunc(feedHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-
-	case "/posts":
-		if r.Method == http.MethodGet {
-			authMiddleware(http.HandlerFunc(postsHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-
-	case "/register":
-		switch r.Method {
-		case http.MethodGet:
-			renderTemplate(w, r, "register-box", nil)
-		case http.MethodPost:
-			registerHandler(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-
-	case "/open-delete-modal":
-		authMiddleware(http.HandlerFunc(openDeleteModalHandler)).ServeHTTP(w, r)
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		apiMux.ServeHTTP(w, r)
+		return
+	}
+	htmlMux.ServeHTTP(w, r)
+}
 
-	case "/open-edit-modal":
-		authMiddleware(http.HandlerFunc(openEditModalHandler)).ServeHTTP(w, r)
+// htmlMux routes the pages and HTMX fragments. Its patterns also label the
+// request metrics and spans. main and the tests build it with newHTMLMux.
+var htmlMux *http.ServeMux
 
-	case "/posts/update":
-		if r.Method == http.MethodPatch {
-			authMiddleware(http.HandlerFunc(updatePostHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	case "/posts/delete":
-		if r.Method == http.MethodDelete {
-			authMiddleware(http.HandlerFunc(deletePostHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	case "/open-create-modal":
-		if r.Method == http.MethodGet {
-			authMiddleware(http.HandlerFunc(openCreateModalHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
+func newHTMLMux(cfg config.Config) *http.ServeMux {
+	mux := http.NewServeMux()
+	s := sessions{passwordLogin: cfg.Auth.PasswordLoginEnabled, secureCookies: cfg.Server.CookieSecure}
 
-	case "/close-modal":
-		if r.Method == http.MethodGet {
-			renderTemplate(w, r, "empty-div", nil)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		renderTemplate(w, r, "index", s.loginBoxMessage(false, ""))
+	})
+	mux.Handle("GET /feed", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(feedHandler)))
+	mux.Handle("GET /posts", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(postsHandler)))
+	mux.Handle("GET /export", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(exportHandler)))
+	mux.Handle("GET /import", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(importPageHandler)))
+	mux.Handle("POST /import", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(importHandler)))
+	mux.Handle("GET /stats", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(statsPageHandler)))
+	mux.Handle("GET /settings", authMiddleware(tokens.ScopeAccountRead, http.HandlerFunc(settingsPageHandler)))
+	mux.Handle("POST /settings", authMiddleware(tokens.ScopeAccountWrite, http.HandlerFunc(updateSettingsHandler)))
+	mux.Handle("GET /on-this-day", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(onThisDayHandler)))
+	mux.Handle("GET /calendar", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(calendarHandler)))
+
+	mux.Handle("GET /feeds", authMiddleware(apiScopeSession, http.HandlerFunc(feedsPageHandler)))
+	mux.Handle("POST /feeds", authMiddleware(apiScopeSession, http.HandlerFunc(createFeedHandler)))
+	mux.Handle("DELETE /feeds/delete", authMiddleware(apiScopeSession, http.HandlerFunc(deleteFeedHandler)))
+	// Feed readers authenticate with the token in the URL, not a session.
+	for path, format := range feedFormats {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) { serveFeed(w, r, format) })
+	}
+	for path, format := range digestFormats {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) { serveDigest(w, r, format) })
+	}
+	mux.HandleFunc("GET /feeds/journal.ics", serveCalendar)
+	mux.Handle("POST /reminders", authMiddleware(apiScopeSession, http.HandlerFunc(createReminderHandler)))
+	mux.Handle("DELETE /reminders/delete", authMiddleware(apiScopeSession, http.HandlerFunc(deleteReminderHandler)))
+
+	mux.Handle("GET /register", s.passwordLoginOnly(func(w http.ResponseWriter, r *http.Request) {
+		renderTemplate(w, r, "register-box", nil)
+	}))
+	mux.Handle("POST /register", s.passwordLoginOnly(s.registerHandler))
+	mux.HandleFunc("POST /login", s.loginHandler)
+	mux.HandleFunc("DELETE /logout", s.logoutHandler)
+	mux.Handle("GET /auth/oidc/login", ssoOnly(s.oidcLoginHandler))
+	mux.Handle("GET /auth/oidc/link", ssoOnly(authMiddleware(apiScopeSession, http.HandlerFunc(s.oidcLinkHandler))))
+	mux.Handle("GET /auth/oidc/callback", ssoOnly(s.oidcCallbackHandler))
+
+	mux.Handle("GET /open-delete-modal", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(openDeleteModalHandler)))
+	mux.Handle("GET /open-edit-modal", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(openEditModalHandler)))
+	mux.Handle("GET /open-create-modal", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(openCreateModalHandler)))
+	mux.HandleFunc("GET /close-modal", func(w http.ResponseWriter, r *http.Request) {
+		renderTemplate(w, r, "empty-div", nil)
+	})
+	mux.Handle("PATCH /posts/update", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(updatePostHandler)))
+	mux.Handle("DELETE /posts/delete", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(deletePostHandler)))
+	mux.Handle("POST /create-post", authMiddleware(tokens.ScopePostsWrite, http.HandlerFunc(createPostHandler)))
+	mux.Handle("GET /search", authMiddleware(tokens.ScopePostsRead, http.HandlerFunc(searchHandler)))
+
+	mux.HandleFunc("GET /livez", livezHandler)
+	mux.HandleFunc("GET /readyz", readyzHandler)
+	mux.HandleFunc("GET /healthz", healthHandler)
+	mux.HandleFunc("GET /health", healthHandler)
+	mux.HandleFunc("GET /admin/replication", func(w http.ResponseWriter, r *http.Request) {
+		if replicator == nil {
+			http.NotFound(w, r)
+			return
 		}
+		adminPageMiddleware(cfg.Admin.Token, http.HandlerFunc(replicationPageHandler)).ServeHTTP(w, r)
+	})
 
-	case "/create-post":
-		if r.Method == http.MethodPost {
-			authMiddleware(http.HandlerFunc(createPostHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	case "/search":
-		if r.Method == http.MethodGet {
-			authMiddleware(http.HandlerFunc(searchHandler)).ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
+	return mux
+}
 
-	case "/health":
-		if r.Method == http.MethodGet {
-			healthHandler(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
+// sessions signs users in and out with the auth and server settings it was
+// built with.
+type sessions struct {
+	passwordLogin bool
+	secureCookies bool
+}
 
-	case "/login":
-		if r.Method == http.MethodPost {
-			loginHandler(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// passwordLoginOnly hides next when password login is disabled.
+func (s sessions) passwordLoginOnly(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.passwordLogin {
+			http.NotFound(w, r)
+			return
 		}
+		next(w, r)
+	})
+}
 
-	case "/logout":
-		if r.Method == http.MethodDelete {
-			logoutHandler(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// ssoOnly hides next unless single sign-on is configured.
+func ssoOnly(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if oidcProvider == nil {
+			http.NotFound(w, r)
+			return
 		}
-
-	default:
-		http.NotFound(w, r)
-	}
+		next(w, r)
+	})
 }
 
 // --- Handlers ---
 
 func feedHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	posts, err := postService.GetPosts(ctx, posts.QueryParams{AccountId: 1}) // Replace 1 with actual user ID from context
+	posts, err := postService.GetPosts(ctx, posts.QueryParams{AccountId: apiAccountId(r)})
 	if err != nil {
-		handleError(w, r, "Error fetching posts: "+err.Error(), http.StatusInternalServerError)
+		handleError(w, r, "Error fetching posts", http.StatusInternalServerError)
 		return
 	}
-	renderTemplate(w, r, "feed-page", posts)
+
+	// Memories are a bonus; the feed is shown without them if they fail.
+	var onThisDay onThisDayView
+	if account, today, err := accountToday(r); err == nil {
+		memories, err := postService.OnThisDay(ctx, account.Id, today)
+		if err != nil {
+			slog.WarnContext(ctx, "Could not load memories", "error", err)
+		}
+		onThisDay = newOnThisDayView(today, memories)
+	}
+	renderTemplate(w, r, "feed-page", map[string]any{"Posts": posts, "OnThisDay": onThisDay})
 }
 
 func postsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	posts, err := postService.GetPosts(ctx, posts.QueryParams{AccountId: 1}) // Replace 1 with actual user ID from context
+	posts, err := postService.GetPosts(ctx, posts.QueryParams{AccountId: apiAccountId(r)})
 	if err != nil {
 		handleError(w, r, err.Error(), http.StatusInternalServerError)
 		return
@@ -192,7 +396,7 @@ func postsHandler(w http.ResponseWriter, r *http.Request) {
 	renderTemplate(w, r, "posts", posts)
 }
 
-func registerHandler(w http.ResponseWriter, r *http.Request) {
+func (s sessions) registerHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		handleError(w, r, "Error parsing form: "+err.Error(), http.StatusBadRequest)
 		return
@@ -202,10 +406,7 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 	passwordConfirmation := r.FormValue("password-confirmation")
 
 	if password != passwordConfirmation {
-		message := LoginBoxMessage{
-			IsInvalidAttempt: true,
-			Message:          "Passwords do not match",
-		}
+		message := s.loginBoxMessage(true, "Passwords do not match")
 		renderTemplate(w, r, "register-box", message)
 		return
 	}
@@ -218,18 +419,12 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	_, err := accountService.CreateAccount(ctx, newAccount)
 	if err != nil {
-		message := LoginBoxMessage{
-			IsInvalidAttempt: true,
-			Message:          "Failed to create account: " + err.Error(),
-		}
+		message := s.loginBoxMessage(true, "Failed to create account: "+err.Error())
 		renderTemplate(w, r, "register-box", message)
 		return
 	}
 
-	message := LoginBoxMessage{
-		IsInvalidAttempt: false,
-		Message:          "Account created successfully",
-	}
+	message := s.loginBoxMessage(false, "Account created successfully")
 
 	renderTemplate(w, r, "register-account-complete", message)
 }
@@ -246,10 +441,8 @@ func openDeleteModalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	post, err := postService.GetPost(ctx, 1, id) // Replace 1 with actual user ID from context
-	if err != nil {
-		handleError(w, r, "Error fetching post: "+err.Error(), http.StatusInternalServerError)
+	post, ok := loadOwnPost(w, r, id)
+	if !ok {
 		return
 	}
 	renderTemplate(w, r, "delete-modal", post)
@@ -268,10 +461,8 @@ func openEditModalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	post, err := postService.GetPost(ctx, 1, id) // Replace 1 with actual user ID from context
-	if err != nil {
-		handleError(w, r, "Error fetching post: "+err.Error(), http.StatusInternalServerError)
+	post, ok := loadOwnPost(w, r, id)
+	if !ok {
 		return
 	}
 	renderTemplate(w, r, "edit-modal", post)
@@ -297,6 +488,9 @@ func updatePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	content := r.FormValue("content")
 
+	if _, ok := loadOwnPost(w, r, id); !ok {
+		return
+	}
 	ctx := r.Context()
 	err = postService.UpdatePost(ctx, content, id)
 	if err != nil {
@@ -319,6 +513,9 @@ func deletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := loadOwnPost(w, r, id); !ok {
+		return
+	}
 	ctx := r.Context()
 	err = postService.DeletePost(ctx, id)
 	if err != nil {
@@ -329,6 +526,22 @@ func deletePostHandler(w http.ResponseWriter, r *http.Request) {
 	renderTemplate(w, r, "empty-div", nil)
 }
 
+// loadOwnPost fetches the post id of the signed-in account, responding with
+// 404 when it does not exist or belongs to someone else.
+func loadOwnPost(w http.ResponseWriter, r *http.Request, id int64) (posts.Post, bool) {
+	post, err := postService.GetPost(r.Context(), apiAccountId(r), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		handleError(w, r, "Post not found.", http.StatusNotFound)
+		return post, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error fetching post", "error", err)
+		handleError(w, r, "Error fetching post.", http.StatusInternalServerError)
+		return post, false
+	}
+	return post, true
+}
+
 func openCreateModalHandler(w http.ResponseWriter, r *http.Request) {
 	renderTemplate(w, r, "create-modal", nil)
 }
@@ -343,7 +556,7 @@ func createPostHandler(w http.ResponseWriter, r *http.Request) {
 		Content:   r.FormValue("content"),
 		CreatedAt: time.Now().Format(time.RFC3339),
 		UpdatedAt: time.Now().Format(time.RFC3339),
-		AccountId: 1, // Replace 1 with actual user ID from context
+		AccountId: apiAccountId(r),
 	}
 
 	ctx := r.Context()
@@ -358,7 +571,7 @@ func createPostHandler(w http.ResponseWriter, r *http.Request) {
 
 func searchHandler(w http.ResponseWriter, r *http.Request) {
 	params := posts.QueryParams{
-		AccountId:  1, // Replace 1 with actual user ID from context
+		AccountId:  apiAccountId(r),
 		SearchText: r.URL.Query().Get("search"),
 		PageSize:   10,
 		PageNumber: 1,
@@ -373,20 +586,11 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 	renderTemplate(w, r, "feed", posts)
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	err := database.Db.Ping()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Header().Set("Content-Type", "application/json")
-		_, _ = w.Write([]byte(`{"status": "error", "database": "error"}`))
+func (s sessions) loginHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.passwordLogin {
+		s.renderLoginError(w, r, "Password login is disabled. Please use single sign-on.")
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write([]byte(`{"status": "ok", "database": "ok"}`))
-}
-
-func loginHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		handleError(w, r, "Could not parse form", http.StatusBadRequest)
 		return
@@ -394,47 +598,37 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 
-	token, err := auth.Login(database.Db, username, password)
+	token, err := authenticator.Login(r.Context(), database.Db, username, password)
+	metrics.Login("password", err == nil && token != "")
 	if err != nil {
-		message := LoginBoxMessage{
-			IsInvalidAttempt: true,
-			Message:          err.Error(),
-		}
+		message := s.loginBoxMessage(true, err.Error())
 		renderTemplate(w, r, "index", message) // Render login page with error
 		return
 	}
 
 	if token != "" {
-		cookie := http.Cookie{
-			Name:     "token",
-			Value:    token,
-			Expires:  time.Now().Add(24 * time.Hour),
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true, // Send over HTTPS only
-			SameSite: http.SameSiteStrictMode,
-		}
-		http.SetCookie(w, &cookie)
+		s.setSessionCookie(w, token)
 		http.Redirect(w, r, "/feed", http.StatusFound)
 		return
 	}
 
-	message := LoginBoxMessage{
-		IsInvalidAttempt: true,
-		Message:          "Invalid Credentials",
-	}
+	message := s.loginBoxMessage(true, "Invalid Credentials")
 
 	renderTemplate(w, r, "index", message) // Render login page
 }
 
-func logoutHandler(w http.ResponseWriter, r *http.Request) {
+func (s sessions) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if session, err := r.Cookie("token"); err == nil {
+		metrics.Sessions.End(session.Value)
+	}
+
 	cookie := http.Cookie{
 		Name:     "token",
 		Value:    "",
 		Expires:  time.Unix(0, 0), // Expired time
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   true,
+		Secure:   s.secureCookies,
 		SameSite: http.SameSiteStrictMode,
 	}
 	http.SetCookie(w, &cookie)
@@ -444,16 +638,38 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 
 // --- Helper Functions ---
 
+func (s sessions) setSessionCookie(w http.ResponseWriter, token string) {
+	expires := time.Now().Add(authenticator.TokenLifetime())
+	metrics.Sessions.Start(token, expires)
+
+	cookie := http.Cookie{
+		Name:     "token",
+		Value:    token,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.secureCookies, // Send over HTTPS only
+		SameSite: http.SameSiteStrictMode,
+	}
+	http.SetCookie(w, &cookie)
+}
+
 func renderTemplate(w http.ResponseWriter, r *http.Request, tmplName string, data interface{}) {
+	_, span := tracing.Start(r.Context(), "render "+tmplName)
 	err := templates.Render(w, tmplName, data, r)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("Error rendering template %s: %v", tmplName, err)
+		slog.ErrorContext(r.Context(), "Error rendering template", "template", tmplName, "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
 func handleError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
-	log.Println(message) // Log the error
+	level := slog.LevelWarn
+	if statusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(r.Context(), level, message, "status", statusCode)
 	w.WriteHeader(statusCode)
 	if r.Header.Get("HX-Request") == "true" {
 		_, _ = w.Write([]byte(fmt.Sprintf(`<div class="error">%s</div>`, message)))
@@ -462,9 +678,40 @@ func handleError(w http.ResponseWriter, r *http.Request, message string, statusC
 	}
 }
 
-// authMiddleware is a middleware function to protect routes.
-func authMiddleware(next http.Handler) http.HandlerFunc {
+// authMiddleware is a middleware function to protect routes. A personal access
+// token must hold scope; apiScopeSession routes take only a signed-in session.
+func authMiddleware(scope string, next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Scripts authenticate with a bearer token instead of the cookie.
+		if userID, scopes, ok, err := authenticateRequestBearer(r); ok {
+			if isCredentialError(err) {
+				handleError(w, r, "Invalid, revoked or expired token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Error checking token", "error", err)
+				handleError(w, r, "Error checking token", http.StatusInternalServerError)
+				return
+			}
+
+			setLogAccount(r, userID)
+			ctx := context.WithValue(r.Context(), "userID", userID)
+			ctx = context.WithValue(ctx, "tokenScopes", scopes)
+			r = r.WithContext(ctx)
+
+			if scope == apiScopeSession && !hasScope(r, scope) {
+				handleError(w, r, "This page requires signing in, not a personal access token", http.StatusForbidden)
+				return
+			}
+			if !hasScope(r, scope) {
+				handleError(w, r, "Token is missing the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		cookie, err := r.Cookie("token")
 		if err != nil {
 			if err == http.ErrNoCookie {
@@ -481,7 +728,7 @@ func authMiddleware(next http.Handler) http.HandlerFunc {
 		}
 
 		tokenStr := cookie.Value
-		claims, err := auth.ValidateToken(tokenStr) // Use the new function
+		claims, err := authenticator.ValidateToken(tokenStr)
 		if err != nil {
 			// Clear the invalid cookie
 			clearCookie := http.Cookie{
@@ -500,13 +747,121 @@ func authMiddleware(next http.Handler) http.HandlerFunc {
 			return
 		}
 
+		if err := checkAccountActive(r.Context(), claims.UserID); err != nil {
+			if !isCredentialError(err) {
+				slog.ErrorContext(r.Context(), "Error checking account", "error", err)
+				handleError(w, r, "Error checking account", http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: "", Expires: time.Unix(0, 0), Path: "/"})
+			handleError(w, r, "This account is disabled or no longer exists.", http.StatusForbidden)
+			return
+		}
+
 		// Store user ID in context (example)
+		setLogAccount(r, claims.UserID)
 		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx)) // Pass the context to the next handler
 	})
 }
 
+// authenticateBearer resolves an `Authorization: Bearer` header holding either
+// a session JWT or a personal access token. ok is false when no bearer token
+// was sent. Session tokens carry no scope list and may do anything. Errors for
+// which isCredentialError is false are failures to check the token.
+func authenticateBearer(r *http.Request) (userID string, scopes []string, ok bool, err error) {
+	tokenStr, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || tokenStr == "" {
+		return "", nil, false, nil
+	}
+
+	if strings.HasPrefix(tokenStr, tokens.Prefix) {
+		token, err := tokenService.Authenticate(r.Context(), tokenStr)
+		if err != nil {
+			return "", nil, true, err
+		}
+		userID := strconv.FormatInt(token.AccountId, 10)
+		if err := checkAccountActive(r.Context(), userID); err != nil {
+			return "", nil, true, err
+		}
+		return userID, token.Scopes, true, nil
+	}
+
+	claims, err := authenticator.ValidateToken(tokenStr)
+	if err != nil {
+		return "", nil, true, fmt.Errorf("%w: %v", errInvalidSession, err)
+	}
+	if err := checkAccountActive(r.Context(), claims.UserID); err != nil {
+		return "", nil, true, err
+	}
+	return claims.UserID, nil, true, nil
+}
+
+// authenticateRequestBearer returns the bearer token csrfMiddleware already
+// accepted for r, or authenticates it.
+func authenticateRequestBearer(r *http.Request) (userID string, scopes []string, ok bool, err error) {
+	if auth, found := r.Context().Value("bearerAuth").(bearerAuth); found {
+		return auth.userID, auth.scopes, true, nil
+	}
+	return authenticateBearer(r)
+}
+
+// errInvalidSession wraps the reason a session JWT was rejected.
+var errInvalidSession = errors.New("invalid session token")
+
+// isCredentialError reports whether err from authenticateBearer or
+// checkAccountActive means the credentials are no good, as opposed to a
+// failure to check them such as a database error.
+func isCredentialError(err error) bool {
+	return errors.Is(err, service.ErrInvalidToken) ||
+		errors.Is(err, service.ErrTokenRevoked) ||
+		errors.Is(err, service.ErrTokenExpired) ||
+		errors.Is(err, service.ErrAccountDisabled) ||
+		errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, errInvalidSession)
+}
+
+// checkAccountActive rejects sessions and tokens of accounts that were
+// disabled or deleted after they were issued.
+func checkAccountActive(ctx context.Context, userID string) error {
+	accountId, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidSession, err)
+	}
+	return accountService.CheckActive(ctx, accountId)
+}
+
+// hasScope reports whether the request was authenticated with scope. Requests
+// authenticated with a session rather than a personal access token have every
+// scope except that only sessions satisfy apiScopeSession.
+func hasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value("tokenScopes").([]string)
+	if scopes == nil {
+		return true
+	}
+	if scope == apiScopeSession {
+		return false
+	}
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type LoginBoxMessage struct {
-	IsInvalidAttempt bool
-	Message          string
+	IsInvalidAttempt     bool
+	Message              string
+	PasswordLoginEnabled bool
+	SSOEnabled           bool
+}
+
+func (s sessions) loginBoxMessage(isInvalidAttempt bool, message string) LoginBoxMessage {
+	return LoginBoxMessage{
+		IsInvalidAttempt:     isInvalidAttempt,
+		Message:              message,
+		PasswordLoginEnabled: s.passwordLogin,
+		SSOEnabled:           oidcProvider != nil,
+	}
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"journal-lite/internal/auth"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "test-admin-token"
+
+// testConfig is the configuration TestMain built the handlers with.
+var testConfig config.Config
+
+// TestMain opens one database for the whole package, since the database
+// package only opens one per process. Tests create their own accounts in it.
+// With runMainEnv set the binary runs main instead, for the command tests.
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "journal-lite-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(dir, "journal.db")
+	cfg.Backup.Dir = filepath.Join(dir, "backups")
+	cfg.Replication.URL = filepath.Join(dir, "replica")
+	cfg.Admin.Token = testAdminToken
+	testConfig = cfg
+
+	authenticator, err = auth.NewAuthenticator("test-jwt-secret", time.Hour)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	assets = newAssets(false)
+	templates = newTemplate(false)
+	if err := database.Initialize(cfg.Database.Path, database.Options{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.CloseDB()
+	initServices(database.Db)
+	if err := configureBackups(cfg.Backup); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	htmlMux, apiMux = newHTMLMux(cfg), newAPIMux(cfg)
+
+	return m.Run()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/feeds"
+	"journal-lite/internal/posts"
+	"journal-lite/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type onThisDayView struct {
+	Date     string
+	Label    string
+	PrevDate string
+	NextDate string
+	Total    int
+	Memories []posts.Memory
+	// Standalone is set on the On this day view, which shows a day even
+	// without memories and links to the days around it.
+	Standalone bool
+}
+
+// accountToday is the current day in the account's time zone.
+func accountToday(r *http.Request) (accounts.Account, time.Time, error) {
+	account, err := accountService.GetAccountById(r.Context(), apiAccountId(r))
+	if err != nil {
+		return account, time.Time{}, err
+	}
+	return account, time.Now().In(account.Location()), nil
+}
+
+func newOnThisDayView(day time.Time, memories []posts.Memory) onThisDayView {
+	view := onThisDayView{
+		Date:     day.Format(time.DateOnly),
+		Label:    day.Format("January 2"),
+		PrevDate: day.AddDate(0, 0, -1).Format(time.DateOnly),
+		NextDate: day.AddDate(0, 0, 1).Format(time.DateOnly),
+		Memories: memories,
+	}
+	for _, memory := range memories {
+		view.Total += len(memory.Posts)
+	}
+	return view
+}
+
+// onThisDayHandler shows what was written on a day in earlier years: today
+// in the account's time zone, or the day given as date.
+func onThisDayHandler(w http.ResponseWriter, r *http.Request) {
+	account, day, err := accountToday(r)
+	if err != nil {
+		handleError(w, r, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+	if value := r.URL.Query().Get("date"); value != "" {
+		if day, err = time.ParseInLocation(time.DateOnly, value, account.Location()); err != nil {
+			handleError(w, r, "Invalid date.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	memories, err := postService.OnThisDay(r.Context(), account.Id, day)
+	if err != nil {
+		handleError(w, r, "Error fetching memories", http.StatusInternalServerError)
+		return
+	}
+	view := newOnThisDayView(day, memories)
+	view.Standalone = true
+	renderTemplate(w, r, "on-this-day", view)
+}
+
+// settingsPageHandler shows the account settings: for now the time zone
+// that memories and the calendar follow.
+func settingsPageHandler(w http.ResponseWriter, r *http.Request) {
+	account, err := accountService.GetAccountById(r.Context(), apiAccountId(r))
+	if err != nil {
+		handleError(w, r, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "settings", map[string]any{
+		"Account":    account,
+		"ServerZone": time.Local.String(),
+	})
+}
+
+func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		handleError(w, r, "Could not read the form.", http.StatusBadRequest)
+		return
+	}
+	err := accountService.SetTimeZone(r.Context(), apiAccountId(r), r.FormValue("time_zone"))
+	if errors.Is(err, service.ErrUnknownTimeZone) {
+		handleError(w, r, "Unknown time zone. Use a name such as Europe/Berlin.", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		handleError(w, r, "Error saving settings.", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "settings-saved", nil)
+}
+
+// digestDays is how many days back the On this day digest goes, so that
+// readers that fetch it rarely still get every day.
+const digestDays = 7
+
+// serveDigest publishes the On this day digest of the feed whose token is
+// in the URL: one entry for each of the last digestDays days, in the
+// account's time zone, on which something was written in earlier years.
+func serveDigest(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	token, feed, account, ok := authenticateFeed(w, r)
+	if !ok {
+		return
+	}
+	doc := newFeedDocument(r, feed, account, token, 1, 1)
+	idBase := feeds.TagURI(hostOf(publicURL(r)), account.CreatedAt)
+	doc.Id = idBase + "on-this-day"
+	doc.Title = account.Username + "'s journal: on this day"
+
+	today := time.Now().In(account.Location())
+	for i := 0; i < digestDays; i++ {
+		day := time.Date(today.Year(), today.Month(), today.Day()-i, 0, 0, 0, 0, today.Location())
+		memories, err := postService.OnThisDay(r.Context(), account.Id, day)
+		if err != nil {
+			handleError(w, r, "Error loading feed", http.StatusInternalServerError)
+			return
+		}
+		if len(memories) == 0 {
+			continue
+		}
+		entry := newDigestEntry(idBase, day, memories)
+		if entry.Updated.After(doc.Updated) {
+			doc.Updated = entry.Updated
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	var body bytes.Buffer
+	if err := format.Write(&body, doc); err != nil {
+		handleError(w, r, "Error writing feed", http.StatusInternalServerError)
+		return
+	}
+	serveFeedBody(w, r, format.ContentType, doc.Updated, body.Bytes())
+}
+
+// newDigestEntry gathers a day's memories into one entry, published when
+// the day began.
+func newDigestEntry(idBase string, day time.Time, memories []posts.Memory) feeds.Entry {
+	var content strings.Builder
+	var count int
+	for _, memory := range memories {
+		years := "1 year ago"
+		if memory.YearsAgo != 1 {
+			years = strconv.Itoa(memory.YearsAgo) + " years ago"
+		}
+		for _, post := range memory.Posts {
+			if content.Len() > 0 {
+				content.WriteString("\n\n")
+			}
+			content.WriteString(years + ", " + memory.Date + ":\n\n" + post.Content)
+			count++
+		}
+	}
+	title := "On this day: 1 entry from earlier years"
+	if count != 1 {
+		title = "On this day: " + strconv.Itoa(count) + " entries from earlier years"
+	}
+	return feeds.Entry{
+		Id:        idBase + "on-this-day/" + day.Format(time.DateOnly),
+		Title:     title,
+		Content:   content.String(),
+		Published: day,
+		Updated:   day,
+	}
+}
@@ -0,0 +1,102 @@
+// metrics.go
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"journal-lite/internal/metrics"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// metricsMiddleware records request counts and latency per route. It must run
+// inside accessLogMiddleware, whose entry provides the status code.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+
+		status := http.StatusOK
+		if entry, ok := r.Context().Value("accessLogEntry").(*accessLogEntry); ok && entry.status != 0 {
+			status = entry.status
+		}
+		metrics.ObserveRequest(routeLabel(r), methodLabel(r.Method), status, time.Since(start))
+	})
+}
+
+// routeLabel is the pattern the request matched. Anything else is reported
+// under a single label so that scanners cannot blow up cardinality.
+func routeLabel(r *http.Request) string {
+	var pattern string
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		_, pattern = apiMux.Handler(r)
+	case strings.HasPrefix(r.URL.Path, "/static/"):
+		return "/static/"
+	case r.URL.Path == "/metrics":
+		return "/metrics"
+	default:
+		_, pattern = htmlMux.Handler(r)
+	}
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
+
+// methodLabel bounds the method label to the standard methods, for the same
+// reason.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// metricsHandler serves /metrics, behind a bearer token if one is configured.
+func metricsHandler(token string) http.Handler {
+	handler := metrics.Handler()
+	if token == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		submitted, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// startMetricsListener serves /metrics on its own address, typically one that
+// is only reachable from inside the cluster, until the jobs are stopped.
+func startMetricsListener(addr string, token string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(token))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	jobs.Go("metrics listener", func(ctx context.Context) {
+		go func() {
+			<-ctx.Done()
+			_ = srv.Shutdown(context.Background())
+		}()
+
+		slog.Info("Metrics listener starting", "addr", addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics listener failed", "error", err)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteLabel(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		target string
+		want   string
+	}{
+		{"GET", "/", "GET /{$}"},
+		{"GET", "/calendar?date=2024-02-29", "GET /calendar"},
+		{"HEAD", "/feeds/journal.atom?token=x", "GET /feeds/journal.atom"},
+		{"DELETE", "/posts/delete?id=1", "DELETE /posts/delete"},
+		{"GET", "/api/v1/posts/42", "GET /api/v1/posts/{id}"},
+		{"GET", "/static/app.0123456789ab.css", "/static/"},
+		{"GET", "/wp-login.php", "unmatched"},
+		// The API answers unknown paths from its catch-all.
+		{"GET", "/api/v1/wp-login.php", "/api/v1/"},
+	} {
+		if got := routeLabel(httptest.NewRequest(tc.method, tc.target, nil)); got != tc.want {
+			t.Errorf("routeLabel(%s %s) = %q, want %q", tc.method, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestMethodLabel(t *testing.T) {
+	for method, want := range map[string]string{"GET": "GET", "PATCH": "PATCH", "PROPFIND": "other", "get": "other"} {
+		if got := methodLabel(method); got != want {
+			t.Errorf("methodLabel(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// replication.go
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"journal-lite/internal/config"
+	"journal-lite/internal/database"
+	"journal-lite/internal/health"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/replication"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var replicator *replication.Replicator
+
+func newReplicaClient(cfg config.ReplicationConfig) (replication.Client, error) {
+	return replication.NewClient(replication.ClientOptions{
+		URL:             cfg.URL,
+		Endpoint:        cfg.Endpoint,
+		Region:          cfg.Region,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+	})
+}
+
+// startReplication starts shipping the WAL in the background if a replica is
+// configured. An unreachable replica does not stop the server; it is retried
+// on every sync and reported by the health check.
+func startReplication(cfg config.ReplicationConfig, databasePath string) error {
+	if cfg.URL == "" {
+		return nil
+	}
+	client, err := newReplicaClient(cfg)
+	if err != nil {
+		return fmt.Errorf("replication.url: %w", err)
+	}
+	replicator = replication.NewReplicator(database.Db, databasePath, client, replication.Options{
+		SnapshotInterval: cfg.SnapshotInterval.Duration,
+		Retention:        cfg.Retention.Duration,
+	})
+
+	interval := cfg.SyncInterval.Duration
+	jobs.Go("replication", func(ctx context.Context) {
+		syncReplica(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Ship the last transactions before the database closes.
+				closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := replicator.Close(closeCtx); err != nil {
+					slog.Error("Final replication sync failed", "error", err)
+				}
+				return
+			case <-ticker.C:
+				syncReplica(ctx)
+			}
+		}
+	})
+
+	maxLag := max(time.Minute, 10*interval)
+	healthChecks.Register(health.Check{
+		Name: "replica",
+		Run: func(ctx context.Context) (string, error) {
+			if err := replicator.Ping(ctx); err != nil {
+				return "", err
+			}
+			status := replicator.Status()
+			if status.ReplicatedAt == nil {
+				return "", health.Warning(errors.New("nothing replicated yet"))
+			}
+			lag := time.Duration(status.LagSeconds * float64(time.Second)).Round(time.Millisecond)
+			if lag > maxLag {
+				return "", health.Warning(fmt.Errorf("replica is %s behind", lag))
+			}
+			return fmt.Sprintf("%s behind", lag), nil
+		},
+	})
+
+	slog.Info("Replicating database", "replica", client.String(), "sync_interval", interval.String())
+	return nil
+}
+
+func syncReplica(ctx context.Context) {
+	err := replicator.Sync(ctx)
+	status := replicator.Status()
+	var replicatedAt time.Time
+	if status.ReplicatedAt != nil {
+		replicatedAt = *status.ReplicatedAt
+	}
+	metrics.ReplicationSync(replicatedAt, err == nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Replication sync failed", "error", err)
+	}
+}
+
+type replicationPage struct {
+	Status      replication.Status
+	Generations []replication.Generation
+	Error       string
+}
+
+func loadReplicationPage(ctx context.Context) replicationPage {
+	page := replicationPage{Status: replicator.Status()}
+	var err error
+	if page.Generations, err = replicator.Generations(ctx); err != nil {
+		page.Error = err.Error()
+	}
+	return page
+}
+
+// replicationPageHandler serves the replication status page.
+func replicationPageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	renderTemplate(w, r, "replication", loadReplicationPage(r.Context()))
+}
+
+func apiReplicationHandler(w http.ResponseWriter, r *http.Request) {
+	if replicator == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Replication is not configured.")
+		return
+	}
+	page := loadReplicationPage(r.Context())
+	if page.Error != "" {
+		writeAPIInternalError(w, r, "Error listing generations", errors.New(page.Error))
+		return
+	}
+	if page.Generations == nil {
+		page.Generations = []replication.Generation{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": page.Status, "generations": page.Generations})
+}
+
+// adminPageMiddleware protects operator pages with the admin token, sent as
+// a bearer token or as the password of HTTP basic authentication so that
+// browsers can prompt for it.
+func adminPageMiddleware(token string, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		submitted, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			_, submitted, _ = r.BasicAuth()
+		}
+		if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="journal-lite-admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
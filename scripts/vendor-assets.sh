@@ -0,0 +1,9 @@
+#!/bin/sh
+# Downloads the pinned third-party assets into static/vendor so they are
+# embedded into the binary. Run with `go generate` from the repository root.
+set -eu
+
+cd "$(dirname "$0")/../static/vendor"
+
+curl -fsSL -o htmx.min.js "https://unpkg.com/htmx.org@1.9.10/dist/htmx.min.js"
+echo "b3bdcf5c741897a53648b1207fff0469a0d61901429ba1f6e88f98ebd84e669e  htmx.min.js" | sha256sum -c -
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentSecurityPolicyLoadsStylesFromSelfOnly(t *testing.T) {
+	directives := strings.Split(contentSecurityPolicy("nonce"), "; ")
+	for _, directive := range directives {
+		if strings.HasPrefix(directive, "style-src ") {
+			if directive != "style-src 'self'" {
+				t.Errorf("got %q, want style-src 'self'", directive)
+			}
+			return
+		}
+	}
+	t.Errorf("no style-src in %q", directives)
+}
@@ -0,0 +1,134 @@
+// server.go
+package main
+
+import (
+	"context"
+	"errors"
+	"journal-lite/internal/config"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// jobs runs background work that must stop before the database is closed.
+var jobs = newBackgroundJobs()
+
+// backgroundJobs tracks long-running goroutines. Every job receives a context
+// that is cancelled on shutdown and is expected to return promptly after that.
+type backgroundJobs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newBackgroundJobs() *backgroundJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundJobs{ctx: ctx, cancel: cancel}
+}
+
+// Go starts fn in a new goroutine.
+func (b *backgroundJobs) Go(name string, fn func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn(b.ctx)
+		if b.ctx.Err() == nil {
+			slog.Warn("Background job exited", "job", name)
+		}
+	}()
+}
+
+// Stop cancels every job and waits for them to return or for ctx to expire.
+func (b *backgroundJobs) Stop(ctx context.Context) error {
+	b.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newServer(server config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              server.Addr,
+		Handler:           handler,
+		ReadTimeout:       server.ReadTimeout.Duration,
+		ReadHeaderTimeout: server.ReadHeaderTimeout.Duration,
+		WriteTimeout:      server.WriteTimeout.Duration,
+		IdleTimeout:       server.IdleTimeout.Duration,
+		MaxHeaderBytes:    server.MaxHeaderBytes,
+	}
+}
+
+// serve runs srv until it fails or the process receives SIGINT or SIGTERM.
+// On a signal it stops accepting connections, drains in-flight requests and
+// stops the background jobs, all within the shutdown timeout.
+func serve(srv *http.Server, shutdownTimeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A second signal kills the process immediately.
+	context.AfterFunc(ctx, stop)
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return errors.Join(err, stopJobs(jobs, shutdownTimeout))
+	}
+	slog.Info("Server starting", "addr", ln.Addr().String())
+	return serveUntil(ctx, srv, ln, jobs, shutdownTimeout)
+}
+
+// serveUntil runs srv on ln until it fails or ctx is done, then shuts down
+// the server and jobs as serve describes.
+func serveUntil(ctx context.Context, srv *http.Server, ln net.Listener, jobs *backgroundJobs, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return errors.Join(err, stopJobs(jobs, shutdownTimeout))
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		err = errors.Join(err, srv.Close())
+	}
+	if jobs.Stop(shutdownCtx) != nil {
+		err = errors.Join(err, errJobsTimeout)
+	}
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	return err
+}
+
+var errJobsTimeout = errors.New("background jobs did not stop in time")
+
+// stopJobs stops jobs when the server failed, waiting at most timeout.
+func stopJobs(jobs *backgroundJobs, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if jobs.Stop(ctx) != nil {
+		return errJobsTimeout
+	}
+	return nil
+}
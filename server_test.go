@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startServer runs handler under serveUntil on a free port and returns its
+// URL, the function that signals shutdown and the channel serveUntil's
+// result arrives on.
+func startServer(t *testing.T, handler http.Handler, jobs *backgroundJobs, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	result := make(chan error, 1)
+	go func() {
+		result <- serveUntil(ctx, &http.Server{Handler: handler}, ln, jobs, shutdownTimeout)
+	}()
+	return "http://" + ln.Addr().String(), cancel, result
+}
+
+// waitResult returns the result of serveUntil and how long it took to arrive.
+func waitResult(t *testing.T, result <-chan error) (error, time.Duration) {
+	t.Helper()
+	start := time.Now()
+	select {
+	case err := <-result:
+		return err, time.Since(start)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+		return nil, 0
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "finished")
+	})
+	url, shutdown, result := startServer(t, handler, newBackgroundJobs(), 5*time.Second)
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{string(body), err}
+	}()
+	<-started
+	shutdown()
+
+	select {
+	case err := <-result:
+		t.Fatalf("server stopped with a request in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("server accepted a request after shutdown began")
+	}
+
+	close(release)
+	if r := <-responses; r.err != nil || r.body != "finished" {
+		t.Errorf("in-flight request got %q, %v; want it to finish", r.body, r.err)
+	}
+	if err, _ := waitResult(t, result); err != nil {
+		t.Errorf("serveUntil = %v, want a clean shutdown", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+
+	t.Run("jobs stop when cancelled", func(t *testing.T) {
+		jobs := newBackgroundJobs()
+		stopped := make(chan struct{})
+		jobs.Go("well behaved", func(ctx context.Context) {
+			<-ctx.Done()
+			close(stopped)
+		})
+		_, shutdown, result := startServer(t, http.NotFoundHandler(), jobs, timeout)
+		shutdown()
+		if err, _ := waitResult(t, result); err != nil {
+			t.Errorf("serveUntil = %v, want a clean shutdown", err)
+		}
+		select {
+		case <-stopped:
+		default:
+			t.Error("serveUntil returned before the job stopped")
+		}
+	})
+
+	t.Run("stuck job", func(t *testing.T) {
+		jobs := newBackgroundJobs()
+		stuck := make(chan struct{})
+		t.Cleanup(func() { close(stuck) })
+		jobs.Go("stuck", func(ctx context.Context) { <-stuck })
+		_, shutdown, result := startServer(t, http.NotFoundHandler(), jobs, timeout)
+		shutdown()
+		err, took := waitResult(t, result)
+		if !errors.Is(err, errJobsTimeout) {
+			t.Errorf("serveUntil = %v, want %v", err, errJobsTimeout)
+		}
+		if took > timeout+time.Second {
+			t.Errorf("shutdown took %v with a %v timeout", took, timeout)
+		}
+	})
+
+	t.Run("stuck request", func(t *testing.T) {
+		started, stuck := make(chan struct{}), make(chan struct{})
+		t.Cleanup(func() { close(stuck) })
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-stuck
+		})
+		url, shutdown, result := startServer(t, handler, newBackgroundJobs(), timeout)
+		go func() {
+			if resp, err := http.Get(url); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		<-started
+		shutdown()
+		err, took := waitResult(t, result)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("serveUntil = %v, want the drain to time out", err)
+		}
+		if took > timeout+time.Second {
+			t.Errorf("shutdown took %v with a %v timeout", took, timeout)
+		}
+	})
+
+	// When the server fails instead of being signalled, the jobs are
+	// stopped under the same timeout.
+	t.Run("server fails with a stuck job", func(t *testing.T) {
+		jobs := newBackgroundJobs()
+		stuck := make(chan struct{})
+		t.Cleanup(func() { close(stuck) })
+		jobs.Go("stuck", func(ctx context.Context) { <-stuck })
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ln.Close()
+
+		result := make(chan error, 1)
+		go func() {
+			result <- serveUntil(context.Background(), &http.Server{Handler: http.NotFoundHandler()}, ln, jobs, timeout)
+		}()
+		err, took := waitResult(t, result)
+		if !errors.Is(err, net.ErrClosed) || !errors.Is(err, errJobsTimeout) {
+			t.Errorf("serveUntil = %v, want the listener's error and %v", err, errJobsTimeout)
+		}
+		if took > timeout+time.Second {
+			t.Errorf("stopping took %v with a %v timeout", took, timeout)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+// sso.go
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"journal-lite/internal/auth"
+	"journal-lite/internal/config"
+	"journal-lite/internal/metrics"
+	"journal-lite/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const oidcFlowCookie = "oidc_flow"
+
+// oidcProvider is nil unless single sign-on is configured.
+var oidcProvider *auth.OIDCProvider
+
+// oidcFlow is the state kept in a signed cookie between redirecting to the
+// provider and handling its callback.
+type oidcFlow struct {
+	State         string `json:"state"`
+	Nonce         string `json:"nonce"`
+	CodeVerifier  string `json:"code_verifier"`
+	LinkAccountId int64  `json:"link_account_id,omitempty"`
+}
+
+// configureSSO sets up the OpenID Connect provider if one is configured.
+func configureSSO(oidc config.OIDCConfig) {
+	if oidc.IssuerURL == "" {
+		return
+	}
+
+	oidcProvider = auth.NewOIDCProvider(oidc.IssuerURL, oidc.ClientID, oidc.ClientSecret, oidc.RedirectURL)
+	slog.Info("Single sign-on enabled", "issuer", oidc.IssuerURL)
+}
+
+// --- Handlers ---
+
+func (s sessions) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	s.startOIDCFlow(w, r, 0)
+}
+
+// oidcLinkHandler links the signed in account to an identity at the provider.
+func (s sessions) oidcLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(string)
+	accountId, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		handleError(w, r, "Invalid session", http.StatusUnauthorized)
+		return
+	}
+	s.startOIDCFlow(w, r, accountId)
+}
+
+func (s sessions) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	flow, err := readOIDCFlow(r)
+	s.clearOIDCFlow(w)
+	if err != nil {
+		s.renderLoginError(w, r, "Your sign-in session expired. Please try again.")
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("state") != flow.State {
+		s.renderLoginError(w, r, "Single sign-on failed: state mismatch.")
+		return
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		slog.WarnContext(r.Context(), "OIDC provider returned error", "error", providerErr, "description", query.Get("error_description"))
+		s.renderLoginError(w, r, "Single sign-on was cancelled or denied.")
+		return
+	}
+
+	ctx := r.Context()
+	claims, err := oidcProvider.Exchange(ctx, query.Get("code"), flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC code exchange failed", "error", err)
+		metrics.Login("oidc", false)
+		s.renderLoginError(w, r, "Single sign-on failed.")
+		return
+	}
+
+	if flow.LinkAccountId != 0 {
+		err := accountService.LinkIdentity(ctx, flow.LinkAccountId, oidcProvider.Issuer, claims.Subject)
+		if errors.Is(err, service.ErrIdentityLinked) {
+			handleError(w, r, "That identity is already linked to another account.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			handleError(w, r, "Error linking account: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderTemplate(w, r, "sso-redirect", "/feed")
+		return
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Email
+	}
+
+	account, err := accountService.ProvisionOIDCAccount(ctx, oidcProvider.Issuer, claims.Subject, username)
+	if err != nil {
+		handleError(w, r, "Error provisioning account: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if account.DisabledAt != "" {
+		metrics.Login("oidc", false)
+		s.renderLoginError(w, r, "This account is disabled.")
+		return
+	}
+
+	token, err := authenticator.IssueToken(account.Id, account.Username)
+	if err != nil {
+		handleError(w, r, "Error occurred while generating the token.", http.StatusInternalServerError)
+		return
+	}
+
+	metrics.Login("oidc", true)
+	s.setSessionCookie(w, token)
+	// The session cookie is SameSite=Strict, so it would not be sent on a
+	// redirect that started at the provider. Navigate from our own page.
+	renderTemplate(w, r, "sso-redirect", "/feed")
+}
+
+// --- Helper Functions ---
+
+func (s sessions) startOIDCFlow(w http.ResponseWriter, r *http.Request, linkAccountId int64) {
+	state, errState := auth.NewState()
+	nonce, errNonce := auth.NewState()
+	verifier, challenge, errVerifier := auth.NewPKCEVerifier()
+	if err := errors.Join(errState, errNonce, errVerifier); err != nil {
+		handleError(w, r, "Could not start single sign-on", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "OIDC authorization URL failed", "error", err)
+		handleError(w, r, "The identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	flow, _ := json.Marshal(oidcFlow{
+		State:         state,
+		Nonce:         nonce,
+		CodeVerifier:  verifier,
+		LinkAccountId: linkAccountId,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    authenticator.SignValue(base64.RawURLEncoding.EncodeToString(flow)),
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		SameSite: http.SameSiteLaxMode, // Must survive the top-level redirect back from the provider
+	})
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", authURL)
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func readOIDCFlow(r *http.Request) (oidcFlow, error) {
+	var flow oidcFlow
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		return flow, err
+	}
+	value, err := authenticator.VerifySignedValue(cookie.Value)
+	if err != nil {
+		return flow, err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return flow, err
+	}
+	err = json.Unmarshal(raw, &flow)
+	return flow, err
+}
+
+func (s sessions) clearOIDCFlow(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s sessions) renderLoginError(w http.ResponseWriter, r *http.Request, message string) {
+	w.WriteHeader(http.StatusUnauthorized)
+	renderTemplate(w, r, "index", s.loginBoxMessage(true, message))
+}
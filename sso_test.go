@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testOIDCClientID     = "journal-lite"
+	testOIDCClientSecret = "client-secret"
+	testOIDCKeyID        = "test-key"
+)
+
+// mockIssuer is an OpenID Connect provider serving discovery, JWKS, an
+// authorization endpoint that approves every request, and a token endpoint
+// that enforces PKCE and signs ID tokens with a test key.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu sync.Mutex
+	// Subject and Username go into the next ID token. Nonce replaces the
+	// one sent to the authorization endpoint, unless it is empty.
+	Subject  string
+	Username string
+	Nonce    string
+	codes    map[string]mockAuthorization
+	// exchanges counts codes traded for ID tokens.
+	exchanges int
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+// newMockIssuer starts a provider and configures single sign-on against it
+// until the test ends.
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{t: t, key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kid": testOIDCKeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", m.authorize)
+	mux.HandleFunc("POST /token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	configureSSO(config.OIDCConfig{
+		IssuerURL:    m.server.URL,
+		ClientID:     testOIDCClientID,
+		ClientSecret: testOIDCClientSecret,
+		RedirectURL:  "http://journal.test/auth/oidc/callback",
+	})
+	t.Cleanup(func() { oidcProvider = nil })
+	return m
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != testOIDCClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	code := "code-" + strconv.Itoa(len(m.codes))
+	m.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	m.mu.Unlock()
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	callback.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, _ := r.BasicAuth()
+	clientID, _ = url.QueryUnescape(clientID)
+	secret, _ = url.QueryUnescape(secret)
+	if clientID != testOIDCClientID || secret != testOIDCClientSecret {
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	authorization, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := authorization.nonce
+	if m.Nonce != "" {
+		nonce = m.Nonce
+	}
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                m.server.URL,
+		"aud":                testOIDCClientID,
+		"sub":                m.Subject,
+		"preferred_username": m.Username,
+		"nonce":              nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = testOIDCKeyID
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		m.t.Error(err)
+		return
+	}
+	m.exchanges++
+	writeMockJSON(w, http.StatusOK, map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+// signIn starts the flow at path, lets the provider approve it, and returns
+// the response to the callback. session, if not empty, authenticates the
+// start of the flow, and tamper may change the callback URL.
+func (m *mockIssuer) signIn(path string, session string, tamper func(callback url.Values)) *httptest.ResponseRecorder {
+	m.t.Helper()
+	start := httptest.NewRequest("GET", path, nil)
+	if session != "" {
+		start.Header.Set("Authorization", "Bearer "+session)
+	}
+	started := httptest.NewRecorder()
+	handler(started, start)
+	if started.Code != http.StatusFound {
+		m.t.Fatalf("GET %s: got status %d, want %d: %s", path, started.Code, http.StatusFound, started.Body)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(started.Header().Get("Location"))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		m.t.Fatalf("authorization endpoint: got status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	query := callback.Query()
+	if tamper != nil {
+		tamper(query)
+	}
+
+	finish := httptest.NewRequest("GET", callback.Path+"?"+query.Encode(), nil)
+	for _, cookie := range started.Result().Cookies() {
+		finish.AddCookie(cookie)
+	}
+	finished := httptest.NewRecorder()
+	handler(finished, finish)
+	return finished
+}
+
+// sessionAccount returns the account id of the session cookie set by the
+// response, or 0 when none was set.
+func sessionAccount(t *testing.T, recorder *httptest.ResponseRecorder) int64 {
+	t.Helper()
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name != "token" || cookie.Value == "" {
+			continue
+		}
+		claims, err := authenticator.ValidateToken(cookie.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := strconv.ParseInt(claims.UserID, 10, 64)
+		return id
+	}
+	return 0
+}
+
+func TestOIDCFirstLoginProvisionsAnAccount(t *testing.T) {
+	m := newMockIssuer(t)
+	m.Subject, m.Username = "alice-subject", "oidc-alice"
+
+	first := m.signIn("/auth/oidc/login", "", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("callback: got status %d, want %d: %s", first.Code, http.StatusOK, first.Body)
+	}
+	accountId := sessionAccount(t, first)
+	account, err := accountService.GetAccountById(context.Background(), accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Username != "oidc-alice" {
+		t.Errorf("provisioned username = %q, want %q", account.Username, "oidc-alice")
+	}
+
+	if again := sessionAccount(t, m.signIn("/auth/oidc/login", "", nil)); again != accountId {
+		t.Errorf("second login signed in as account %d, want %d", again, accountId)
+	}
+	if m.exchanges != 2 {
+		t.Errorf("token endpoint exchanged %d codes, want 2", m.exchanges)
+	}
+}
+
+func TestOIDCRejectsMismatchedFlows(t *testing.T) {
+	m := newMockIssuer(t)
+	m.Subject, m.Username = "mallory-subject", "oidc-mallory"
+
+	stateMismatch := m.signIn("/auth/oidc/login", "", func(callback url.Values) {
+		callback.Set("state", "forged")
+	})
+	if stateMismatch.Code != http.StatusUnauthorized || sessionAccount(t, stateMismatch) != 0 {
+		t.Errorf("state mismatch: got status %d, want %d without a session", stateMismatch.Code, http.StatusUnauthorized)
+	}
+	if m.exchanges != 0 {
+		t.Errorf("state mismatch: the code was exchanged")
+	}
+
+	// The provider only redeems codes for the verifier whose challenge
+	// started the flow.
+	codeSwap := m.signIn("/auth/oidc/login", "", func(callback url.Values) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.codes[callback.Get("code")] = mockAuthorization{challenge: "another-flows-challenge"}
+	})
+	if codeSwap.Code != http.StatusUnauthorized || sessionAccount(t, codeSwap) != 0 {
+		t.Errorf("PKCE mismatch: got status %d, want %d without a session", codeSwap.Code, http.StatusUnauthorized)
+	}
+
+	m.Nonce = "replayed-nonce"
+	nonceMismatch := m.signIn("/auth/oidc/login", "", nil)
+	if nonceMismatch.Code != http.StatusUnauthorized || sessionAccount(t, nonceMismatch) != 0 {
+		t.Errorf("nonce mismatch: got status %d, want %d without a session", nonceMismatch.Code, http.StatusUnauthorized)
+	}
+
+	if _, err := accountService.GetAccountByUsername(context.Background(), "oidc-mallory"); err == nil {
+		t.Error("a rejected flow provisioned an account")
+	}
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+	m := newMockIssuer(t)
+	m.Subject, m.Username = "bob-subject", "oidc-bob"
+	ctx := context.Background()
+
+	accountId, err := accountService.CreateAccount(ctx, accounts.Account{Username: "password-bob", PasswordHash: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked := m.signIn("/auth/oidc/link", mustIssueToken(t, accountId), nil); linked.Code != http.StatusOK {
+		t.Fatalf("link callback: got status %d, want %d: %s", linked.Code, http.StatusOK, linked.Body)
+	}
+	if got := sessionAccount(t, m.signIn("/auth/oidc/login", "", nil)); got != accountId {
+		t.Errorf("login with the linked identity signed in as account %d, want %d", got, accountId)
+	}
+
+	otherId, err := accountService.CreateAccount(ctx, accounts.Account{Username: "password-eve", PasswordHash: "correct horse battery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relinked := m.signIn("/auth/oidc/link", mustIssueToken(t, otherId), nil); relinked.Code != http.StatusConflict {
+		t.Errorf("linking an identity linked to another account: got status %d, want %d", relinked.Code, http.StatusConflict)
+	}
+}
+
+func TestOIDCRefusesDisabledAccounts(t *testing.T) {
+	m := newMockIssuer(t)
+	m.Subject, m.Username = "carol-subject", "oidc-carol"
+
+	accountId := sessionAccount(t, m.signIn("/auth/oidc/login", "", nil))
+	if accountId == 0 {
+		t.Fatal("first login did not sign in")
+	}
+	if err := accountService.SetDisabled(context.Background(), accountId, true); err != nil {
+		t.Fatal(err)
+	}
+
+	refused := m.signIn("/auth/oidc/login", "", nil)
+	if refused.Code != http.StatusUnauthorized || sessionAccount(t, refused) != 0 {
+		t.Errorf("disabled account: got status %d, want %d without a session", refused.Code, http.StatusUnauthorized)
+	}
+}
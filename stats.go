@@ -0,0 +1,150 @@
+package main
+
+import (
+	"journal-lite/internal/stats"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Charts are drawn in a viewBox of this height, and chartWidth or half of
+// it wide when two share a row, and scale with the page.
+const (
+	chartWidth  = 600
+	chartHeight = 160
+	// chartTop and chartBottom bound the bars; the axis labels go below.
+	chartTop    = 14
+	chartBottom = 136
+)
+
+type barChart struct {
+	Title  string
+	Unit   string
+	Width  int
+	Height int
+	// Base is the y of the axis the bars stand on.
+	Base  int
+	Max   int64
+	Total int64
+	Bars  []chartBar
+}
+
+type chartBar struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	// Label is written under the bar, unless it is empty.
+	Label  string
+	LabelX float64
+	// Title is the tooltip, with the bar's value.
+	Title string
+	Value int64
+}
+
+// newBarChart lays out one bar per value, scaled to the largest. Every
+// labelEvery-th bar is labelled.
+func newBarChart(title string, unit string, width int, labels []string, titles []string, values []int64, labelEvery int) barChart {
+	chart := barChart{Title: title, Unit: unit, Width: width, Height: chartHeight, Base: chartBottom}
+	for _, value := range values {
+		chart.Max = max(chart.Max, value)
+		chart.Total += value
+	}
+	slot := float64(width) / float64(len(values))
+	for i, value := range values {
+		bar := chartBar{
+			X:      round2(float64(i)*slot + slot*0.1),
+			Width:  round2(slot * 0.8),
+			LabelX: round2(float64(i)*slot + slot/2),
+			Title:  titles[i] + ": " + strconv.FormatInt(value, 10) + " " + unit,
+			Value:  value,
+		}
+		if chart.Max > 0 {
+			bar.Height = round2(float64(value) / float64(chart.Max) * (chartBottom - chartTop))
+		}
+		bar.Y = round2(chartBottom - bar.Height)
+		if i%labelEvery == 0 {
+			bar.Label = labels[i]
+		}
+		chart.Bars = append(chart.Bars, bar)
+	}
+	return chart
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+type statsView struct {
+	Stats          stats.Stats
+	WordsPerEntry  int64
+	WordsPerDay    barChart
+	WordsPerMonth  barChart
+	EntriesWeekday barChart
+	EntriesHour    barChart
+	// MaxTagCount scales the bars of the top tags.
+	MaxTagCount int64
+}
+
+func newStatsView(summary stats.Stats) statsView {
+	view := statsView{Stats: summary}
+	if summary.Entries > 0 {
+		view.WordsPerEntry = summary.Words / summary.Entries
+	}
+
+	var labels, titles []string
+	var values []int64
+	for _, day := range summary.Days {
+		date, _ := time.Parse(time.DateOnly, day.Date)
+		labels = append(labels, date.Format("Jan 2"))
+		titles = append(titles, date.Format("Mon, Jan 2"))
+		values = append(values, day.Words)
+	}
+	view.WordsPerDay = newBarChart("Words per day", "words", chartWidth, labels, titles, values, 7)
+
+	labels, titles, values = nil, nil, nil
+	for _, month := range summary.Months {
+		date, _ := time.Parse("2006-01", month.Month)
+		labels = append(labels, date.Format("Jan"))
+		titles = append(titles, date.Format("January 2006"))
+		values = append(values, month.Words)
+	}
+	view.WordsPerMonth = newBarChart("Words per month", "words", chartWidth, labels, titles, values, 1)
+
+	labels, values = nil, nil
+	for i, count := range summary.Weekdays {
+		labels = append(labels, time.Weekday((i + 1) % 7).String()[:3])
+		values = append(values, count)
+	}
+	view.EntriesWeekday = newBarChart("Entries by weekday", "entries", chartWidth/2, labels, labels, values, 1)
+
+	labels, values = nil, nil
+	for hour, count := range summary.Hours {
+		labels = append(labels, strconv.Itoa(hour)+":00")
+		values = append(values, count)
+	}
+	view.EntriesHour = newBarChart("Entries by hour", "entries", chartWidth/2, labels, labels, values, 6)
+
+	for _, tag := range summary.TopTags {
+		view.MaxTagCount = max(view.MaxTagCount, tag.PostCount)
+	}
+	return view
+}
+
+// statsPageHandler shows streaks and charts of how the account writes.
+// Today, which the current streak and recent days end on, is in the
+// account's time zone.
+func statsPageHandler(w http.ResponseWriter, r *http.Request) {
+	account, today, err := accountToday(r)
+	if err != nil {
+		handleError(w, r, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+	summary, err := statsService.GetStats(r.Context(), account.Id, today)
+	if err != nil {
+		handleError(w, r, "Error computing statistics", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, "stats", newStatsView(summary))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"journal-lite/internal/tokens"
+	"journal-lite/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqlLiteral matches the string and numeric literals RedactSQL replaces.
+var sqlLiteral = regexp.MustCompile(`'|\b\d+\b`)
+
+// TestSearchIsTraced drives /search through the server middleware and checks
+// that its spans nest from the request down to the statements, and that no
+// statement is recorded with its literals.
+func TestSearchIsTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(sdktrace.WithSyncer(exporter), "journal-lite-test", 1)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, token := newTestAccount(t, "traced-search", tokens.ScopePostsRead)
+	mux := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(handler))
+	req := httptest.NewRequest("GET", "/search?search=confidential", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	newRootHandler(mux).ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /search: got status %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	spans := exporter.GetSpans()
+	byID := make(map[trace.SpanID]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		byID[span.SpanContext.SpanID()] = span
+	}
+	parentName := func(span tracetest.SpanStub) string {
+		return byID[span.Parent.SpanID()].Name
+	}
+	find := func(name string) tracetest.SpanStub {
+		t.Helper()
+		for _, span := range spans {
+			if span.Name == name {
+				return span
+			}
+		}
+		t.Fatalf("no %s span among %d", name, len(spans))
+		return tracetest.SpanStub{}
+	}
+
+	server := find("GET /search")
+	if server.SpanKind != trace.SpanKindServer || server.Parent.IsValid() {
+		t.Errorf("GET /search span is not the root server span")
+	}
+	service := find("PostService.GetPosts")
+	if got := parentName(service); got != server.Name {
+		t.Errorf("PostService.GetPosts is a child of %q, want %q", got, server.Name)
+	}
+	repo := find("PostRepository.GetPosts")
+	if got := parentName(repo); got != service.Name {
+		t.Errorf("PostRepository.GetPosts is a child of %q, want %q", got, service.Name)
+	}
+
+	var search *tracetest.SpanStub
+	for i, span := range spans {
+		for _, attr := range span.Attributes {
+			if strings.Contains(attr.Value.Emit(), "confidential") {
+				t.Errorf("%s records the search text in %s: %s", span.Name, attr.Key, attr.Value.Emit())
+			}
+			if attr.Key != semconv.DBQueryTextKey {
+				continue
+			}
+			query := attr.Value.AsString()
+			if span.Parent.SpanID() == repo.SpanContext.SpanID() && strings.Contains(query, "LIKE ?") {
+				search = &spans[i]
+			}
+			if sqlLiteral.MatchString(query) {
+				t.Errorf("%s records a literal: %s", span.Name, query)
+			}
+			if query != tracing.RedactSQL(query) {
+				t.Errorf("%s records a statement RedactSQL would change: %s", span.Name, query)
+			}
+		}
+	}
+	if search == nil {
+		t.Fatal("no database span with the search statement under PostRepository.GetPosts")
+	}
+	if search.SpanKind != trace.SpanKindClient {
+		t.Errorf("search statement span kind = %v, want client", search.SpanKind)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"journal-lite/internal/accounts"
+	"journal-lite/internal/config"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// userOutput is an account as the user command prints it. Password is only
+// set when the command generated one.
+type userOutput struct {
+	Id         int64  `json:"id"`
+	Username   string `json:"username"`
+	CreatedAt  string `json:"created_at"`
+	Disabled   bool   `json:"disabled"`
+	DisabledAt string `json:"disabled_at,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+func toUserOutput(account accounts.Account) userOutput {
+	return userOutput{
+		Id:         account.Id,
+		Username:   account.Username,
+		CreatedAt:  account.CreatedAt,
+		Disabled:   account.DisabledAt != "",
+		DisabledAt: account.DisabledAt,
+	}
+}
+
+// runUserCommand manages accounts without going through the web UI.
+func runUserCommand(_ config.Config, args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: journal-lite [flags] user list [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] user create NAME [--password-stdin] [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] user reset-password NAME [--password-stdin] [--json]")
+		fmt.Fprintln(os.Stderr, "       journal-lite [flags] user disable|enable NAME [--json]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	action, args := args[0], args[1:]
+	flags := flag.NewFlagSet("user "+action, flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	passwordStdin := new(bool)
+	if action == "create" || action == "reset-password" {
+		passwordStdin = flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	}
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "list":
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() > 0 {
+			flags.Usage()
+			return 2
+		}
+		return listUsers(ctx, *asJSON)
+	case "create", "reset-password", "disable", "enable":
+	default:
+		usage()
+		return 2
+	}
+
+	username, ok := parseWithName(flags, args)
+	if !ok {
+		return 2
+	}
+
+	switch action {
+	case "create":
+		password, generated, err := commandPassword(*passwordStdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		id, err := accountService.CreateAccount(ctx, accounts.Account{Username: username, PasswordHash: password})
+		if err != nil {
+			slog.Error("Could not create account", "error", err)
+			return 1
+		}
+		if id == 0 {
+			fmt.Fprintf(os.Stderr, "An account named %q already exists\n", username)
+			return 1
+		}
+		account, err := accountService.GetAccountById(ctx, id)
+		if err != nil {
+			slog.Error("Could not load account", "error", err)
+			return 1
+		}
+		return printUser(account, generated, *asJSON, "Created")
+
+	case "reset-password":
+		account, code := lookupAccount(ctx, username)
+		if code != 0 {
+			return code
+		}
+		password, generated, err := commandPassword(*passwordStdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := accountService.ResetPassword(ctx, account.Id, password); err != nil {
+			slog.Error("Could not reset password", "error", err)
+			return 1
+		}
+		return printUser(account, generated, *asJSON, "Reset the password of")
+
+	case "disable", "enable":
+		account, code := lookupAccount(ctx, username)
+		if code != 0 {
+			return code
+		}
+		if err := accountService.SetDisabled(ctx, account.Id, action == "disable"); err != nil {
+			slog.Error("Could not update account", "error", err)
+			return 1
+		}
+		account, err := accountService.GetAccountById(ctx, account.Id)
+		if err != nil {
+			slog.Error("Could not load account", "error", err)
+			return 1
+		}
+		verb := "Enabled"
+		if action == "disable" {
+			verb = "Disabled"
+		}
+		return printUser(account, "", *asJSON, verb)
+	}
+	return 2
+}
+
+func listUsers(ctx context.Context, asJSON bool) int {
+	list, err := accountService.ListAccounts(ctx)
+	if err != nil {
+		slog.Error("Could not list accounts", "error", err)
+		return 1
+	}
+	users := make([]userOutput, len(list))
+	for i, account := range list {
+		users[i] = toUserOutput(account)
+	}
+	if asJSON {
+		return printJSON(users)
+	}
+	table := newTable()
+	fmt.Fprintln(table, "ID\tUSERNAME\tCREATED AT\tSTATUS")
+	for _, user := range users {
+		status := "active"
+		if user.Disabled {
+			status = "disabled since " + user.DisabledAt
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", user.Id, user.Username, user.CreatedAt, status)
+	}
+	table.Flush()
+	return 0
+}
+
+func printUser(account accounts.Account, generatedPassword string, asJSON bool, verb string) int {
+	user := toUserOutput(account)
+	user.Password = generatedPassword
+	if asJSON {
+		return printJSON(user)
+	}
+	fmt.Printf("%s %s (id %d)\n", verb, user.Username, user.Id)
+	if user.Password != "" {
+		fmt.Printf("Password: %s\n", user.Password)
+	}
+	return 0
+}
+
+func lookupAccount(ctx context.Context, username string) (accounts.Account, int) {
+	account, err := accountService.GetAccountByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "No account named %q\n", username)
+		return account, 1
+	}
+	if err != nil {
+		slog.Error("Could not load account", "error", err)
+		return account, 1
+	}
+	return account, 0
+}
+
+// commandPassword reads a password from stdin, or generates one and returns
+// it as generated so that it can be shown once.
+func commandPassword(fromStdin bool) (password string, generated string, err error) {
+	if !fromStdin {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return "", "", err
+		}
+		password = base64.RawURLEncoding.EncodeToString(b)
+		return password, password, nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", "", err
+	}
+	password = strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", "", errors.New("the password read from stdin is empty")
+	}
+	return password, "", nil
+}
+
+// parseWithName parses flags around a single positional name, so that both
+// `user create alice --json` and `user create --json alice` work.
+func parseWithName(flags *flag.FlagSet, args []string) (string, bool) {
+	if err := flags.Parse(args); err != nil {
+		return "", false
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return "", false
+	}
+	name := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return "", false
+	}
+	if flags.NArg() > 0 || name == "" {
+		flags.Usage()
+		return "", false
+	}
+	return name, true
+}
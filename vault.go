@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"journal-lite/internal/config"
+	"journal-lite/internal/health"
+	"journal-lite/internal/repository"
+	"journal-lite/internal/vault"
+	"log/slog"
+	"time"
+)
+
+var vaultSyncer *vault.Syncer
+
+// startVaultSync mirrors the configured account's journal to the vault
+// folder. Notes are synced as soon as an editor saves them, and changes made
+// in the app are picked up every vault.interval.
+func startVaultSync(cfg config.VaultConfig, repo repository.PostRepository) error {
+	if cfg.Path == "" {
+		return nil
+	}
+	account, err := accountService.GetAccountByUsername(context.Background(), cfg.Account)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("vault.account: no account named %q", cfg.Account)
+	}
+	if err != nil {
+		return err
+	}
+	watcher, err := vault.NewWatcher(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("vault.path: %w", err)
+	}
+	vaultSyncer = vault.NewSyncer(repo, account.Id, cfg.Path, cfg.FileFormat)
+
+	interval := cfg.Interval.Duration
+	jobs.Go("vault", func(ctx context.Context) {
+		defer watcher.Close()
+		lastError := ""
+		syncVault := func() {
+			result, err := vaultSyncer.Sync(ctx)
+			if err != nil {
+				// A vault on a disconnected drive fails every time; say so once.
+				if err.Error() != lastError {
+					slog.ErrorContext(ctx, "Vault sync failed", "error", err)
+				}
+				lastError = err.Error()
+				return
+			}
+			lastError = ""
+			if result.Conflicts > 0 {
+				slog.WarnContext(ctx, "Vault sync found conflicting edits; the older versions were saved",
+					"conflicts", result.Conflicts, "dir", vault.ConflictDir)
+			}
+			if result.Changed() {
+				slog.InfoContext(ctx, "Vault synced", "created", result.Created, "updated", result.Updated,
+					"deleted", result.Deleted, "notes_written", result.Written, "notes_removed", result.Removed)
+			}
+		}
+
+		syncVault()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watcher.Changes():
+				syncVault()
+			case err := <-watcher.Errors():
+				slog.ErrorContext(ctx, "Watching the vault failed", "error", err)
+			case <-ticker.C:
+				syncVault()
+			}
+		}
+	})
+
+	healthChecks.Register(health.Check{
+		Name: "vault",
+		Run: func(ctx context.Context) (string, error) {
+			status := vaultSyncer.Status()
+			if status.LastSyncAt == nil {
+				return "", health.Warning(errors.New("not synced yet"))
+			}
+			if status.LastError != "" {
+				return "", health.Warning(errors.New(status.LastError))
+			}
+			return fmt.Sprintf("synced %s ago, %d conflicts", time.Since(*status.LastSyncAt).Round(time.Second), status.Conflicts), nil
+		},
+	})
+
+	slog.Info("Syncing journal with vault", "dir", cfg.Path, "account", account.Username)
+	return nil
+}